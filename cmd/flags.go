@@ -1,11 +1,14 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/urfave/cli/v2"
 	"github.com/w-h-a/flags/internal/flags"
+	"gopkg.in/yaml.v3"
 )
 
 func Flags(ctx *cli.Context) error {
@@ -20,6 +23,12 @@ func Flags(ctx *cli.Context) error {
 		return err
 	}
 
+	env := ctx.String("env")
+
+	if len(env) > 0 {
+		return render(bs, ctx.String("format"), env)
+	}
+
 	fs, err := flags.Factory(
 		bs,
 		ctx.String("format"),
@@ -34,3 +43,29 @@ func Flags(ctx *cli.Context) error {
 
 	return nil
 }
+
+// render prints the flags as the server in env would serve them
+func render(bs []byte, format, env string) error {
+	fs, err := flags.FactoryIn(bs, format, env)
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+
+	switch strings.ToLower(format) {
+	case "json":
+		out, err = json.MarshalIndent(fs, "", "  ")
+		out = append(out, '\n')
+	default:
+		out, err = yaml.Marshal(fs)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(string(out))
+
+	return nil
+}
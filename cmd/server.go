@@ -2,28 +2,54 @@ package cmd
 
 import (
 	"context"
+	"crypto/ed25519"
 	"fmt"
 	"log/slog"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/urfave/cli/v2"
 	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/apikey"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/oidc"
+	"github.com/w-h-a/flags/internal/server/clients/broadcaster"
+	localbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/local"
+	postgresbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/postgres"
+	redisbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/redis"
 	"github.com/w-h-a/flags/internal/server/clients/exporter"
 	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
 	"github.com/w-h-a/flags/internal/server/clients/notifier"
 	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
+	"github.com/w-h-a/flags/internal/server/clients/notifier/routing"
 	"github.com/w-h-a/flags/internal/server/clients/notifier/slack"
 	"github.com/w-h-a/flags/internal/server/clients/reader"
 	dynamodbreader "github.com/w-h-a/flags/internal/server/clients/reader/dynamodb"
+	gitreader "github.com/w-h-a/flags/internal/server/clients/reader/git"
 	"github.com/w-h-a/flags/internal/server/clients/reader/github"
 	"github.com/w-h-a/flags/internal/server/clients/reader/gitlab"
+	httpreader "github.com/w-h-a/flags/internal/server/clients/reader/http"
+	"github.com/w-h-a/flags/internal/server/clients/reader/layered"
 	localreader "github.com/w-h-a/flags/internal/server/clients/reader/local"
 	postgresreader "github.com/w-h-a/flags/internal/server/clients/reader/postgres"
+	redisreader "github.com/w-h-a/flags/internal/server/clients/reader/redis"
+	"github.com/w-h-a/flags/internal/server/clients/reader/resilient"
+	s3reader "github.com/w-h-a/flags/internal/server/clients/reader/s3"
+	"github.com/w-h-a/flags/internal/server/clients/reader/signed"
+	"github.com/w-h-a/flags/internal/server/clients/reader/snapshot"
+	sqlitereader "github.com/w-h-a/flags/internal/server/clients/reader/sqlite"
 	"github.com/w-h-a/flags/internal/server/clients/writer"
 	dynamodbwriter "github.com/w-h-a/flags/internal/server/clients/writer/dynamodb"
+	gitwriter "github.com/w-h-a/flags/internal/server/clients/writer/git"
+	githubwriter "github.com/w-h-a/flags/internal/server/clients/writer/github"
+	gitlabwriter "github.com/w-h-a/flags/internal/server/clients/writer/gitlab"
+	localwriter "github.com/w-h-a/flags/internal/server/clients/writer/local"
 	"github.com/w-h-a/flags/internal/server/clients/writer/noop"
 	postgreswriter "github.com/w-h-a/flags/internal/server/clients/writer/postgres"
+	rediswriter "github.com/w-h-a/flags/internal/server/clients/writer/redis"
+	sqlitewriter "github.com/w-h-a/flags/internal/server/clients/writer/sqlite"
 	"github.com/w-h-a/flags/internal/server/config"
 	"go.opentelemetry.io/contrib/bridges/otelslog"
 	"go.opentelemetry.io/contrib/instrumentation/host"
@@ -140,17 +166,32 @@ func Server(ctx *cli.Context) error {
 	}
 
 	// clients
-	writeClient := initWriteClient()
-	readClient := initReadClient()
+	if err := config.ValidateEnvironments(); err != nil {
+		return err
+	}
+
+	environments := []server.Environment{}
+
+	for _, env := range config.Environments() {
+		environments = append(environments, server.Environment{
+			Name:        env,
+			WriteClient: initWriteClient(env),
+			ReadClient:  initReadClient(env),
+		})
+	}
+
 	exportClient := initExportClient()
 	notifyClient := initNotifyClient()
+	authClient := initAuthClient()
+	broadcastClient := initBroadcastClient()
 
 	// server + services
-	httpServer, cacheService, exportService, notifyService, err := server.Factory(
-		writeClient,
-		readClient,
+	httpServer, cacheServices, exportService, notifyServices, err := server.EnvironmentsFactory(
+		environments,
 		exportClient,
 		notifyClient,
+		authClient,
+		broadcastClient,
 	)
 	if err != nil {
 		return err
@@ -158,7 +199,7 @@ func Server(ctx *cli.Context) error {
 
 	// wait group and error chan
 	wg := &sync.WaitGroup{}
-	errCh := make(chan error, 3)
+	errCh := make(chan error, 2+len(environments))
 
 	// start http server
 	wg.Add(1)
@@ -168,18 +209,40 @@ func Server(ctx *cli.Context) error {
 		errCh <- httpServer.Start()
 	}()
 
-	// start cache updater
 	cacheStop := make(chan struct{})
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		errCh <- server.UpdateCache(
-			cacheService,
-			notifyService,
-			cacheStop,
-			time.Duration(config.ReadInterval())*time.Second,
-		)
-	}()
+	invalidationStop := make(chan struct{})
+
+	for _, environment := range environments {
+		cacheService := cacheServices[environment.Name]
+		notifyService := notifyServices[environment.Name]
+
+		// start cache updater
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errCh <- server.UpdateCache(
+				cacheService,
+				notifyService,
+				cacheStop,
+				time.Duration(config.ReadInterval())*time.Second,
+			)
+		}()
+
+		// start cache invalidation listener, which the cache
+		// updater covers for when it stops
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := server.ListenForInvalidations(
+				cacheService,
+				notifyService,
+				broadcastClient,
+				invalidationStop,
+			); err != nil {
+				slog.WarnContext(context.Background(), "stopped listening for cache invalidations", "environment", environment.Name, "error", err)
+			}
+		}()
+	}
 
 	// start exporter
 	exportStop := make(chan struct{})
@@ -208,6 +271,8 @@ func Server(ctx *cli.Context) error {
 		wg.Wait()
 	}()
 
+	close(invalidationStop)
+
 	close(cacheStop)
 
 	slog.InfoContext(context.Background(), "successfully stopped cache")
@@ -267,46 +332,271 @@ func initMetricsExporter(ctx context.Context) (metricsdk.Exporter, error) {
 	}
 }
 
-func initWriteClient() writer.Writer {
-	switch config.WriteClient() {
+func initWriteClient(env string) writer.Writer {
+	location := config.EnvironmentWriteClientLocation(env)
+
+	switch config.EnvironmentWriteClient(env) {
 	case "postgres":
 		return postgreswriter.NewWriter(
-			writer.WithLocation(config.WriteClientLocation()),
+			writer.WithLocation(location),
+			writer.WithFormat(config.FlagFormat()),
+			postgreswriter.WithSchema(config.WriteClientSchema()),
+		)
+	case "sqlite":
+		return sqlitewriter.NewWriter(
+			writer.WithLocation(location),
 		)
 	case "dynamodb":
 		return dynamodbwriter.NewWriter(
-			writer.WithLocation(config.WriteClientLocation()),
+			writer.WithLocation(location),
+		)
+	case "redis":
+		return rediswriter.NewWriter(
+			writer.WithLocation(location),
+			writer.WithPrefix(config.WriteClientPrefix()),
+		)
+	case "git":
+		return gitwriter.NewWriter(
+			writer.WithLocation(location),
+			writer.WithFormat(config.FlagFormat()),
+			writer.WithRemote(config.WriteClientRemote()),
+			writer.WithBranch(config.WriteClientBranch()),
+			writer.WithPath(config.WriteClientPath()),
+		)
+	case "github":
+		return githubwriter.NewWriter(
+			writer.WithLocation(location),
+			writer.WithToken(config.WriteClientToken()),
+			writer.WithFormat(config.FlagFormat()),
+		)
+	case "gitlab":
+		return gitlabwriter.NewWriter(
+			writer.WithLocation(location),
+			writer.WithToken(config.WriteClientToken()),
+			writer.WithFormat(config.FlagFormat()),
+		)
+	case "local":
+		return localwriter.NewWriter(
+			writer.WithLocation(location),
+			writer.WithFormat(config.FlagFormat()),
 		)
 	default:
 		return noop.NewWriter(
-			writer.WithLocation(config.WriteClientLocation()),
+			writer.WithLocation(location),
 		)
 	}
 }
 
-func initReadClient() reader.Reader {
-	switch config.ReadClient() {
+func initReadClient(env string) reader.Reader {
+	readClient := newReadClient(config.EnvironmentReadClient(env), config.EnvironmentReadClientLocation(env))
+
+	location := config.EnvironmentReadClientSnapshot(env)
+
+	if len(location) == 0 {
+		return readClient
+	}
+
+	return snapshot.NewReader(
+		reader.WithLocation(location),
+		reader.WithFormat(config.FlagFormat()),
+		snapshot.WithReader(readClient),
+		snapshot.WithLenient(config.LenientLoading()),
+	)
+}
+
+func newReadClient(client, location string) reader.Reader {
+	readClient := withRetries(client, buildReadClient(client, location))
+
+	// layers are signed one by one
+	if len(config.ReadClientPublicKeys()) == 0 || client == "layered" {
+		return readClient
+	}
+
+	keys := []ed25519.PublicKey{}
+
+	for _, encoded := range strings.Split(config.ReadClientPublicKeys(), ",") {
+		key, err := signed.ParsePublicKey(encoded)
+		if err != nil {
+			detail := "failed to parse read client public keys"
+			slog.ErrorContext(context.Background(), detail, "error", err)
+			panic(detail)
+		}
+
+		keys = append(keys, key)
+	}
+
+	signatureLocation, err := newSignatureLocation(client, location)
+	if err != nil {
+		detail := fmt.Sprintf("failed to configure signed read client: READ_CLIENT_PUBLIC_KEYS is not supported by the %s read client", client)
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	return signed.NewReader(
+		reader.WithFormat(config.FlagFormat()),
+		signed.WithReader(readClient),
+		signed.WithSignature(withRetries(client, buildReadClient(client, signatureLocation))),
+		signed.WithPublicKeys(keys...),
+	)
+}
+
+// newSignatureLocation puts the signature next to the flags file. Clients
+// that keep a row per flag (postgres, sqlite, dynamodb and redis) have no
+// file to sign and the git reader only reads one path, so they are refused.
+func newSignatureLocation(client, location string) (string, error) {
+	switch client {
+	case "local":
+		return location + signed.Extension, nil
+	case "http", "github", "s3":
+		u, err := url.Parse(location)
+		if err != nil {
+			return "", err
+		}
+
+		u.Path += signed.Extension
+		u.RawPath = ""
+
+		return u.String(), nil
+	case "gitlab":
+		u, err := url.Parse(location)
+		if err != nil {
+			return "", err
+		}
+
+		// https://gitlab.com/api/v4/projects/:id/repository/files/:filePath/raw
+		// becomes .../files/:filePath.sig/raw
+		prefix, filePath, ok := strings.Cut(u.EscapedPath(), "/repository/files/")
+		if !ok || !strings.HasSuffix(filePath, "/raw") {
+			return "", fmt.Errorf("signatures need a gitlab file location")
+		}
+
+		filePath = strings.TrimSuffix(filePath, "/raw") + signed.Extension
+
+		escaped := prefix + "/repository/files/" + filePath + "/raw"
+
+		if u.Path, err = url.PathUnescape(escaped); err != nil {
+			return "", err
+		}
+
+		u.RawPath = escaped
+
+		return u.String(), nil
+	default:
+		return "", fmt.Errorf("signatures are only supported by the local, http, github, gitlab and s3 read clients")
+	}
+}
+
+// withRetries wraps sources that can fail for a while. Local files do not
+// fail transiently, the http reader retries on its own, and layers are
+// wrapped one by one.
+func withRetries(client string, readClient reader.Reader) reader.Reader {
+	switch client {
+	case "github", "gitlab", "git", "postgres", "dynamodb", "s3", "redis":
+		return resilient.NewReader(
+			resilient.WithReader(client, readClient),
+			reader.WithRetries(config.ReadClientRetries()),
+			resilient.WithBreaker(
+				config.ReadClientBreakerThreshold(),
+				time.Duration(config.ReadClientBreakerCooldown())*time.Second,
+			),
+		)
+	default:
+		return readClient
+	}
+}
+
+func buildReadClient(client, location string) reader.Reader {
+	switch client {
+	case "layered":
+		opts := []reader.Option{
+			reader.WithFormat(config.FlagFormat()),
+			layered.WithMerge(config.ReadClientMerge()),
+		}
+
+		// READ_CLIENT_LAYERS=git=/srv/flags,postgres=postgres://...,local=./flags.local.yaml
+		names := map[string]int{}
+
+		for _, layer := range strings.Split(config.ReadClientLayers(), ",") {
+			client, location, ok := strings.Cut(strings.TrimSpace(layer), "=")
+			if !ok || client == "layered" {
+				detail := "failed to parse read client layers"
+				slog.ErrorContext(context.Background(), detail, "layer", layer)
+				panic(detail)
+			}
+
+			names[client]++
+
+			name := client
+			if names[client] > 1 {
+				name = fmt.Sprintf("%s-%d", client, names[client])
+			}
+
+			opts = append(opts, layered.WithLayer(name, newReadClient(client, location)))
+		}
+
+		return layered.NewReader(opts...)
+	case "git":
+		return gitreader.NewReader(
+			reader.WithLocation(location),
+			reader.WithFormat(config.FlagFormat()),
+			reader.WithRemote(config.ReadClientRemote()),
+			reader.WithBranch(config.ReadClientBranch()),
+			reader.WithPath(config.ReadClientPath()),
+		)
 	case "github":
 		return github.NewReader(
-			reader.WithLocation(config.ReadClientLocation()),
+			reader.WithLocation(location),
+			reader.WithFormat(config.FlagFormat()),
 			reader.WithToken(config.ReadClientToken()),
 		)
 	case "gitlab":
 		return gitlab.NewReader(
-			reader.WithLocation(config.ReadClientLocation()),
+			reader.WithLocation(location),
+			reader.WithFormat(config.FlagFormat()),
 			reader.WithToken(config.ReadClientToken()),
 		)
+	case "http":
+		return httpreader.NewReader(
+			reader.WithLocation(location),
+			reader.WithFormat(config.FlagFormat()),
+			reader.WithToken(config.ReadClientToken()),
+			reader.WithHeaders(config.ReadClientHeaders()),
+			reader.WithTimeout(time.Duration(config.ReadClientTimeout())*time.Second),
+			reader.WithRetries(config.ReadClientRetries()),
+		)
 	case "postgres":
 		return postgresreader.NewReader(
-			reader.WithLocation(config.ReadClientLocation()),
+			reader.WithLocation(location),
+			reader.WithFormat(config.FlagFormat()),
+			postgresreader.WithSchema(config.ReadClientSchema()),
+		)
+	case "sqlite":
+		return sqlitereader.NewReader(
+			reader.WithLocation(location),
+			reader.WithFormat(config.FlagFormat()),
 		)
 	case "dynamodb":
 		return dynamodbreader.NewReader(
-			reader.WithLocation(config.ReadClientLocation()),
+			reader.WithLocation(location),
+			reader.WithFormat(config.FlagFormat()),
+			dynamodbreader.WithStream(config.ReadClientStream()),
+		)
+	case "s3":
+		return s3reader.NewReader(
+			reader.WithLocation(location),
+			reader.WithFormat(config.FlagFormat()),
+			reader.WithEndpoint(config.ReadClientEndpoint()),
+		)
+	case "redis":
+		return redisreader.NewReader(
+			reader.WithLocation(location),
+			reader.WithFormat(config.FlagFormat()),
+			reader.WithPrefix(config.ReadClientPrefix()),
 		)
 	default:
 		return localreader.NewReader(
-			reader.WithLocation(config.ReadClientLocation()),
+			reader.WithLocation(location),
+			reader.WithFormat(config.FlagFormat()),
 		)
 	}
 }
@@ -321,12 +611,70 @@ func initExportClient() exporter.Exporter {
 }
 
 func initNotifyClient() notifier.Notifier {
-	switch config.NotifyClient() {
+	notifyClient := newNotifyClient(config.NotifyClient(), config.NotifyURL())
+
+	opts := []notifier.Option{
+		routing.WithNotifier(notifyClient),
+	}
+
+	// projects without a notifier of their own share the default one
+	for _, project := range config.Projects() {
+		if len(config.ProjectNotifyClient(project)) == 0 {
+			continue
+		}
+
+		opts = append(opts, routing.WithRoute(
+			project,
+			newNotifyClient(config.ProjectNotifyClient(project), config.ProjectNotifyURL(project)),
+		))
+	}
+
+	if len(opts) == 1 {
+		return notifyClient
+	}
+
+	return routing.NewNotifier(opts...)
+}
+
+func newNotifyClient(client, url string) notifier.Notifier {
+	switch client {
 	case "slack":
 		return slack.NewNotifier(
-			notifier.WithURL(config.NotifyURL()),
+			notifier.WithURL(url),
 		)
 	default:
 		return localnotifier.NewNotifier()
 	}
 }
+
+func initAuthClient() authenticator.Authenticator {
+	switch config.AuthClient() {
+	case "oidc":
+		return oidc.NewAuthenticator(
+			authenticator.WithLocation(config.AuthClientLocation()),
+			authenticator.WithIssuer(config.AuthClientIssuer()),
+			authenticator.WithAudience(config.AuthClientAudience()),
+			authenticator.WithRolesClaim(config.AuthClientRolesClaim()),
+			authenticator.WithReadRole(config.AuthClientReadRole()),
+			authenticator.WithWriteRole(config.AuthClientWriteRole()),
+		)
+	default:
+		return apikey.NewAuthenticator()
+	}
+}
+
+func initBroadcastClient() broadcaster.Broadcaster {
+	switch config.BroadcastClient() {
+	case "postgres":
+		return postgresbroadcaster.NewBroadcaster(
+			broadcaster.WithLocation(config.BroadcastClientLocation()),
+		)
+	case "redis":
+		return redisbroadcaster.NewBroadcaster(
+			broadcaster.WithLocation(config.BroadcastClientLocation()),
+			broadcaster.WithPrefix(config.ReadClientPrefix()),
+		)
+	default:
+		return localbroadcaster.NewBroadcaster()
+	}
+}
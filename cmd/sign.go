@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	localreader "github.com/w-h-a/flags/internal/server/clients/reader/local"
+	"github.com/w-h-a/flags/internal/server/clients/reader/signed"
+)
+
+func Sign(ctx *cli.Context) error {
+	keyPath := ctx.String("key")
+
+	if ctx.Bool("generate") {
+		return generateKey(keyPath)
+	}
+
+	filePath := ctx.String("filePath")
+	if len(filePath) == 0 {
+		return fmt.Errorf("filePath is required to sign")
+	}
+
+	encoded, err := os.ReadFile(keyPath)
+	if err != nil {
+		return err
+	}
+
+	key, err := signed.ParsePrivateKey(string(encoded))
+	if err != nil {
+		return err
+	}
+
+	// read the flags the same way the server does so
+	// that directories are signed as they are merged
+	bs, err := localreader.NewReader(
+		reader.WithLocation(filePath),
+		reader.WithFormat(ctx.String("format")),
+	).Read(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if _, err := flags.Factory(bs, ctx.String("format")); err != nil {
+		return err
+	}
+
+	signaturePath := filePath + signed.Extension
+
+	if err := os.WriteFile(signaturePath, signed.Sign(bs, key), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("wrote %s for public key %s\n", signaturePath, encodePublicKey(key))
+
+	return nil
+}
+
+func generateKey(keyPath string) error {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	// never overwrite a key that may already be trusted
+	file, err := os.OpenFile(keyPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	if _, err := fmt.Fprintln(file, base64.StdEncoding.EncodeToString(key.Seed())); err != nil {
+		return err
+	}
+
+	fmt.Printf("wrote %s, add its public key to READ_CLIENT_PUBLIC_KEYS:\n%s\n", keyPath, encodePublicKey(key))
+
+	return nil
+}
+
+func encodePublicKey(key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+}
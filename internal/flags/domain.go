@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"maps"
 
+	"github.com/google/go-cmp/cmp"
 	queryeval "github.com/nikunjy/rules/parser"
 )
 
@@ -15,6 +17,7 @@ const (
 	ReasonSplit          string = "SPLIT"
 
 	ErrorNotFound string = "FLAG_NOT_FOUND"
+	ErrorParse    string = "PARSE_ERROR"
 )
 
 var (
@@ -25,6 +28,11 @@ type Flag struct {
 	Disabled *bool          `json:"disabled" yaml:"disabled"`
 	Variants map[string]any `json:"variants" yaml:"variants"`
 	Rules    []*Rule        `json:"rules" yaml:"rules"`
+	Tags     []string       `json:"tags,omitempty" yaml:"tags,omitempty"`
+	// changes to the flag in specific environments
+	Environments map[string]*Override `json:"environments,omitempty" yaml:"environments,omitempty"`
+	// the layers this flag was put together from
+	Sources []string `json:"sources,omitempty" yaml:"sources,omitempty"`
 
 	DefaultRule *Rule `json:"-" yaml:"-"`
 }
@@ -68,6 +76,33 @@ func (f *Flag) Evaluate(evalCtx map[string]any) (any, ResolutionDetails) {
 	return f.value(variant), resolutionDetails
 }
 
+// In returns the flag as it is in the environment
+func (f *Flag) In(env string) *Flag {
+	c := *f
+	c.Environments = nil
+
+	override, ok := f.Environments[env]
+	if !ok || override == nil {
+		return &c
+	}
+
+	if override.Disabled != nil {
+		c.Disabled = override.Disabled
+	}
+
+	// an empty list clears the rules
+	if override.Rules != nil {
+		c.Rules = *override.Rules
+	}
+
+	if len(override.Variants) > 0 {
+		c.Variants = maps.Clone(f.Variants)
+		maps.Copy(c.Variants, override.Variants)
+	}
+
+	return &c
+}
+
 func (f *Flag) IsDisabled() bool {
 	if f.Disabled == nil {
 		return true
@@ -86,6 +121,14 @@ func (f *Flag) value(name string) any {
 	return nil
 }
 
+// Override replaces parts of a flag in one environment. Variants
+// only change the values of variants the flag already has.
+type Override struct {
+	Disabled *bool          `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	Variants map[string]any `json:"variants,omitempty" yaml:"variants,omitempty"`
+	Rules    *[]*Rule       `json:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
 type Rule struct {
 	Name    string `json:"name" yaml:"name"`
 	Variant string `json:"variant" yaml:"variant"`
@@ -120,10 +163,49 @@ type Diff struct {
 	Deleted map[string]*Flag       `json:"deleted"`
 	Added   map[string]*Flag       `json:"added"`
 	Updated map[string]DiffUpdated `json:"updated"`
+	// flags that could not be loaded
+	Failed map[string][]*ValidationError `json:"failed,omitempty"`
 }
 
 func (d *Diff) HasDiff() bool {
-	return len(d.Deleted) > 0 || len(d.Added) > 0 || len(d.Updated) > 0
+	return len(d.Deleted) > 0 || len(d.Added) > 0 || len(d.Updated) > 0 || len(d.Failed) > 0
+}
+
+func NewDiff(old, new map[string]*Flag) Diff {
+	diff := Diff{
+		Deleted: map[string]*Flag{},
+		Added:   map[string]*Flag{},
+		Updated: map[string]DiffUpdated{},
+	}
+
+	for k := range old {
+		nf, ok := new[k]
+		of := old[k]
+
+		// if it's not in new, it needs to be shown as deleted
+		if !ok {
+			diff.Deleted[k] = of
+			continue
+		}
+
+		// if it's not equal, it needs to be shown as updated
+		if !cmp.Equal(of, nf) {
+			diff.Updated[k] = DiffUpdated{
+				Before: of,
+				After:  nf,
+			}
+		}
+	}
+
+	for k := range new {
+		// if not in old, it needs to be shown as added
+		if _, ok := old[k]; !ok {
+			f := new[k]
+			diff.Added[k] = f
+		}
+	}
+
+	return diff
 }
 
 type DiffUpdated struct {
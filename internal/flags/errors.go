@@ -1,7 +1,48 @@
 package flags
 
-import "errors"
+import (
+	"errors"
+	"strings"
+)
 
 var (
-	ErrNotFound = errors.New("flag not found")
+	ErrNotFound        = errors.New("flag not found")
+	ErrPatchTestFailed = errors.New("patch test failed")
+	ErrParse           = errors.New("flag could not be parsed")
 )
+
+// ValidationError locates a problem in a flags document
+// with a JSON pointer (RFC 6901) into that document.
+type ValidationError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// Key is the flag that the error points into
+func (e *ValidationError) Key() string {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(e.Path, "/"), "/")
+
+	segment = strings.ReplaceAll(segment, "~1", "/")
+	segment = strings.ReplaceAll(segment, "~0", "~")
+
+	return segment
+}
+
+func newValidationError(message string, segments ...string) *ValidationError {
+	path := ""
+
+	for _, segment := range segments {
+		segment = strings.ReplaceAll(segment, "~", "~0")
+		segment = strings.ReplaceAll(segment, "/", "~1")
+		path += "/" + segment
+	}
+
+	return &ValidationError{
+		Path:    path,
+		Message: message,
+	}
+}
@@ -3,12 +3,116 @@ package flags
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/antlr4-go/antlr/v4"
+	queryeval "github.com/nikunjy/rules/parser"
 	"gopkg.in/yaml.v3"
 )
 
 func Factory(bs []byte, format string) (map[string]*Flag, error) {
+	flags, errs := Validate(bs, format)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	return flags, nil
+}
+
+// FactoryIn loads the flags as they are in the environment
+func FactoryIn(bs []byte, format string, env string) (map[string]*Flag, error) {
+	flags, err := Factory(bs, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return Resolve(flags, env), nil
+}
+
+// PartitionIn partitions the flags as they are in the environment
+func PartitionIn(bs []byte, format string, env string) (map[string]*Flag, map[string][]*ValidationError, error) {
+	flags, invalid, err := Partition(bs, format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return Resolve(flags, env), invalid, nil
+}
+
+// Resolve applies the overrides of the environment to every flag
+func Resolve(flags map[string]*Flag, env string) map[string]*Flag {
+	resolved := make(map[string]*Flag, len(flags))
+
+	for k, f := range flags {
+		resolved[k] = f.In(env)
+	}
+
+	return resolved
+}
+
+// Definition takes the flag out of its own document and gives it back
+// as JSON with its tags, the way stores with a row per flag keep it
+func Definition(key string, bs []byte, format string) ([]byte, []string, error) {
+	doc := map[string]*Flag{}
+
+	var err error
+
+	switch strings.ToLower(format) {
+	case "json":
+		err = json.Unmarshal(bs, &doc)
+	default:
+		err = yaml.Unmarshal(bs, &doc)
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse flag %s: %w", key, err)
+	}
+
+	flag, ok := doc[key]
+	if !ok || flag == nil {
+		return nil, nil, fmt.Errorf("failed to find flag %s in its document", key)
+	}
+
+	tags := flag.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	def, err := json.Marshal(flag)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return def, tags, nil
+}
+
+// Partition loads every valid flag and sets the invalid ones aside
+// with their errors. Only a document that cannot be parsed is an error.
+func Partition(bs []byte, format string) (map[string]*Flag, map[string][]*ValidationError, error) {
+	flags, errs := Validate(bs, format)
+
+	invalid := map[string][]*ValidationError{}
+
+	for _, err := range errs {
+		if len(err.Path) == 0 {
+			return nil, nil, err
+		}
+
+		invalid[err.Key()] = append(invalid[err.Key()], err)
+	}
+
+	for key := range invalid {
+		delete(flags, key)
+	}
+
+	return flags, invalid, nil
+}
+
+// Validate parses the document and collects every problem it finds
+// instead of stopping at the first one. Errors are ordered by flag key.
+func Validate(bs []byte, format string) (map[string]*Flag, []*ValidationError) {
 	flags := map[string]*Flag{}
 
 	var err error
@@ -21,82 +125,180 @@ func Factory(bs []byte, format string) (map[string]*Flag, error) {
 	}
 
 	if err != nil {
-		return nil, err
+		return nil, []*ValidationError{{Path: "", Message: err.Error()}}
+	}
+
+	keys := make([]string, 0, len(flags))
+
+	for k := range flags {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	errs := []*ValidationError{}
+
+	for _, k := range keys {
+		errs = append(errs, parseFlag(k, flags[k])...)
+	}
+
+	return flags, errs
+}
+
+func parseFlag(key string, flag *Flag) []*ValidationError {
+	errs := []*ValidationError{}
+
+	// sanity checks
+	if flag == nil {
+		return append(errs, newValidationError("nil flag", key))
 	}
 
-	for k, flag := range flags {
-		// sanity checks
-		if flag == nil {
-			return nil, fmt.Errorf("nil flag")
+	if len(key) == 0 {
+		return append(errs, newValidationError("flag missing key", key))
+	}
+
+	// add the default
+	flag.DefaultRule = &Rule{
+		Name:    "default",
+		Variant: "default",
+	}
+
+	// requirements
+	if len(flag.Variants) == 0 {
+		errs = append(errs, newValidationError("flag missing variants", key, "variants"))
+	} else if _, ok := flag.Variants["default"]; !ok {
+		errs = append(errs, newValidationError("flag missing default variant", key, "variants"))
+	}
+
+	errs = append(errs, parseRules(flag.Rules, flag.Variants, key, "rules")...)
+
+	// more complicated requirement checks
+	variantNames := make([]string, 0, len(flag.Variants))
+
+	for name := range flag.Variants {
+		variantNames = append(variantNames, name)
+	}
+
+	sort.Strings(variantNames)
+
+	var variantType string
+
+	for _, name := range variantNames {
+		currentType, err := extractVariantType(flag.Variants[name])
+		if err != nil {
+			errs = append(errs, newValidationError(err.Error(), key, "variants", name))
+			continue
 		}
 
-		if len(k) == 0 {
-			return nil, fmt.Errorf("flag missing key")
+		if len(variantType) == 0 {
+			variantType = currentType
+		} else if currentType != variantType {
+			errs = append(errs, newValidationError("discovered flag variants with different types", key, "variants", name))
 		}
+	}
+
+	envs := make([]string, 0, len(flag.Environments))
+
+	for env := range flag.Environments {
+		envs = append(envs, env)
+	}
 
-		// add the default
-		flag.DefaultRule = &Rule{
-			Name:    "default",
-			Variant: "default",
+	sort.Strings(envs)
+
+	for _, env := range envs {
+		errs = append(errs, parseOverride(flag.Environments[env], flag.Variants, variantType, key, "environments", env)...)
+	}
+
+	return errs
+}
+
+func parseRules(rules []*Rule, variants map[string]any, segments ...string) []*ValidationError {
+	errs := []*ValidationError{}
+
+	at := func(more ...string) []string {
+		return append(append([]string{}, segments...), more...)
+	}
+
+	ruleNames := map[string]any{}
+
+	for i, rule := range rules {
+		index := strconv.Itoa(i)
+
+		if rule == nil {
+			errs = append(errs, newValidationError("nil rule", at(index)...))
+			continue
 		}
 
-		// requirements
-		if len(flag.Variants) == 0 {
-			return nil, fmt.Errorf("flag missing variants")
+		if err := parseRule(rule, variants); err != nil {
+			errs = append(errs, newValidationError(err.Error(), at(index, err.field)...))
 		}
 
-		if _, ok := flag.Variants["default"]; !ok {
-			return nil, fmt.Errorf("flag missing default variant")
+		if _, ok := ruleNames[rule.Name]; ok {
+			errs = append(errs, newValidationError("multiple rules with the same name", at(index, "name")...))
+		} else {
+			ruleNames[rule.Name] = nil
 		}
+	}
+
+	return errs
+}
+
+// parseOverride checks an environment override against the flag it changes
+func parseOverride(override *Override, variants map[string]any, variantType string, segments ...string) []*ValidationError {
+	errs := []*ValidationError{}
+
+	at := func(more ...string) []string {
+		return append(append([]string{}, segments...), more...)
+	}
 
-		ruleNames := map[string]any{}
+	if override == nil {
+		return append(errs, newValidationError("nil environment override", at()...))
+	}
+
+	variantNames := make([]string, 0, len(override.Variants))
+
+	for name := range override.Variants {
+		variantNames = append(variantNames, name)
+	}
 
-		for _, rule := range flag.Rules {
-			if rule == nil {
-				return nil, fmt.Errorf("nil rule")
-			}
+	sort.Strings(variantNames)
 
-			if err := parseRule(rule, flag.Variants); err != nil {
-				return nil, err
-			}
+	for _, name := range variantNames {
+		if _, ok := variants[name]; !ok {
+			errs = append(errs, newValidationError("environment override for unknown variant", at("variants", name)...))
+			continue
+		}
 
-			if _, ok := ruleNames[rule.Name]; ok {
-				return nil, fmt.Errorf("multiple rules with the same name")
-			} else {
-				ruleNames[rule.Name] = nil
-			}
+		currentType, err := extractVariantType(override.Variants[name])
+		if err != nil {
+			errs = append(errs, newValidationError(err.Error(), at("variants", name)...))
+			continue
 		}
 
-		// more complicated requirement checks
-		var variantType string
-		var err error
-
-		for _, variant := range flag.Variants {
-			var currentType string
-
-			if len(variantType) > 0 {
-				currentType, err = extractVariantType(variant)
-				if err != nil {
-					return nil, err
-				}
-				if currentType != variantType {
-					return nil, fmt.Errorf("discovered flag variants with different types")
-				}
-			} else {
-				variantType, err = extractVariantType(variant)
-				if err != nil {
-					return nil, err
-				}
-			}
+		if len(variantType) > 0 && currentType != variantType {
+			errs = append(errs, newValidationError("environment override changes the variant type", at("variants", name)...))
 		}
 	}
 
-	return flags, nil
+	if override.Rules != nil {
+		errs = append(errs, parseRules(*override.Rules, variants, at("rules")...)...)
+	}
+
+	return errs
 }
 
-func parseRule(rule *Rule, variants map[string]any) error {
+type ruleError struct {
+	field   string
+	message string
+}
+
+func (e *ruleError) Error() string {
+	return e.message
+}
+
+func parseRule(rule *Rule, variants map[string]any) *ruleError {
 	if len(rule.Name) == 0 {
-		return fmt.Errorf("rule missing name")
+		return &ruleError{field: "name", message: "rule missing name"}
 	}
 
 	// we need to have exactly one of these but not both
@@ -104,12 +306,60 @@ func parseRule(rule *Rule, variants map[string]any) error {
 	// 2) percentages (with variants that add up to 100)
 
 	if len(rule.Variant) == 0 {
-		return fmt.Errorf("rule missing variant")
+		return &ruleError{field: "variant", message: "rule missing variant"}
 	}
 
 	// if this thing has percentages, check the variants there instead
 	if _, ok := variants[rule.Variant]; !ok {
-		return fmt.Errorf("rule includes unknown variant")
+		return &ruleError{field: "variant", message: "rule includes unknown variant"}
+	}
+
+	if len(rule.Query) > 0 {
+		if err := parseQuery(rule.Query); err != nil {
+			return &ruleError{field: "query", message: fmt.Sprintf("rule includes invalid query: %v", err)}
+		}
+	}
+
+	return nil
+}
+
+type queryErrorListener struct {
+	*antlr.DefaultErrorListener
+	errs []string
+}
+
+func (l *queryErrorListener) SyntaxError(_ antlr.Recognizer, _ any, line, column int, msg string, _ antlr.RecognitionException) {
+	l.errs = append(l.errs, fmt.Sprintf("%d:%d %s", line, column, msg))
+}
+
+func parseQuery(query string) (err error) {
+	// antlr panics on some exceptions
+	defer func() {
+		if info := recover(); info != nil {
+			err = fmt.Errorf("%v", info)
+		}
+	}()
+
+	listener := &queryErrorListener{DefaultErrorListener: antlr.NewDefaultErrorListener()}
+
+	lexer := queryeval.NewJsonQueryLexer(antlr.NewInputStream(query))
+	lexer.RemoveErrorListeners()
+	lexer.AddErrorListener(listener)
+
+	tokens := antlr.NewCommonTokenStream(lexer, antlr.TokenDefaultChannel)
+
+	parser := queryeval.NewJsonQueryParser(tokens)
+	parser.RemoveErrorListeners()
+	parser.AddErrorListener(listener)
+	parser.Query()
+
+	if len(listener.errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(listener.errs, "; "))
+	}
+
+	// the grammar does not anchor to the end of input
+	if tokens.LA(1) != antlr.TokenEOF {
+		return fmt.Errorf("unexpected input at position %d", tokens.LT(1).GetStart())
 	}
 
 	return nil
@@ -0,0 +1,87 @@
+package flags
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+type Patch interface {
+	apply(doc []byte) ([]byte, error)
+}
+
+func (p *DisabledPatch) apply(doc []byte) ([]byte, error) {
+	bs, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonpatch.MergePatch(doc, bs)
+}
+
+// JSONPatch is an RFC 6902 patch against a single flag
+type JSONPatch struct {
+	operations jsonpatch.Patch
+}
+
+func (p *JSONPatch) apply(doc []byte) ([]byte, error) {
+	bs, err := p.operations.Apply(doc)
+	if err != nil && errors.Is(err, jsonpatch.ErrTestFailed) {
+		return nil, fmt.Errorf("%w: %v", ErrPatchTestFailed, err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return bs, nil
+}
+
+func NewJSONPatch(bs []byte) (*JSONPatch, error) {
+	operations, err := jsonpatch.DecodePatch(bs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JSONPatch{operations: operations}, nil
+}
+
+// MergePatch is an RFC 7386 patch against a single flag
+type MergePatch struct {
+	document []byte
+}
+
+func (p *MergePatch) apply(doc []byte) ([]byte, error) {
+	return jsonpatch.MergePatch(doc, p.document)
+}
+
+func NewMergePatch(bs []byte) (*MergePatch, error) {
+	var document map[string]any
+
+	if err := json.Unmarshal(bs, &document); err != nil {
+		return nil, fmt.Errorf("merge patch must be a json object: %v", err)
+	}
+
+	return &MergePatch{document: bs}, nil
+}
+
+// ApplyPatch patches the flag and revalidates the result
+// exactly as if it had been PUT in full.
+func ApplyPatch(key string, flag *Flag, patch Patch) (map[string]*Flag, error) {
+	doc, err := json.Marshal(flag)
+	if err != nil {
+		return nil, err
+	}
+
+	patched, err := patch.apply(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	bs, err := json.Marshal(map[string]json.RawMessage{key: patched})
+	if err != nil {
+		return nil, err
+	}
+
+	return Factory(bs, "json")
+}
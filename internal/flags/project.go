@@ -0,0 +1,76 @@
+package flags
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	// a project's flags are stored as <project>/<key>
+	ProjectSeparator = "/"
+)
+
+type projectKey struct{}
+
+func ContextWithProject(ctx context.Context, project string) context.Context {
+	return context.WithValue(ctx, projectKey{}, project)
+}
+
+// Project returns the project of the caller or an empty string
+// for callers that see every flag under its stored key
+func Project(ctx context.Context) string {
+	project, _ := ctx.Value(projectKey{}).(string)
+	return project
+}
+
+func QualifyKey(project, key string) string {
+	if len(project) == 0 {
+		return key
+	}
+
+	return project + ProjectSeparator + key
+}
+
+// SplitKey returns the project and the key within it
+func SplitKey(key string) (string, string) {
+	project, rest, ok := strings.Cut(key, ProjectSeparator)
+	if !ok {
+		return "", key
+	}
+
+	return project, rest
+}
+
+// InProject keeps the entries of one project under their keys within it
+func InProject[T any](project string, m map[string]T) map[string]T {
+	if len(project) == 0 {
+		return m
+	}
+
+	prefix := project + ProjectSeparator
+
+	in := map[string]T{}
+
+	for k, v := range m {
+		if key, ok := strings.CutPrefix(k, prefix); ok {
+			in[key] = v
+		}
+	}
+
+	return in
+}
+
+// Qualify puts every entry under the project
+func Qualify[T any](project string, m map[string]T) map[string]T {
+	if len(project) == 0 {
+		return m
+	}
+
+	qualified := map[string]T{}
+
+	for k, v := range m {
+		qualified[QualifyKey(project, k)] = v
+	}
+
+	return qualified
+}
@@ -0,0 +1,48 @@
+package apikey
+
+import (
+	"context"
+
+	"github.com/w-h-a/flags/internal/server/clients/authenticator"
+	"github.com/w-h-a/flags/internal/server/config"
+)
+
+const (
+	subject = "api-key"
+)
+
+type client struct {
+	options authenticator.Options
+}
+
+func (c *client) Authenticate(ctx context.Context, token string) (*authenticator.Principal, error) {
+	env, ok := config.APIKeyEnvironment(token)
+	if !ok {
+		return nil, authenticator.ErrUnauthenticated
+	}
+
+	project, _ := config.APIKeyProject(token)
+
+	// static keys are all-powerful within their environment and project
+	principal := &authenticator.Principal{
+		Subject: subject,
+		Roles: map[string]bool{
+			authenticator.RoleRead:  true,
+			authenticator.RoleWrite: true,
+		},
+		Environment: env,
+		Project:     project,
+	}
+
+	return principal, nil
+}
+
+func NewAuthenticator(opts ...authenticator.Option) authenticator.Authenticator {
+	options := authenticator.NewOptions(opts...)
+
+	c := &client{
+		options: options,
+	}
+
+	return c
+}
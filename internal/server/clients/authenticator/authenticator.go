@@ -0,0 +1,14 @@
+package authenticator
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrUnauthenticated = errors.New("not authenticated")
+)
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+}
@@ -0,0 +1,39 @@
+package authenticator
+
+const (
+	RoleRead  string = "read"
+	RoleWrite string = "write"
+)
+
+type Principal struct {
+	Subject string
+	Roles   map[string]bool
+	// empty for principals that work in every environment
+	Environment string
+	// empty for principals that see every flag
+	Project string
+}
+
+func (p *Principal) HasRole(role string) bool {
+	if p == nil {
+		return false
+	}
+
+	return p.Roles[role]
+}
+
+func (p *Principal) InEnvironment(env string) bool {
+	if p == nil {
+		return false
+	}
+
+	return len(p.Environment) == 0 || p.Environment == env
+}
+
+func (p *Principal) InProject(project string) bool {
+	if p == nil {
+		return false
+	}
+
+	return len(p.Project) == 0 || p.Project == project
+}
@@ -0,0 +1,217 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator"
+)
+
+const (
+	refreshInterval = time.Minute
+)
+
+type client struct {
+	options     authenticator.Options
+	httpClient  *http.Client
+	keys        map[string]crypto.PublicKey
+	lastRefresh time.Time
+	mtx         sync.RWMutex
+}
+
+func (c *client) Authenticate(ctx context.Context, token string) (*authenticator.Principal, error) {
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithExpirationRequired(),
+	}
+
+	if len(c.options.Issuer) > 0 {
+		parserOpts = append(parserOpts, jwt.WithIssuer(c.options.Issuer))
+	}
+
+	if len(c.options.Audience) > 0 {
+		parserOpts = append(parserOpts, jwt.WithAudience(c.options.Audience))
+	}
+
+	claims := jwt.MapClaims{}
+
+	if _, err := jwt.ParseWithClaims(token, claims, c.keyFunc(ctx), parserOpts...); err != nil {
+		slog.DebugContext(ctx, "failed to validate jwt", "error", err)
+		return nil, authenticator.ErrUnauthenticated
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || len(subject) == 0 {
+		return nil, authenticator.ErrUnauthenticated
+	}
+
+	principal := &authenticator.Principal{
+		Subject: subject,
+		Roles:   map[string]bool{},
+	}
+
+	for _, role := range c.roles(claims) {
+		switch role {
+		case c.options.WriteRole:
+			principal.Roles[authenticator.RoleWrite] = true
+			principal.Roles[authenticator.RoleRead] = true
+		case c.options.ReadRole:
+			principal.Roles[authenticator.RoleRead] = true
+		}
+	}
+
+	return principal, nil
+}
+
+func (c *client) keyFunc(ctx context.Context) jwt.Keyfunc {
+	return func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		if key, ok := c.lookup(kid); ok {
+			return key, nil
+		}
+
+		// the provider may have rotated its keys
+		if err := c.refresh(ctx, false); err != nil {
+			return nil, err
+		}
+
+		if key, ok := c.lookup(kid); ok {
+			return key, nil
+		}
+
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+}
+
+func (c *client) lookup(kid string) (crypto.PublicKey, bool) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	if len(kid) == 0 && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key, true
+		}
+	}
+
+	key, ok := c.keys[kid]
+
+	return key, ok
+}
+
+func (c *client) refresh(ctx context.Context, force bool) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if !force && time.Since(c.lastRefresh) < refreshInterval {
+		return nil
+	}
+
+	c.lastRefresh = time.Now()
+
+	bs, err := c.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys, err := parseJWKS(bs)
+	if err != nil {
+		return err
+	}
+
+	c.keys = keys
+
+	return nil
+}
+
+func (c *client) load(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(c.options.Location, "http://") && !strings.HasPrefix(c.options.Location, "https://") {
+		return os.ReadFile(c.options.Location)
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		c.options.Location,
+		strings.NewReader(""),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	rsp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %v", err)
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode > 399 {
+		return nil, fmt.Errorf("received status code %d from jwks endpoint", rsp.StatusCode)
+	}
+
+	return io.ReadAll(rsp.Body)
+}
+
+func (c *client) roles(claims jwt.MapClaims) []string {
+	// nested claims (e.g., realm_access.roles) are addressed with dots
+	var value any = map[string]any(claims)
+
+	for _, part := range strings.Split(c.options.RolesClaim, ".") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = m[part]
+	}
+
+	switch v := value.(type) {
+	case string:
+		// space-delimited like the standard scope claim
+		return strings.Fields(v)
+	case []any:
+		roles := []string{}
+		for _, r := range v {
+			if s, ok := r.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+		return roles
+	default:
+		return nil
+	}
+}
+
+func NewAuthenticator(opts ...authenticator.Option) authenticator.Authenticator {
+	options := authenticator.NewOptions(opts...)
+
+	if err := options.Validate(); err != nil {
+		detail := "failed to configure oidc authenticator"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	c := &client{
+		options:    options,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		keys:       map[string]crypto.PublicKey{},
+		mtx:        sync.RWMutex{},
+	}
+
+	if err := c.refresh(context.Background(), true); err != nil {
+		detail := "failed to load jwks for oidc authenticator"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	return c
+}
@@ -0,0 +1,77 @@
+package authenticator
+
+import (
+	"context"
+	"fmt"
+)
+
+type Option func(o *Options)
+
+type Options struct {
+	Location   string
+	Issuer     string
+	Audience   string
+	RolesClaim string
+	ReadRole   string
+	WriteRole  string
+	Context    context.Context
+}
+
+func (o Options) Validate() error {
+	if len(o.Location) == 0 {
+		return fmt.Errorf("missing jwks location")
+	}
+
+	return nil
+}
+
+func WithLocation(location string) Option {
+	return func(o *Options) {
+		o.Location = location
+	}
+}
+
+func WithIssuer(issuer string) Option {
+	return func(o *Options) {
+		o.Issuer = issuer
+	}
+}
+
+func WithAudience(audience string) Option {
+	return func(o *Options) {
+		o.Audience = audience
+	}
+}
+
+func WithRolesClaim(claim string) Option {
+	return func(o *Options) {
+		o.RolesClaim = claim
+	}
+}
+
+func WithReadRole(role string) Option {
+	return func(o *Options) {
+		o.ReadRole = role
+	}
+}
+
+func WithWriteRole(role string) Option {
+	return func(o *Options) {
+		o.WriteRole = role
+	}
+}
+
+func NewOptions(opts ...Option) Options {
+	options := Options{
+		RolesClaim: "roles",
+		ReadRole:   "flags:read",
+		WriteRole:  "flags:write",
+		Context:    context.Background(),
+	}
+
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	return options
+}
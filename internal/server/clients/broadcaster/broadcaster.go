@@ -0,0 +1,8 @@
+package broadcaster
+
+import "context"
+
+type Broadcaster interface {
+	Broadcast(ctx context.Context) error
+	Listen(ctx context.Context) (<-chan struct{}, error)
+}
@@ -0,0 +1,37 @@
+package local
+
+import (
+	"context"
+
+	"github.com/w-h-a/flags/internal/server/clients/broadcaster"
+)
+
+// there are no other instances to tell
+type client struct {
+	options broadcaster.Options
+}
+
+func (c *client) Broadcast(ctx context.Context) error {
+	return nil
+}
+
+func (c *client) Listen(ctx context.Context) (<-chan struct{}, error) {
+	ch := make(chan struct{})
+
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+func NewBroadcaster(opts ...broadcaster.Option) broadcaster.Broadcaster {
+	options := broadcaster.NewOptions(opts...)
+
+	c := &client{
+		options: options,
+	}
+
+	return c
+}
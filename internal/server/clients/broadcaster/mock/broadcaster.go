@@ -0,0 +1,65 @@
+package mock
+
+import (
+	"context"
+	"sync"
+
+	"github.com/w-h-a/flags/internal/server/clients/broadcaster"
+)
+
+type Client struct {
+	options        broadcaster.Options
+	listeners      []chan struct{}
+	broadcastCount int
+	mtx            sync.RWMutex
+}
+
+func (c *Client) Broadcast(ctx context.Context) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.broadcastCount++
+
+	return nil
+}
+
+func (c *Client) Listen(ctx context.Context) (<-chan struct{}, error) {
+	ch := make(chan struct{}, 1)
+
+	c.mtx.Lock()
+	c.listeners = append(c.listeners, ch)
+	c.mtx.Unlock()
+
+	return ch, nil
+}
+
+// Trigger simulates a signal from another instance
+func (c *Client) Trigger() {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	for _, ch := range c.listeners {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (c *Client) BroadcastCount() int {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.broadcastCount
+}
+
+func NewBroadcaster(opts ...broadcaster.Option) broadcaster.Broadcaster {
+	options := broadcaster.NewOptions(opts...)
+
+	c := &Client{
+		options: options,
+		mtx:     sync.RWMutex{},
+	}
+
+	return c
+}
@@ -0,0 +1,46 @@
+package broadcaster
+
+import (
+	"context"
+	"fmt"
+)
+
+type Option func(o *Options)
+
+type Options struct {
+	Location string
+	Prefix   string
+	Context  context.Context
+}
+
+func (o Options) Validate() error {
+	if len(o.Location) == 0 {
+		return fmt.Errorf("missing broadcast location")
+	}
+
+	return nil
+}
+
+func WithLocation(location string) Option {
+	return func(o *Options) {
+		o.Location = location
+	}
+}
+
+func WithPrefix(prefix string) Option {
+	return func(o *Options) {
+		o.Prefix = prefix
+	}
+}
+
+func NewOptions(opts ...Option) Options {
+	options := Options{
+		Context: context.Background(),
+	}
+
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	return options
+}
@@ -10,7 +10,7 @@ import (
 
 const (
 	FilenameTemplate = "flag-evaluation-{{ .Timestamp }}.{{ .Format }}"
-	CsvTemplate      = "{{ .CreationDate }};{{ .Key }};{{ .Value }};{{ .Variant }};{{ .Reason }};{{ .ErrorCode }};{{ .ErrorMessage }}\n"
+	CsvTemplate      = "{{ .CreationDate }};{{ .Key }};{{ .Value }};{{ .Variant }};{{ .Reason }};{{ .ErrorCode }};{{ .ErrorMessage }};{{ .Project }};{{ .Environment }}\n"
 )
 
 type Parser struct {
@@ -33,6 +33,12 @@ func (c *client) Notify(ctx context.Context, diff flags.Diff) error {
 		slog.InfoContext(ctx, "flag is updated", "flag", k)
 	}
 
+	for k, errs := range diff.Failed {
+		for _, err := range errs {
+			slog.WarnContext(ctx, "flag failed to load", "flag", k, "path", err.Path, "error", err.Message)
+		}
+	}
+
 	return nil
 }
 
@@ -11,6 +11,7 @@ import (
 type Client struct {
 	options   notifier.Options
 	wasCalled bool
+	diffs     []flags.Diff
 	mtx       sync.RWMutex
 }
 
@@ -19,10 +20,18 @@ func (c *Client) Notify(ctx context.Context, diff flags.Diff) error {
 	defer c.mtx.Unlock()
 
 	c.wasCalled = true
+	c.diffs = append(c.diffs, diff)
 
 	return nil
 }
 
+func (c *Client) Diffs() []flags.Diff {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return append([]flags.Diff{}, c.diffs...)
+}
+
 func (c *Client) WasCalled() bool {
 	c.mtx.RLock()
 	defer c.mtx.RUnlock()
@@ -0,0 +1,88 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server/clients/notifier"
+)
+
+type client struct {
+	options  notifier.Options
+	fallback notifier.Notifier
+	routes   map[string]notifier.Notifier
+}
+
+func (c *client) Notify(ctx context.Context, diff flags.Diff) error {
+	diffs := map[notifier.Notifier]*flags.Diff{}
+
+	part := func(key string) *flags.Diff {
+		project, _ := flags.SplitKey(key)
+
+		n, ok := c.routes[project]
+		if !ok {
+			n = c.fallback
+		}
+
+		if _, ok := diffs[n]; !ok {
+			diffs[n] = &flags.Diff{
+				Deleted: map[string]*flags.Flag{},
+				Added:   map[string]*flags.Flag{},
+				Updated: map[string]flags.DiffUpdated{},
+				Failed:  map[string][]*flags.ValidationError{},
+			}
+		}
+
+		return diffs[n]
+	}
+
+	for k, f := range diff.Deleted {
+		part(k).Deleted[k] = f
+	}
+
+	for k, f := range diff.Added {
+		part(k).Added[k] = f
+	}
+
+	for k, u := range diff.Updated {
+		part(k).Updated[k] = u
+	}
+
+	for k, errs := range diff.Failed {
+		part(k).Failed[k] = errs
+	}
+
+	var errs []error
+
+	for n, d := range diffs {
+		if err := n.Notify(ctx, *d); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func NewNotifier(opts ...notifier.Option) notifier.Notifier {
+	options := notifier.NewOptions(opts...)
+
+	fallback, ok := Notifier(options.Context)
+	if !ok || fallback == nil {
+		detail := "failed to configure routing notifier"
+		slog.ErrorContext(context.Background(), detail, "error", fmt.Errorf("missing notifier"))
+		panic(detail)
+	}
+
+	routes, _ := Routes(options.Context)
+
+	c := &client{
+		options:  options,
+		fallback: fallback,
+		routes:   routes,
+	}
+
+	return c
+}
@@ -0,0 +1,42 @@
+package routing
+
+import (
+	"context"
+	"maps"
+
+	"github.com/w-h-a/flags/internal/server/clients/notifier"
+)
+
+type fallbackKey struct{}
+
+// WithNotifier sets the notifier for flags outside of a routed project
+func WithNotifier(n notifier.Notifier) notifier.Option {
+	return func(o *notifier.Options) {
+		o.Context = context.WithValue(o.Context, fallbackKey{}, n)
+	}
+}
+
+func Notifier(ctx context.Context) (notifier.Notifier, bool) {
+	n, ok := ctx.Value(fallbackKey{}).(notifier.Notifier)
+	return n, ok
+}
+
+type routesKey struct{}
+
+// WithRoute sends the changes to one project's flags to their own notifier
+func WithRoute(project string, n notifier.Notifier) notifier.Option {
+	return func(o *notifier.Options) {
+		routes, _ := Routes(o.Context)
+		routes = maps.Clone(routes)
+		if routes == nil {
+			routes = map[string]notifier.Notifier{}
+		}
+		routes[project] = n
+		o.Context = context.WithValue(o.Context, routesKey{}, routes)
+	}
+}
+
+func Routes(ctx context.Context) (map[string]notifier.Notifier, bool) {
+	routes, ok := ctx.Value(routesKey{}).(map[string]notifier.Notifier)
+	return routes, ok
+}
@@ -20,6 +20,7 @@ const (
 	colorDeleted = "#FF0000"
 	colorAdded   = "#008000"
 	colorUpdated = "#FFA500"
+	colorFailed  = "#8B0000"
 )
 
 type client struct {
@@ -65,6 +66,7 @@ func (c *client) convert(diff flags.Diff) slackMessage {
 	attachments := c.convertDeleted(diff)
 	attachments = append(attachments, c.convertAdded(diff)...)
 	attachments = append(attachments, c.convertUpdated(diff)...)
+	attachments = append(attachments, c.convertFailed(diff)...)
 
 	result := slackMessage{
 		Text:         "Changes detected in feature flags",
@@ -141,6 +143,31 @@ func (c *client) convertUpdated(diff flags.Diff) []attachment {
 	return attachments
 }
 
+func (c *client) convertFailed(diff flags.Diff) []attachment {
+	attachments := []attachment{}
+
+	emoji := "🚨"
+
+	for k, errs := range diff.Failed {
+		attachment := attachment{
+			Title:  fmt.Sprintf("%s Flag \"%s\" failed to load", emoji, k),
+			Color:  colorFailed,
+			Fields: []field{},
+		}
+
+		for _, err := range errs {
+			attachment.Fields = append(
+				attachment.Fields,
+				field{Title: err.Path, Value: err.Message},
+			)
+		}
+
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments
+}
+
 func NewNotifier(opts ...notifier.Option) notifier.Notifier {
 	options := notifier.NewOptions(opts...)
 
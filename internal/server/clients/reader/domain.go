@@ -4,3 +4,13 @@ type Record struct {
 	Key   string
 	Value []byte
 }
+
+type Changes struct {
+	// a flags document with only the flags that changed
+	Changed []byte
+	Removed []string
+	// Changed holds every flag and replaces what came before
+	Full bool
+	// pass this to the next ReadChanges
+	Cursor string
+}
@@ -0,0 +1,21 @@
+package dynamodb
+
+import (
+	"context"
+
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+)
+
+type streamKey struct{}
+
+// WithStream refreshes from the table's stream instead of scanning it
+func WithStream(stream bool) reader.Option {
+	return func(o *reader.Options) {
+		o.Context = context.WithValue(o.Context, streamKey{}, stream)
+	}
+}
+
+func Stream(ctx context.Context) (bool, bool) {
+	stream, ok := ctx.Value(streamKey{}).(bool)
+	return stream, ok
+}
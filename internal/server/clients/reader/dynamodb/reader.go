@@ -4,12 +4,14 @@ import (
 	"context"
 	"log/slog"
 	"strings"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
 	"github.com/w-h-a/flags/internal/server/clients/reader"
 	"github.com/w-h-a/flags/internal/server/config"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-sdk-go-v2/otelaws"
@@ -126,6 +128,17 @@ func NewReader(opts ...reader.Option) reader.Reader {
 
 	c.conn = conn
 
+	stream, _ := Stream(options.Context)
+
+	var streamSpecification *types.StreamSpecification
+
+	if stream {
+		streamSpecification = &types.StreamSpecification{
+			StreamEnabled:  aws.Bool(true),
+			StreamViewType: types.StreamViewTypeNewImage,
+		}
+	}
+
 	if _, err := c.conn.CreateTable(
 		context.Background(),
 		&dynamodb.CreateTableInput{
@@ -146,6 +159,7 @@ func NewReader(opts ...reader.Option) reader.Reader {
 				ReadCapacityUnits:  aws.Int64(10),
 				WriteCapacityUnits: aws.Int64(5),
 			},
+			StreamSpecification: streamSpecification,
 		},
 	); err != nil && !strings.Contains(err.Error(), "ResourceInUseException") {
 		detail := "failed to create table for dynamodb reader"
@@ -153,5 +167,38 @@ func NewReader(opts ...reader.Option) reader.Reader {
 		panic(detail)
 	}
 
-	return c
+	if !stream {
+		return c
+	}
+
+	rsp, err := c.conn.DescribeTable(
+		context.Background(),
+		&dynamodb.DescribeTableInput{
+			TableName: aws.String(table),
+		},
+	)
+	if err != nil {
+		detail := "failed to describe table for dynamodb reader"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	// tables created before streams were asked for keep scanning
+	specification := rsp.Table.StreamSpecification
+	if rsp.Table.LatestStreamArn == nil || specification == nil || !aws.ToBool(specification.StreamEnabled) || specification.StreamViewType == types.StreamViewTypeKeysOnly || specification.StreamViewType == types.StreamViewTypeOldImage {
+		slog.WarnContext(context.Background(), "dynamodb table has no stream with new images, falling back to scans", "table", table)
+		return c
+	}
+
+	return &streamClient{
+		client: c,
+		streams: dynamodbstreams.NewFromConfig(
+			AWSCFG,
+			func(o *dynamodbstreams.Options) {
+				o.EndpointResolverV2 = &streamResolver{location: options.Location}
+			},
+		),
+		arn: aws.ToString(rsp.Table.LatestStreamArn),
+		mtx: sync.Mutex{},
+	}
 }
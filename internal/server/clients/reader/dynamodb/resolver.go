@@ -5,6 +5,7 @@ import (
 	"net/url"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
 	transport "github.com/aws/smithy-go/endpoints"
 )
 
@@ -22,3 +23,18 @@ func (r *resolver) ResolveEndpoint(ctx context.Context, params dynamodb.Endpoint
 		URI: *u,
 	}, nil
 }
+
+type streamResolver struct {
+	location string
+}
+
+func (r *streamResolver) ResolveEndpoint(ctx context.Context, params dynamodbstreams.EndpointParameters) (transport.Endpoint, error) {
+	u, err := url.Parse(r.location)
+	if err != nil {
+		return transport.Endpoint{}, err
+	}
+
+	return transport.Endpoint{
+		URI: *u,
+	}, nil
+}
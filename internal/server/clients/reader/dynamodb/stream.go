@@ -0,0 +1,228 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+)
+
+// position is how far into the stream a batch of changes reaches
+type position struct {
+	// open shards and where we are in them
+	iterators map[string]*string
+	// shards we have read to the end
+	done map[string]bool
+}
+
+// streamClient follows the table's stream so that a refresh
+// only reads the flags that changed instead of the whole table
+type streamClient struct {
+	*client
+	streams *dynamodbstreams.Client
+	arn     string
+	// where the last batch the cache took ends
+	committed *position
+	// where the last batch we handed out ends
+	pending *position
+	// the cursor of the committed position, and the
+	// pending position's cursor is the one after it
+	generation int
+	mtx        sync.Mutex
+}
+
+func (s *streamClient) ReadChanges(ctx context.Context, cursor string) (*reader.Changes, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	switch {
+	case s.committed == nil:
+		return s.reload(ctx)
+	case s.pending != nil && cursor == strconv.Itoa(s.generation+1):
+		// the cache took the last batch
+		s.committed = s.pending
+		s.generation++
+	case cursor != strconv.Itoa(s.generation):
+		return s.reload(ctx)
+	}
+
+	// a batch the cache refused is read again from where it started
+	s.pending = nil
+
+	next := &position{
+		iterators: maps.Clone(s.committed.iterators),
+		done:      maps.Clone(s.committed.done),
+	}
+
+	if err := s.discover(ctx, next, types.ShardIteratorTypeTrimHorizon); err != nil {
+		return nil, err
+	}
+
+	changed := map[string][]byte{}
+	removed := map[string]bool{}
+
+	for shard, iterator := range next.iterators {
+		for iterator != nil {
+			rsp, err := s.streams.GetRecords(ctx, &dynamodbstreams.GetRecordsInput{
+				ShardIterator: iterator,
+			})
+
+			var expired *types.ExpiredIteratorException
+			var trimmed *types.TrimmedDataAccessException
+
+			if errors.As(err, &expired) || errors.As(err, &trimmed) {
+				// we fell too far behind the stream
+				return s.reload(ctx)
+			} else if err != nil {
+				return nil, err
+			}
+
+			for _, record := range rsp.Records {
+				key, ok := record.Dynamodb.Keys["Key"].(*types.AttributeValueMemberS)
+				if !ok {
+					continue
+				}
+
+				if record.EventName == types.OperationTypeRemove {
+					delete(changed, key.Value)
+					removed[key.Value] = true
+					continue
+				}
+
+				value, ok := record.Dynamodb.NewImage["Value"].(*types.AttributeValueMemberB)
+				if !ok {
+					continue
+				}
+
+				delete(removed, key.Value)
+				changed[key.Value] = value.Value
+			}
+
+			iterator = rsp.NextShardIterator
+
+			if len(rsp.Records) == 0 {
+				break
+			}
+		}
+
+		if iterator == nil {
+			delete(next.iterators, shard)
+			next.done[shard] = true
+		} else {
+			next.iterators[shard] = iterator
+		}
+	}
+
+	values := make([][]byte, 0, len(changed))
+
+	for _, value := range changed {
+		values = append(values, value)
+	}
+
+	bs := []byte{}
+
+	if len(values) > 0 {
+		var err error
+		if bs, err = reader.JoinRecords(values, s.options.Format); err != nil {
+			return nil, err
+		}
+	}
+
+	// only a cache that took this batch comes back with its cursor
+	s.pending = next
+
+	changes := &reader.Changes{
+		Changed: bs,
+		Cursor:  strconv.Itoa(s.generation + 1),
+	}
+
+	for key := range removed {
+		changes.Removed = append(changes.Removed, key)
+	}
+
+	return changes, nil
+}
+
+// reload scans the table after marking where the stream is
+// so that nothing written during the scan is missed
+func (s *streamClient) reload(ctx context.Context) (*reader.Changes, error) {
+	next := &position{
+		iterators: map[string]*string{},
+		done:      map[string]bool{},
+	}
+
+	if err := s.discover(ctx, next, types.ShardIteratorTypeLatest); err != nil {
+		return nil, err
+	}
+
+	bs, err := s.client.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.committed = next
+	s.pending = nil
+	s.generation++
+
+	return &reader.Changes{
+		Changed: bs,
+		Full:    true,
+		Cursor:  strconv.Itoa(s.generation),
+	}, nil
+}
+
+// discover starts reading shards we have not seen yet. A child shard
+// waits for its parent so that changes to a flag stay in order.
+func (s *streamClient) discover(ctx context.Context, pos *position, from types.ShardIteratorType) error {
+	var start *string
+
+	for {
+		rsp, err := s.streams.DescribeStream(ctx, &dynamodbstreams.DescribeStreamInput{
+			StreamArn:             aws.String(s.arn),
+			ExclusiveStartShardId: start,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, shard := range rsp.StreamDescription.Shards {
+			id := aws.ToString(shard.ShardId)
+
+			if _, ok := pos.iterators[id]; ok || pos.done[id] {
+				continue
+			}
+
+			// the scan already has what closed shards hold
+			if from == types.ShardIteratorTypeLatest && shard.SequenceNumberRange.EndingSequenceNumber != nil {
+				pos.done[id] = true
+				continue
+			}
+
+			if _, ok := pos.iterators[aws.ToString(shard.ParentShardId)]; ok {
+				continue
+			}
+
+			iterator, err := s.streams.GetShardIterator(ctx, &dynamodbstreams.GetShardIteratorInput{
+				StreamArn:         aws.String(s.arn),
+				ShardId:           shard.ShardId,
+				ShardIteratorType: from,
+			})
+			if err != nil {
+				return err
+			}
+
+			pos.iterators[id] = iterator.ShardIterator
+		}
+
+		start = rsp.StreamDescription.LastEvaluatedShardId
+		if start == nil {
+			return nil
+		}
+	}
+}
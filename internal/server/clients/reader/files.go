@@ -0,0 +1,83 @@
+package reader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File is one of several flags documents that together make up the flags
+type File struct {
+	Name string
+	Data []byte
+}
+
+// IsPattern tells a glob apart from a plain path
+func IsPattern(location string) bool {
+	return strings.ContainsAny(location, "*?[")
+}
+
+// IsFlagsFile tells whether a file in a directory holds flags
+func IsFlagsFile(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	default:
+		return false
+	}
+}
+
+// MergeFiles combines documents in either format, detected by extension,
+// into one document in the given format. A flag may only be defined once.
+func MergeFiles(files []File, format string) ([]byte, error) {
+	merged := map[string]any{}
+	definedIn := map[string]string{}
+
+	for _, file := range files {
+		// a team without any flags yet
+		if len(bytes.TrimSpace(file.Data)) == 0 {
+			continue
+		}
+
+		doc := map[string]any{}
+
+		var err error
+
+		switch strings.ToLower(path.Ext(file.Name)) {
+		case ".json":
+			err = json.Unmarshal(file.Data, &doc)
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(file.Data, &doc)
+		default:
+			if strings.ToLower(format) == "json" {
+				err = json.Unmarshal(file.Data, &doc)
+			} else {
+				err = yaml.Unmarshal(file.Data, &doc)
+			}
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file.Name, err)
+		}
+
+		for key, flag := range doc {
+			if other, ok := definedIn[key]; ok {
+				return nil, fmt.Errorf("flag %q is defined in both %s and %s", key, other, file.Name)
+			}
+
+			merged[key] = flag
+			definedIn[key] = file.Name
+		}
+	}
+
+	switch strings.ToLower(format) {
+	case "json":
+		return json.Marshal(merged)
+	default:
+		return yaml.Marshal(merged)
+	}
+}
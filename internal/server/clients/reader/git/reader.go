@@ -0,0 +1,124 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+)
+
+const (
+	remoteName = "origin"
+)
+
+type client struct {
+	options reader.Options
+	cloned  bool
+	mtx     sync.Mutex
+}
+
+func (c *client) ReadByKey(ctx context.Context, key string) ([]byte, error) {
+	bs, err := c.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return reader.ExtractKey(bs, key, c.options.Format)
+}
+
+func (c *client) Read(ctx context.Context) ([]byte, error) {
+	if err := c.clone(ctx); err != nil {
+		return nil, err
+	}
+
+	// read what is committed rather than the working copy
+	ref := c.options.Branch
+
+	if len(c.options.Remote) > 0 {
+		if _, err := c.git(ctx, "fetch", "--quiet", remoteName, c.options.Branch); err != nil {
+			return nil, err
+		}
+
+		ref = remoteName + "/" + c.options.Branch
+	}
+
+	return c.git(ctx, "show", fmt.Sprintf("%s:%s", ref, filepath.ToSlash(c.options.Path)))
+}
+
+// clone gets a working copy on the first read rather than at startup
+// so that a remote that is down does not keep the server from starting
+func (c *client) clone(ctx context.Context) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.cloned {
+		return nil
+	}
+
+	if _, err := os.Stat(filepath.Join(c.options.Location, ".git")); err != nil {
+		if _, err := run(ctx, "", "clone", "--quiet", "--no-checkout", "--origin", remoteName, "--branch", c.options.Branch, c.options.Remote, c.options.Location); err != nil {
+			return err
+		}
+	}
+
+	c.cloned = true
+
+	return nil
+}
+
+func (c *client) git(ctx context.Context, args ...string) ([]byte, error) {
+	return run(ctx, c.options.Location, args...)
+}
+
+func run(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+func NewReader(opts ...reader.Option) reader.Reader {
+	options := reader.NewOptions(opts...)
+
+	if err := options.Validate(); err != nil {
+		detail := "failed to configure git reader"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	if len(options.Branch) == 0 || len(options.Path) == 0 {
+		detail := "failed to configure git reader"
+		slog.ErrorContext(context.Background(), detail, "error", fmt.Errorf("missing branch or path"))
+		panic(detail)
+	}
+
+	c := &client{
+		options: options,
+		mtx:     sync.Mutex{},
+	}
+
+	// without a remote there is nothing to clone later
+	if _, err := os.Stat(filepath.Join(c.options.Location, ".git")); err != nil && len(c.options.Remote) == 0 {
+		detail := "failed to find git repository for git reader"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	return c
+}
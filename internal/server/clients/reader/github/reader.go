@@ -1,33 +1,107 @@
 package github
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"path"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/w-h-a/flags/internal/server/clients/reader"
 )
 
+// entry is one item of a contents directory listing
+type entry struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
 type client struct {
 	options    reader.Options
 	httpClient *http.Client
 }
 
 func (c *client) ReadByKey(ctx context.Context, key string) ([]byte, error) {
-	return nil, nil
+	bs, err := c.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return reader.ExtractKey(bs, key, c.options.Format)
 }
 
 func (c *client) Read(ctx context.Context) ([]byte, error) {
 	// Github location:
 	// https://api.github.com/repos/:owner/:repo/contents/:filePath?ref=main
+	// where the file path may also be a directory or end in a glob
+	location := c.options.Location
+	pattern := ""
+
+	if u, err := url.Parse(location); err == nil && reader.IsPattern(u.Path) {
+		pattern = path.Base(u.Path)
+		u.Path = path.Dir(u.Path)
+		u.RawPath = ""
+		location = u.String()
+	}
+
+	bs, err := c.get(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+
+	// a directory comes back as a listing instead of the raw file
+	if len(pattern) == 0 && !bytes.HasPrefix(bytes.TrimSpace(bs), []byte("[")) {
+		return bs, nil
+	}
+
+	entries := []entry{}
+
+	if err := json.Unmarshal(bs, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse directory listing from github: %v", err)
+	}
+
+	files := []reader.File{}
+
+	for _, e := range entries {
+		if e.Type != "file" {
+			continue
+		}
+
+		if len(pattern) > 0 {
+			if ok, _ := path.Match(pattern, e.Name); !ok {
+				continue
+			}
+		} else if !reader.IsFlagsFile(e.Name) {
+			continue
+		}
+
+		bs, err := c.get(ctx, e.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, reader.File{Name: e.Path, Data: bs})
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	return reader.MergeFiles(files, c.options.Format)
+}
+
+func (c *client) get(ctx context.Context, location string) ([]byte, error) {
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodGet,
-		c.options.Location,
+		location,
 		strings.NewReader(""),
 	)
 	if err != nil {
@@ -47,6 +121,10 @@ func (c *client) Read(ctx context.Context) ([]byte, error) {
 
 	defer rsp.Body.Close()
 
+	if err := reader.RateLimit(rsp, time.Now()); err != nil {
+		return nil, err
+	}
+
 	if rsp.StatusCode > 399 {
 		return nil, fmt.Errorf("received status code %d from github", rsp.StatusCode)
 	}
@@ -2,36 +2,138 @@ package gitlab
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"path"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/w-h-a/flags/internal/server/clients/reader"
 )
 
+// entry is one item of a repository tree listing
+type entry struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Type string `json:"type"`
+}
+
 type client struct {
 	options    reader.Options
 	httpClient *http.Client
 }
 
 func (c *client) ReadByKey(ctx context.Context, key string) ([]byte, error) {
-	return nil, nil
+	bs, err := c.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return reader.ExtractKey(bs, key, c.options.Format)
 }
 
 func (c *client) Read(ctx context.Context) ([]byte, error) {
 	// Gitlab location:
 	// https://gitlab.com/api/v4/projects/:id/repository/files/:filePath/raw?ref=main
+	// or for a directory or a glob:
+	// https://gitlab.com/api/v4/projects/:id/repository/tree?path=:dir/*.yaml&ref=main
+	u, err := url.Parse(c.options.Location)
+	if err != nil || !strings.HasSuffix(u.Path, "/repository/tree") {
+		bs, _, err := c.get(ctx, c.options.Location)
+		return bs, err
+	}
+
+	query := u.Query()
+
+	dir := query.Get("path")
+	pattern := ""
+
+	if reader.IsPattern(dir) {
+		pattern = path.Base(dir)
+		dir = path.Dir(dir)
+	}
+
+	query.Set("path", dir)
+	query.Set("per_page", "100")
+
+	files := []reader.File{}
+
+	for page := "1"; len(page) > 0; {
+		query.Set("page", page)
+		u.RawQuery = query.Encode()
+
+		bs, header, err := c.get(ctx, u.String())
+		if err != nil {
+			return nil, err
+		}
+
+		entries := []entry{}
+
+		if err := json.Unmarshal(bs, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse directory listing from gitlab: %v", err)
+		}
+
+		for _, e := range entries {
+			if e.Type != "blob" {
+				continue
+			}
+
+			if len(pattern) > 0 {
+				if ok, _ := path.Match(pattern, e.Name); !ok {
+					continue
+				}
+			} else if !reader.IsFlagsFile(e.Name) {
+				continue
+			}
+
+			bs, _, err := c.get(ctx, c.fileURL(u, e.Path))
+			if err != nil {
+				return nil, err
+			}
+
+			files = append(files, reader.File{Name: e.Path, Data: bs})
+		}
+
+		page = header.Get("x-next-page")
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	return reader.MergeFiles(files, c.options.Format)
+}
+
+// fileURL points the files API at a path from a tree listing
+func (c *client) fileURL(tree *url.URL, filePath string) string {
+	prefix := strings.TrimSuffix(tree.Path, "/tree")
+
+	u := *tree
+	u.Path = prefix + "/files/" + filePath + "/raw"
+	u.RawPath = prefix + "/files/" + url.PathEscape(filePath) + "/raw"
+
+	query := url.Values{}
+	if ref := tree.Query().Get("ref"); len(ref) > 0 {
+		query.Set("ref", ref)
+	}
+
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+func (c *client) get(ctx context.Context, location string) ([]byte, http.Header, error) {
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodGet,
-		c.options.Location,
+		location,
 		strings.NewReader(""),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+		return nil, nil, fmt.Errorf("failed to create request: %v", err)
 	}
 
 	if len(c.options.Token) > 0 {
@@ -40,16 +142,22 @@ func (c *client) Read(ctx context.Context) ([]byte, error) {
 
 	rsp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %v", err)
+		return nil, nil, fmt.Errorf("failed to make request: %v", err)
 	}
 
 	defer rsp.Body.Close()
 
+	if err := reader.RateLimit(rsp, time.Now()); err != nil {
+		return nil, nil, err
+	}
+
 	if rsp.StatusCode > 399 {
-		return nil, fmt.Errorf("received status code %d from github", rsp.StatusCode)
+		return nil, nil, fmt.Errorf("received status code %d from gitlab", rsp.StatusCode)
 	}
 
-	return io.ReadAll(rsp.Body)
+	bs, err := io.ReadAll(rsp.Body)
+
+	return bs, rsp.Header, err
 }
 
 func NewReader(opts ...reader.Option) reader.Reader {
@@ -0,0 +1,189 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+)
+
+type client struct {
+	options      reader.Options
+	httpClient   *http.Client
+	body         []byte
+	etag         string
+	lastModified string
+	// what the cache last accepted
+	served string
+	// what the cache was given but has not accepted yet
+	pending string
+	mtx     sync.Mutex
+}
+
+func (c *client) ReadByKey(ctx context.Context, key string) ([]byte, error) {
+	bs, err := c.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return reader.ExtractKey(bs, key, c.options.Format)
+}
+
+func (c *client) Read(ctx context.Context) ([]byte, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if err := c.fetch(ctx); err != nil {
+		return nil, err
+	}
+
+	return c.body, nil
+}
+
+func (c *client) ReadIfModified(ctx context.Context) ([]byte, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.pending = ""
+
+	if err := c.fetch(ctx); err != nil {
+		return nil, err
+	}
+
+	signature := c.signature()
+
+	if signature == c.served {
+		return nil, reader.ErrNotModified
+	}
+
+	c.pending = signature
+
+	return c.body, nil
+}
+
+func (c *client) Ack(ctx context.Context) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if len(c.pending) > 0 {
+		c.served = c.pending
+		c.pending = ""
+	}
+}
+
+func (c *client) fetch(ctx context.Context) error {
+	var err error
+
+	for attempt := 0; attempt <= c.options.Retries; attempt++ {
+		if attempt > 0 {
+			// 250ms, 500ms, 1s, ...
+			backoff := time.Duration(250<<(attempt-1)) * time.Millisecond
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+		}
+
+		var retry bool
+
+		retry, err = c.get(ctx)
+		if err == nil || !retry {
+			return err
+		}
+
+		slog.WarnContext(ctx, "failed to fetch flags", "location", c.options.Location, "attempt", attempt+1, "error", err)
+	}
+
+	return err
+}
+
+// get reports whether a failed request is worth retrying
+func (c *client) get(ctx context.Context) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.options.Location, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	if len(c.options.Token) > 0 {
+		req.Header.Set("authorization", fmt.Sprintf("Bearer %s", c.options.Token))
+	}
+
+	for k, v := range c.options.Headers {
+		req.Header.Set(k, v)
+	}
+
+	if c.body != nil {
+		if len(c.etag) > 0 {
+			req.Header.Set("if-none-match", c.etag)
+		}
+
+		if len(c.lastModified) > 0 {
+			req.Header.Set("if-modified-since", c.lastModified)
+		}
+	}
+
+	rsp, err := c.httpClient.Do(req)
+	if err != nil {
+		return !errors.Is(ctx.Err(), context.Canceled), fmt.Errorf("failed to make request: %v", err)
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode == http.StatusNotModified && c.body != nil {
+		return false, nil
+	}
+
+	if rsp.StatusCode > 399 {
+		retry := rsp.StatusCode == http.StatusTooManyRequests || rsp.StatusCode > 499
+		return retry, fmt.Errorf("received status code %d", rsp.StatusCode)
+	}
+
+	body, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return true, fmt.Errorf("failed to read response: %v", err)
+	}
+
+	c.body = body
+	c.etag = rsp.Header.Get("etag")
+	c.lastModified = rsp.Header.Get("last-modified")
+
+	return false, nil
+}
+
+func (c *client) signature() string {
+	if len(c.etag) > 0 || len(c.lastModified) > 0 {
+		return c.etag + "\n" + c.lastModified
+	}
+
+	// servers without validators still send the same bytes
+	return fmt.Sprintf("%x", sha256.Sum256(c.body))
+}
+
+func NewReader(opts ...reader.Option) reader.Reader {
+	options := reader.NewOptions(opts...)
+
+	if err := options.Validate(); err != nil {
+		detail := "failed to configure http reader"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	c := &client{
+		options: options,
+		httpClient: &http.Client{
+			Timeout: options.Timeout,
+		},
+		mtx: sync.Mutex{},
+	}
+
+	return c
+}
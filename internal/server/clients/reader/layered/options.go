@@ -0,0 +1,48 @@
+package layered
+
+import (
+	"context"
+
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+)
+
+const (
+	MergeFlag  = "flag"
+	MergeField = "field"
+)
+
+type Layer struct {
+	Name   string
+	Reader reader.Reader
+}
+
+type layersKey struct{}
+
+// WithLayer adds a source on top of the ones added before it
+func WithLayer(name string, r reader.Reader) reader.Option {
+	return func(o *reader.Options) {
+		layers, _ := Layers(o.Context)
+		layers = append(append([]Layer{}, layers...), Layer{Name: name, Reader: r})
+		o.Context = context.WithValue(o.Context, layersKey{}, layers)
+	}
+}
+
+func Layers(ctx context.Context) ([]Layer, bool) {
+	layers, ok := ctx.Value(layersKey{}).([]Layer)
+	return layers, ok
+}
+
+type mergeKey struct{}
+
+// WithMerge chooses between replacing whole flags and replacing
+// only the fields that a later source sets
+func WithMerge(merge string) reader.Option {
+	return func(o *reader.Options) {
+		o.Context = context.WithValue(o.Context, mergeKey{}, merge)
+	}
+}
+
+func Merge(ctx context.Context) (string, bool) {
+	merge, ok := ctx.Value(mergeKey{}).(string)
+	return merge, ok
+}
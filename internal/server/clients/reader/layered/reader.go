@@ -0,0 +1,197 @@
+package layered
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	"gopkg.in/yaml.v3"
+)
+
+type client struct {
+	options reader.Options
+	layers  []Layer
+	merge   string
+}
+
+func (c *client) ReadByKey(ctx context.Context, key string) ([]byte, error) {
+	bs, err := c.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return reader.ExtractKey(bs, key, c.options.Format)
+}
+
+func (c *client) Read(ctx context.Context) ([]byte, error) {
+	merged := map[string]map[string]any{}
+	sources := map[string][]string{}
+
+	// later layers take precedence
+	for _, layer := range c.layers {
+		bs, err := layer.Reader.Read(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read layer %s: %w", layer.Name, err)
+		}
+
+		doc, err := c.decode(bs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse layer %s: %w", layer.Name, err)
+		}
+
+		for key, fields := range doc {
+			if fields == nil {
+				continue
+			}
+
+			delete(fields, "sources")
+
+			existing, ok := merged[key]
+			if !ok || c.merge != MergeField {
+				merged[key] = fields
+				sources[key] = []string{layer.Name}
+				continue
+			}
+
+			for field, value := range fields {
+				existing[field] = value
+			}
+
+			sources[key] = append(sources[key], layer.Name)
+		}
+	}
+
+	for key, fields := range merged {
+		fields["sources"] = sources[key]
+	}
+
+	switch strings.ToLower(c.options.Format) {
+	case "json":
+		return json.Marshal(merged)
+	default:
+		return yaml.Marshal(merged)
+	}
+}
+
+func (c *client) decode(bs []byte) (map[string]map[string]any, error) {
+	doc := map[string]map[string]any{}
+
+	// a source without any flags yet
+	if len(bytes.TrimSpace(bs)) == 0 {
+		return doc, nil
+	}
+
+	var err error
+
+	switch strings.ToLower(c.options.Format) {
+	case "json":
+		err = json.Unmarshal(bs, &doc)
+	default:
+		err = yaml.Unmarshal(bs, &doc)
+	}
+
+	return doc, err
+}
+
+// Watch passes on changes from every layer that can push them
+// and closes once any of those watches is lost
+func (c *client) Watch(ctx context.Context) (<-chan struct{}, error) {
+	watchCtx, cancel := context.WithCancel(ctx)
+
+	watches, err := c.watchLayers(watchCtx)
+	if err != nil || len(watches) == 0 {
+		cancel()
+		return nil, err
+	}
+
+	changes := make(chan struct{}, 1)
+
+	wg := &sync.WaitGroup{}
+
+	for _, watch := range watches {
+		wg.Add(1)
+
+		go func(watch <-chan struct{}) {
+			defer wg.Done()
+
+			for {
+				select {
+				case _, ok := <-watch:
+					if !ok {
+						cancel()
+						return
+					}
+
+					select {
+					case changes <- struct{}{}:
+					default:
+					}
+				case <-watchCtx.Done():
+					return
+				}
+			}
+		}(watch)
+	}
+
+	go func() {
+		wg.Wait()
+		cancel()
+		close(changes)
+	}()
+
+	return changes, nil
+}
+
+func (c *client) watchLayers(ctx context.Context) ([]<-chan struct{}, error) {
+	watches := []<-chan struct{}{}
+
+	for _, layer := range c.layers {
+		watcher, ok := layer.Reader.(reader.Watcher)
+		if !ok {
+			continue
+		}
+
+		watch, err := watcher.Watch(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to watch layer %s: %w", layer.Name, err)
+		}
+
+		watches = append(watches, watch)
+	}
+
+	return watches, nil
+}
+
+func NewReader(opts ...reader.Option) reader.Reader {
+	options := reader.NewOptions(opts...)
+
+	layers, _ := Layers(options.Context)
+	if len(layers) == 0 {
+		detail := "failed to configure layered reader"
+		slog.ErrorContext(context.Background(), detail, "error", fmt.Errorf("missing layers"))
+		panic(detail)
+	}
+
+	c := &client{
+		options: options,
+		layers:  layers,
+		merge:   MergeFlag,
+	}
+
+	if merge, ok := Merge(options.Context); ok && len(merge) > 0 {
+		c.merge = merge
+	}
+
+	if c.merge != MergeFlag && c.merge != MergeField {
+		detail := "failed to configure layered reader"
+		slog.ErrorContext(context.Background(), detail, "error", fmt.Errorf("unknown merge %q", c.merge))
+		panic(detail)
+	}
+
+	return c
+}
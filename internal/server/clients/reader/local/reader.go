@@ -4,6 +4,9 @@ import (
 	"context"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/w-h-a/flags/internal/server/clients/reader"
 )
@@ -13,11 +16,86 @@ type client struct {
 }
 
 func (c *client) ReadByKey(ctx context.Context, key string) ([]byte, error) {
-	return nil, nil
+	bs, err := c.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return reader.ExtractKey(bs, key, c.options.Format)
 }
 
 func (c *client) Read(ctx context.Context) ([]byte, error) {
-	return os.ReadFile(c.options.Location)
+	names, ok, err := c.files()
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return os.ReadFile(c.options.Location)
+	}
+
+	files := make([]reader.File, 0, len(names))
+
+	for _, name := range names {
+		bs, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, reader.File{Name: name, Data: bs})
+	}
+
+	return reader.MergeFiles(files, c.options.Format)
+}
+
+// files lists what a directory or glob location matches in a stable
+// order. It returns false when the location is a single file.
+func (c *client) files() ([]string, bool, error) {
+	if reader.IsPattern(c.options.Location) {
+		names, err := filepath.Glob(c.options.Location)
+		if err != nil {
+			return nil, true, err
+		}
+
+		matched := []string{}
+
+		for _, name := range names {
+			if info, err := os.Stat(name); err == nil && info.Mode().IsRegular() {
+				matched = append(matched, name)
+			}
+		}
+
+		sort.Strings(matched)
+
+		return matched, true, nil
+	}
+
+	info, err := os.Stat(c.options.Location)
+	if err != nil || !info.IsDir() {
+		return nil, false, nil
+	}
+
+	entries, err := os.ReadDir(c.options.Location)
+	if err != nil {
+		return nil, true, err
+	}
+
+	names := []string{}
+
+	for _, entry := range entries {
+		// config maps keep their real files in hidden directories
+		if strings.HasPrefix(entry.Name(), ".") || !reader.IsFlagsFile(entry.Name()) {
+			continue
+		}
+
+		name := filepath.Join(c.options.Location, entry.Name())
+
+		if info, err := os.Stat(name); err == nil && info.Mode().IsRegular() {
+			names = append(names, name)
+		}
+	}
+
+	return names, true, nil
 }
 
 func NewReader(opts ...reader.Option) reader.Reader {
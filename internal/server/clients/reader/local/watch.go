@@ -0,0 +1,150 @@
+package local
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+)
+
+// editors write a file in several steps
+const debounce = 100 * time.Millisecond
+
+func (c *client) Watch(ctx context.Context) (<-chan struct{}, error) {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	file := filepath.Clean(c.options.Location)
+
+	target, _ := filepath.EvalSymlinks(file)
+
+	dirs := []string{filepath.Dir(file)}
+
+	_, multi, _ := c.files()
+	if multi {
+		dirs = watchedDirs(file)
+	}
+
+	// the directory survives atomic renames and symlink swaps
+	for _, dir := range dirs {
+		if err := fsWatcher.Add(dir); err != nil {
+			fsWatcher.Close()
+			return nil, err
+		}
+	}
+
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		defer close(changes)
+		defer fsWatcher.Close()
+
+		timer := time.NewTimer(debounce)
+		timer.Stop()
+
+		for {
+			select {
+			case event, ok := <-fsWatcher.Events:
+				if !ok {
+					return
+				}
+
+				// any file in the directory may be one of ours
+				if multi {
+					// watches are not recursive so new directories
+					// a glob reaches into need watches of their own
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() && event.Has(fsnotify.Create) {
+						for _, dir := range watchedDirs(file) {
+							if err := fsWatcher.Add(dir); err != nil {
+								slog.WarnContext(ctx, "failed to watch flags directory", "location", dir, "error", err)
+							}
+						}
+					}
+
+					if !event.Has(fsnotify.Chmod) {
+						timer.Reset(debounce)
+					}
+
+					continue
+				}
+
+				// a config map swaps the ..data symlink so
+				// the file changes without an event of its own
+				current, _ := filepath.EvalSymlinks(file)
+
+				if filepath.Clean(event.Name) != file && current == target {
+					continue
+				}
+
+				if event.Has(fsnotify.Chmod) && current == target {
+					continue
+				}
+
+				target = current
+
+				timer.Reset(debounce)
+			case err, ok := <-fsWatcher.Errors:
+				if !ok {
+					return
+				}
+
+				slog.WarnContext(ctx, "failed to watch flags file", "location", file, "error", err)
+			case <-timer.C:
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+// watchedDirs lists the directories a directory or glob location can
+// have files in, from the deepest one without wildcards down to every
+// directory the wildcards expand to
+func watchedDirs(location string) []string {
+	if !reader.IsPattern(location) {
+		return []string{location}
+	}
+
+	root := location
+
+	for reader.IsPattern(root) {
+		root = filepath.Dir(root)
+	}
+
+	dirs := []string{root}
+
+	rest, err := filepath.Rel(root, filepath.Dir(location))
+	if err != nil || rest == "." {
+		return dirs
+	}
+
+	prefix := root
+
+	for _, segment := range strings.Split(rest, string(filepath.Separator)) {
+		prefix = filepath.Join(prefix, segment)
+
+		matches, _ := filepath.Glob(prefix)
+
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && info.IsDir() {
+				dirs = append(dirs, match)
+			}
+		}
+	}
+
+	return dirs
+}
@@ -16,6 +16,8 @@ type Client struct {
 	initialFlags map[string]*flags.Flag
 	updatedFlags map[string]*flags.Flag
 	callCount    int
+	watchers     []chan struct{}
+	watchCount   int
 	mtx          sync.RWMutex
 }
 
@@ -45,6 +47,49 @@ func (c *Client) Read(ctx context.Context) ([]byte, error) {
 	return yaml.Marshal(result)
 }
 
+func (c *Client) Watch(ctx context.Context) (<-chan struct{}, error) {
+	ch := make(chan struct{}, 1)
+
+	c.mtx.Lock()
+	c.watchers = append(c.watchers, ch)
+	c.watchCount++
+	c.mtx.Unlock()
+
+	return ch, nil
+}
+
+// Trigger simulates a change pushed by the source
+func (c *Client) Trigger() {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	for _, ch := range c.watchers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Drop simulates losing the connection to the source
+func (c *Client) Drop() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for _, ch := range c.watchers {
+		close(ch)
+	}
+
+	c.watchers = nil
+}
+
+func (c *Client) WatchCount() int {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.watchCount
+}
+
 func (c *Client) CallCount() int {
 	c.mtx.RLock()
 	defer c.mtx.RUnlock()
@@ -3,6 +3,7 @@ package reader
 import (
 	"context"
 	"fmt"
+	"time"
 )
 
 type Option func(o *Options)
@@ -10,6 +11,15 @@ type Option func(o *Options)
 type Options struct {
 	Location string
 	Token    string
+	Format   string
+	Remote   string
+	Branch   string
+	Path     string
+	Prefix   string
+	Endpoint string
+	Headers  map[string]string
+	Timeout  time.Duration
+	Retries  int
 	Context  context.Context
 }
 
@@ -33,8 +43,65 @@ func WithToken(token string) Option {
 	}
 }
 
+func WithFormat(format string) Option {
+	return func(o *Options) {
+		o.Format = format
+	}
+}
+
+func WithRemote(remote string) Option {
+	return func(o *Options) {
+		o.Remote = remote
+	}
+}
+
+func WithBranch(branch string) Option {
+	return func(o *Options) {
+		o.Branch = branch
+	}
+}
+
+func WithPath(path string) Option {
+	return func(o *Options) {
+		o.Path = path
+	}
+}
+
+func WithPrefix(prefix string) Option {
+	return func(o *Options) {
+		o.Prefix = prefix
+	}
+}
+
+func WithEndpoint(endpoint string) Option {
+	return func(o *Options) {
+		o.Endpoint = endpoint
+	}
+}
+
+func WithHeaders(headers map[string]string) Option {
+	return func(o *Options) {
+		o.Headers = headers
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = timeout
+	}
+}
+
+func WithRetries(retries int) Option {
+	return func(o *Options) {
+		o.Retries = retries
+	}
+}
+
 func NewOptions(opts ...Option) Options {
 	options := Options{
+		Format:  "yaml",
+		Headers: map[string]string{},
+		Timeout: 10 * time.Second,
 		Context: context.Background(),
 	}
 
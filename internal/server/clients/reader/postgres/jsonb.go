@@ -0,0 +1,274 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// now() is when a transaction started, so a slow write can
+	// commit after a later one that we have already seen
+	overlap = time.Minute
+)
+
+// jsonbSchema is the migration that postgres writers in the jsonb
+// schema run. Rows it leaves without a definition are derived here.
+var jsonbSchema = []string{
+	`ALTER TABLE flags ADD COLUMN IF NOT EXISTS definition jsonb;`,
+	`ALTER TABLE flags ADD COLUMN IF NOT EXISTS updated_at timestamptz NOT NULL DEFAULT now();`,
+	`ALTER TABLE flags ADD COLUMN IF NOT EXISTS updated_by text;`,
+	`ALTER TABLE flags ADD COLUMN IF NOT EXISTS tags text[] NOT NULL DEFAULT '{}';`,
+	`CREATE INDEX IF NOT EXISTS updated_at_index_flags ON flags (updated_at);`,
+	`CREATE INDEX IF NOT EXISTS definition_index_flags ON flags USING gin (definition jsonb_path_ops);`,
+	`CREATE INDEX IF NOT EXISTS tags_index_flags ON flags USING gin (tags);`,
+	`CREATE OR REPLACE FUNCTION flags_touch() RETURNS trigger AS $$ BEGIN NEW.updated_at = now(); IF NEW.value IS DISTINCT FROM OLD.value AND NEW.definition IS NOT DISTINCT FROM OLD.definition THEN NEW.definition = NULL; END IF; RETURN NEW; END; $$ LANGUAGE plpgsql;`,
+	`DO $$ BEGIN IF NOT EXISTS (SELECT 1 FROM pg_trigger WHERE tgname = 'flags_touch') THEN CREATE TRIGGER flags_touch BEFORE UPDATE ON flags FOR EACH ROW EXECUTE PROCEDURE flags_touch(); END IF; END $$;`,
+}
+
+func (c *client) readDefinition(ctx context.Context, key string) ([]byte, error) {
+	row := c.readOne.QueryRowContext(ctx, key)
+
+	var value []byte
+	var def []byte
+
+	if err := row.Scan(&key, &value, &def); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return []byte{}, reader.ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	if def == nil {
+		var err error
+		if def, _, err = flags.Definition(key, value, c.options.Format); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.encode(map[string]json.RawMessage{key: def})
+}
+
+func (c *client) readDefinitions(ctx context.Context) ([]byte, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if err := c.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	return c.encode(c.definitions)
+}
+
+func (c *client) readDefinitionsIfModified(ctx context.Context) ([]byte, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.pending = -1
+
+	if err := c.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	if c.served == c.version {
+		return nil, reader.ErrNotModified
+	}
+
+	bs, err := c.encode(c.definitions)
+	if err != nil {
+		return nil, err
+	}
+
+	c.pending = c.version
+
+	return bs, nil
+}
+
+func (c *client) readDefinitionChanges(ctx context.Context, cursor string) (*reader.Changes, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if err := c.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	next := strconv.Itoa(c.version)
+
+	// a cursor from before the last reload or from
+	// another process tells us nothing about what they have
+	from, err := strconv.Atoi(cursor)
+	if err != nil || from < c.reloadedAt || from > c.version {
+		bs, err := c.encode(c.definitions)
+		if err != nil {
+			return nil, err
+		}
+
+		return &reader.Changes{Changed: bs, Full: true, Cursor: next}, nil
+	}
+
+	definitions := map[string]json.RawMessage{}
+
+	for key, version := range c.changedAt {
+		if version > from {
+			definitions[key] = c.definitions[key]
+		}
+	}
+
+	bs := []byte{}
+
+	if len(definitions) > 0 {
+		if bs, err = c.encode(definitions); err != nil {
+			return nil, err
+		}
+	}
+
+	return &reader.Changes{Changed: bs, Cursor: next}, nil
+}
+
+// refresh fetches the rows that changed since the last refresh
+// and starts over when rows were deleted
+func (c *client) refresh(ctx context.Context) error {
+	next := c.version + 1
+
+	since := c.since
+	if !since.IsZero() {
+		since = since.Add(-overlap)
+	}
+
+	changed, err := c.fetch(ctx, since, next)
+	if err != nil {
+		return err
+	}
+
+	stale, err := c.stale(ctx)
+	if err != nil {
+		return err
+	}
+
+	if stale {
+		c.definitions = map[string]json.RawMessage{}
+		c.changedAt = map[string]int{}
+		c.reloadedAt = next
+
+		if _, err = c.fetch(ctx, time.Time{}, next); err != nil {
+			return err
+		}
+
+		changed = true
+	}
+
+	if changed {
+		c.version = next
+	}
+
+	return nil
+}
+
+// stale tells whether the flags we hold are not the flags in the
+// table, which a count misses when a delete and an insert land
+// between refreshes
+func (c *client) stale(ctx context.Context) (bool, error) {
+	rows, err := c.readKeys.QueryContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	defer rows.Close()
+
+	keys := map[string]struct{}{}
+
+	for rows.Next() {
+		var key string
+
+		if err := rows.Scan(&key); err != nil {
+			return false, err
+		}
+
+		keys[key] = struct{}{}
+	}
+
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	if len(keys) != len(c.definitions) {
+		return true, nil
+	}
+
+	for key := range c.definitions {
+		if _, ok := keys[key]; !ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (c *client) fetch(ctx context.Context, since time.Time, version int) (bool, error) {
+	rows, err := c.readChanged.QueryContext(ctx, since)
+	if err != nil {
+		return false, err
+	}
+
+	defer rows.Close()
+
+	changed := false
+
+	for rows.Next() {
+		var key string
+		var value []byte
+		var def []byte
+		var updatedAt time.Time
+
+		if err := rows.Scan(&key, &value, &def, &updatedAt); err != nil {
+			return false, err
+		}
+
+		// rows that no writer has migrated yet
+		if def == nil {
+			if def, _, err = flags.Definition(key, value, c.options.Format); err != nil {
+				return false, err
+			}
+		}
+
+		if existing, ok := c.definitions[key]; !ok || !bytes.Equal(existing, def) {
+			c.definitions[key] = def
+			c.changedAt[key] = version
+			changed = true
+		}
+
+		if updatedAt.After(c.since) {
+			c.since = updatedAt
+		}
+	}
+
+	return changed, rows.Err()
+}
+
+func (c *client) encode(definitions map[string]json.RawMessage) ([]byte, error) {
+	switch strings.ToLower(c.options.Format) {
+	case "json":
+		return json.Marshal(definitions)
+	default:
+		doc := map[string]any{}
+
+		for key, def := range definitions {
+			var v any
+			if err := json.Unmarshal(def, &v); err != nil {
+				return nil, err
+			}
+			doc[key] = v
+		}
+
+		return yaml.Marshal(doc)
+	}
+}
@@ -0,0 +1,28 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+)
+
+const (
+	// each flag is an opaque document in the flag format
+	SchemaBlob = "blob"
+	// each flag is a jsonb definition with metadata columns
+	SchemaJSONB = "jsonb"
+)
+
+type schemaKey struct{}
+
+// WithSchema sets how flags are stored in the flags table
+func WithSchema(schema string) reader.Option {
+	return func(o *reader.Options) {
+		o.Context = context.WithValue(o.Context, schemaKey{}, schema)
+	}
+}
+
+func Schema(ctx context.Context) (string, bool) {
+	schema, ok := ctx.Value(schemaKey{}).(string)
+	return schema, ok
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	channel = "flags_changed"
+)
+
+// Watch listens for the notifications the writer's trigger sends.
+// Without the trigger nothing would ever arrive, so polling is all
+// that happens.
+func (c *client) Watch(ctx context.Context) (<-chan struct{}, error) {
+	if err := c.setup(ctx); err != nil {
+		return nil, err
+	}
+
+	var installed bool
+
+	if err := c.conn.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM pg_trigger WHERE tgname = 'flags_notify_change');`).Scan(&installed); err != nil {
+		return nil, err
+	}
+
+	if !installed {
+		slog.WarnContext(ctx, "postgres reader found no notify trigger on the flags table so it will only poll")
+		return nil, nil
+	}
+
+	listener := pq.NewListener(
+		c.options.Location,
+		10*time.Second,
+		time.Minute,
+		func(event pq.ListenerEventType, err error) {
+			if err != nil {
+				slog.WarnContext(ctx, "postgres reader listener event", "event", event, "error", err)
+			}
+		},
+	)
+
+	if err := listener.Listen(channel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		defer close(changes)
+		defer listener.Close()
+
+		for {
+			select {
+			case <-listener.Notify:
+				// a nil notification means the connection was re-established
+				// and we may have missed something in between
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			case <-time.After(90 * time.Second):
+				go listener.Ping()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return changes, nil
+}
@@ -0,0 +1,55 @@
+package reader
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimitError is returned when the source asked us to back off.
+// RetryAfter is zero when the source did not say for how long.
+type RateLimitError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited with status code %d, retry after %s", e.StatusCode, e.RetryAfter)
+}
+
+// RateLimit reads the rate limit headers that GitHub and GitLab send.
+// It returns nil when the response was not rate limited.
+func RateLimit(rsp *http.Response, now time.Time) *RateLimitError {
+	remaining := rsp.Header.Get("x-ratelimit-remaining")
+	if len(remaining) == 0 {
+		remaining = rsp.Header.Get("ratelimit-remaining")
+	}
+
+	limited := rsp.StatusCode == http.StatusTooManyRequests ||
+		(rsp.StatusCode == http.StatusForbidden && remaining == "0")
+
+	if !limited {
+		return nil
+	}
+
+	err := &RateLimitError{StatusCode: rsp.StatusCode}
+
+	if seconds, convErr := strconv.Atoi(rsp.Header.Get("retry-after")); convErr == nil && seconds > 0 {
+		err.RetryAfter = time.Duration(seconds) * time.Second
+		return err
+	}
+
+	reset := rsp.Header.Get("x-ratelimit-reset")
+	if len(reset) == 0 {
+		reset = rsp.Header.Get("ratelimit-reset")
+	}
+
+	if unix, convErr := strconv.ParseInt(reset, 10, 64); convErr == nil {
+		if wait := time.Unix(unix, 0).Sub(now); wait > 0 {
+			err.RetryAfter = wait
+		}
+	}
+
+	return err
+}
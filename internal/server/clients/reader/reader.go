@@ -7,9 +7,41 @@ import (
 
 var (
 	ErrRecordNotFound = errors.New("record not found")
+	ErrNotModified    = errors.New("not modified")
 )
 
 type Reader interface {
 	ReadByKey(ctx context.Context, key string) ([]byte, error)
 	Read(ctx context.Context) ([]byte, error)
 }
+
+// ConditionalReader is implemented by readers that can tell when
+// nothing changed since the last document the cache accepted.
+// ReadIfModified returns ErrNotModified instead of the document in
+// that case. Ack tells the reader that the cache accepted the last
+// document, so one the cache refused is served again.
+type ConditionalReader interface {
+	ReadIfModified(ctx context.Context) ([]byte, error)
+	Ack(ctx context.Context)
+}
+
+// Watcher is implemented by readers whose source can tell us about
+// changes. Every value on the channel asks for a fresh Read. The
+// channel is closed when ctx is done or the watch is lost.
+type Watcher interface {
+	Watch(ctx context.Context) (<-chan struct{}, error)
+}
+
+// IncrementalReader is implemented by readers that can tell which
+// flags changed since a cursor. An empty or unknown cursor gets every
+// flag back with Full set.
+type IncrementalReader interface {
+	ReadChanges(ctx context.Context, cursor string) (*Changes, error)
+}
+
+// Degradable is implemented by readers that can keep serving
+// something when their source is down. Degraded returns why,
+// or nil while the source is healthy.
+type Degradable interface {
+	Degraded() error
+}
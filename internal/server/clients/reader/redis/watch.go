@@ -0,0 +1,47 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+)
+
+// Watch only hears about changes when the server has
+// notify-keyspace-events enabled for generic and string commands
+func (c *client) Watch(ctx context.Context) (<-chan struct{}, error) {
+	keyspace := fmt.Sprintf("__keyspace@%d__:%s*", c.conn.Options().DB, c.prefix)
+
+	pubsub := c.conn.PSubscribe(ctx, keyspace)
+
+	// wait for the subscription to be confirmed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		defer close(changes)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+
+		for {
+			select {
+			case _, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return changes, nil
+}
@@ -0,0 +1,66 @@
+package resilient
+
+import (
+	"context"
+	"time"
+
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+)
+
+type liveKey struct{}
+
+// WithReader sets the reader that calls are retried against
+func WithReader(name string, r reader.Reader) reader.Option {
+	return func(o *reader.Options) {
+		o.Context = context.WithValue(o.Context, liveKey{}, named{name: name, reader: r})
+	}
+}
+
+type named struct {
+	name   string
+	reader reader.Reader
+}
+
+func Reader(ctx context.Context) (string, reader.Reader, bool) {
+	n, ok := ctx.Value(liveKey{}).(named)
+	return n.name, n.reader, ok
+}
+
+type backoffKey struct{}
+
+type backoff struct {
+	base time.Duration
+	max  time.Duration
+}
+
+// WithBackoff sets the first delay between retries and the most it can grow to
+func WithBackoff(base, max time.Duration) reader.Option {
+	return func(o *reader.Options) {
+		o.Context = context.WithValue(o.Context, backoffKey{}, backoff{base: base, max: max})
+	}
+}
+
+func Backoff(ctx context.Context) (time.Duration, time.Duration, bool) {
+	b, ok := ctx.Value(backoffKey{}).(backoff)
+	return b.base, b.max, ok
+}
+
+type breakerKey struct{}
+
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+}
+
+// WithBreaker opens the circuit after threshold failed reads in a row
+// and keeps it open for the cooldown
+func WithBreaker(threshold int, cooldown time.Duration) reader.Option {
+	return func(o *reader.Options) {
+		o.Context = context.WithValue(o.Context, breakerKey{}, breaker{threshold: threshold, cooldown: cooldown})
+	}
+}
+
+func Breaker(ctx context.Context) (int, time.Duration, bool) {
+	b, ok := ctx.Value(breakerKey{}).(breaker)
+	return b.threshold, b.cooldown, ok
+}
@@ -0,0 +1,352 @@
+package resilient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	defaultBase      = 250 * time.Millisecond
+	defaultMax       = 10 * time.Second
+	defaultThreshold = 5
+	defaultCooldown  = 30 * time.Second
+)
+
+var (
+	ErrCircuitOpen = errors.New("circuit open")
+)
+
+type state int64
+
+const (
+	closed state = iota
+	halfOpen
+	open
+)
+
+type client struct {
+	options   reader.Options
+	live      reader.Reader
+	name      string
+	base      time.Duration
+	max       time.Duration
+	threshold int
+	cooldown  time.Duration
+	state     state
+	failures  int
+	openUntil time.Time
+	// the error that opened the circuit
+	cause   error
+	retries metric.Int64Counter
+	mtx     sync.Mutex
+}
+
+func (c *client) ReadByKey(ctx context.Context, key string) ([]byte, error) {
+	var bs []byte
+
+	err := c.do(ctx, func(ctx context.Context) error {
+		var err error
+		bs, err = c.live.ReadByKey(ctx, key)
+		return err
+	})
+
+	return bs, err
+}
+
+func (c *client) Read(ctx context.Context) ([]byte, error) {
+	var bs []byte
+
+	err := c.do(ctx, func(ctx context.Context) error {
+		var err error
+		bs, err = c.live.Read(ctx)
+		return err
+	})
+
+	return bs, err
+}
+
+func (c *client) ReadIfModified(ctx context.Context) ([]byte, error) {
+	conditional, ok := c.live.(reader.ConditionalReader)
+	if !ok {
+		return c.Read(ctx)
+	}
+
+	var bs []byte
+
+	err := c.do(ctx, func(ctx context.Context) error {
+		var err error
+		bs, err = conditional.ReadIfModified(ctx)
+		return err
+	})
+
+	return bs, err
+}
+
+func (c *client) Ack(ctx context.Context) {
+	if conditional, ok := c.live.(reader.ConditionalReader); ok {
+		conditional.Ack(ctx)
+	}
+}
+
+func (c *client) ReadChanges(ctx context.Context, cursor string) (*reader.Changes, error) {
+	incremental, ok := c.live.(reader.IncrementalReader)
+	if !ok {
+		bs, err := c.ReadIfModified(ctx)
+		if err != nil && errors.Is(err, reader.ErrNotModified) {
+			return &reader.Changes{Cursor: cursor}, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		return &reader.Changes{Changed: bs, Full: true}, nil
+	}
+
+	var changes *reader.Changes
+
+	err := c.do(ctx, func(ctx context.Context) error {
+		var err error
+		changes, err = incremental.ReadChanges(ctx, cursor)
+		return err
+	})
+
+	return changes, err
+}
+
+func (c *client) Watch(ctx context.Context) (<-chan struct{}, error) {
+	watcher, ok := c.live.(reader.Watcher)
+	if !ok {
+		return nil, nil
+	}
+
+	return watcher.Watch(ctx)
+}
+
+func (c *client) Degraded() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.state == closed {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %v", ErrCircuitOpen, c.cause)
+}
+
+func (c *client) do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := c.allow(); err != nil {
+		return err
+	}
+
+	var err error
+
+	for attempt := 0; attempt <= c.options.Retries; attempt++ {
+		if attempt > 0 {
+			c.retries.Add(ctx, 1, metric.WithAttributes(attribute.String("reader", c.name)))
+
+			select {
+			case <-ctx.Done():
+				c.abandon()
+				return ctx.Err()
+			case <-time.After(c.delay(attempt)):
+			}
+		}
+
+		err = fn(ctx)
+		if !retryable(err) {
+			break
+		}
+
+		// the source told us how long to stay away
+		var rateLimitErr *reader.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			break
+		}
+
+		slog.WarnContext(ctx, "failed to read flags", "reader", c.name, "attempt", attempt+1, "error", err)
+	}
+
+	// readers do not all wrap the error of a cancelled request
+	if errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled) {
+		c.abandon()
+		return err
+	}
+
+	c.record(err)
+
+	return err
+}
+
+// delay grows exponentially with equal jitter so that
+// instances that failed together do not retry together
+func (c *client) delay(attempt int) time.Duration {
+	d := c.base << (attempt - 1)
+	if d <= 0 || d > c.max {
+		d = c.max
+	}
+
+	half := d / 2
+
+	return half + rand.N(half+1)
+}
+
+func (c *client) allow() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	switch c.state {
+	case open:
+		if time.Now().Before(c.openUntil) {
+			return fmt.Errorf("%w until %s: %v", ErrCircuitOpen, c.openUntil.Format(time.RFC3339), c.cause)
+		}
+
+		// let a single read through to see if the source is back
+		c.state = halfOpen
+
+		return nil
+	case halfOpen:
+		return fmt.Errorf("%w while probing: %v", ErrCircuitOpen, c.cause)
+	default:
+		return nil
+	}
+}
+
+func (c *client) record(err error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if !retryable(err) {
+		if c.state != closed {
+			slog.InfoContext(context.Background(), "circuit closed", "reader", c.name)
+		}
+
+		c.state = closed
+		c.failures = 0
+		c.cause = nil
+
+		return
+	}
+
+	c.failures++
+
+	cooldown := c.cooldown
+
+	var rateLimitErr *reader.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		if rateLimitErr.RetryAfter > 0 {
+			cooldown = rateLimitErr.RetryAfter
+		}
+	} else if c.state != halfOpen && c.failures < c.threshold {
+		return
+	}
+
+	if c.state != open {
+		slog.WarnContext(context.Background(), "circuit opened", "reader", c.name, "cooldown", cooldown, "error", err)
+	}
+
+	c.state = open
+	c.openUntil = time.Now().Add(cooldown)
+	c.cause = err
+}
+
+// abandon gives up a read that was cancelled. That says nothing
+// about the source, so a probe leaves the circuit open.
+func (c *client) abandon() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.state == halfOpen {
+		c.state = open
+	}
+}
+
+func (c *client) observe(ctx context.Context, o metric.Int64Observer) error {
+	c.mtx.Lock()
+	s := c.state
+	c.mtx.Unlock()
+
+	o.Observe(int64(s), metric.WithAttributes(attribute.String("reader", c.name)))
+
+	return nil
+}
+
+// retryable tells failures of the source apart from answers
+func retryable(err error) bool {
+	return err != nil &&
+		!errors.Is(err, reader.ErrRecordNotFound) &&
+		!errors.Is(err, reader.ErrNotModified) &&
+		!errors.Is(err, context.Canceled)
+}
+
+func NewReader(opts ...reader.Option) reader.Reader {
+	options := reader.NewOptions(opts...)
+
+	name, live, ok := Reader(options.Context)
+	if !ok {
+		detail := "failed to configure resilient reader"
+		slog.ErrorContext(context.Background(), detail, "error", fmt.Errorf("missing reader"))
+		panic(detail)
+	}
+
+	c := &client{
+		options:   options,
+		live:      live,
+		name:      name,
+		base:      defaultBase,
+		max:       defaultMax,
+		threshold: defaultThreshold,
+		cooldown:  defaultCooldown,
+		mtx:       sync.Mutex{},
+	}
+
+	if base, max, ok := Backoff(options.Context); ok {
+		c.base = base
+		c.max = max
+	}
+
+	if threshold, cooldown, ok := Breaker(options.Context); ok {
+		c.threshold = threshold
+		c.cooldown = cooldown
+	}
+
+	if c.threshold <= 0 || c.cooldown <= 0 {
+		detail := "failed to configure resilient reader"
+		slog.ErrorContext(context.Background(), detail, "error", fmt.Errorf("breaker threshold %d and cooldown %s must be positive", c.threshold, c.cooldown))
+		panic(detail)
+	}
+
+	meter := otel.Meter("github.com/w-h-a/flags/internal/server/clients/reader/resilient")
+
+	retries, err := meter.Int64Counter(
+		"flags.reader.retries",
+		metric.WithDescription("Reads retried after a failure"),
+	)
+	if err != nil {
+		detail := "failed to create retry counter for resilient reader"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	c.retries = retries
+
+	if _, err := meter.Int64ObservableGauge(
+		"flags.reader.breaker.state",
+		metric.WithDescription("Circuit breaker state: 0 closed, 1 half open, 2 open"),
+		metric.WithInt64Callback(c.observe),
+	); err != nil {
+		detail := "failed to create breaker gauge for resilient reader"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	return c
+}
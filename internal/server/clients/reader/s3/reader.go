@@ -0,0 +1,285 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	"github.com/w-h-a/flags/internal/server/config"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-sdk-go-v2/otelaws"
+)
+
+type object struct {
+	etag string
+	body []byte
+}
+
+type client struct {
+	options reader.Options
+	conn    *s3.Client
+	bucket  string
+	key     string
+	// a key ending in a slash means every object under it
+	prefix  bool
+	objects map[string]*object
+	// what the cache last accepted
+	served string
+	// what the cache was given but has not accepted yet
+	pending string
+	mtx     sync.Mutex
+}
+
+func (c *client) ReadByKey(ctx context.Context, key string) ([]byte, error) {
+	bs, err := c.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return reader.ExtractKey(bs, key, c.options.Format)
+}
+
+func (c *client) Read(ctx context.Context) ([]byte, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	bs, _, err := c.fetch(ctx)
+
+	return bs, err
+}
+
+func (c *client) ReadIfModified(ctx context.Context) ([]byte, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.pending = ""
+
+	bs, signature, err := c.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if signature == c.served {
+		return nil, reader.ErrNotModified
+	}
+
+	c.pending = signature
+
+	return bs, nil
+}
+
+func (c *client) Ack(ctx context.Context) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if len(c.pending) > 0 {
+		c.served = c.pending
+		c.pending = ""
+	}
+}
+
+// fetch only downloads objects whose etag changed since the last fetch
+func (c *client) fetch(ctx context.Context) ([]byte, string, error) {
+	if !c.prefix {
+		obj, err := c.getObject(ctx, c.key, c.objects[c.key])
+		if err != nil {
+			return nil, "", err
+		}
+
+		c.objects = map[string]*object{c.key: obj}
+
+		return obj.body, signature(c.objects), nil
+	}
+
+	listed := map[string]string{}
+
+	paginator := s3.NewListObjectsV2Paginator(c.conn, &s3.ListObjectsV2Input{
+		Bucket: aws.String(c.bucket),
+		Prefix: aws.String(c.key),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+
+		for _, o := range page.Contents {
+			key := aws.ToString(o.Key)
+			if strings.HasSuffix(key, "/") {
+				continue
+			}
+			listed[key] = aws.ToString(o.ETag)
+		}
+	}
+
+	objects := map[string]*object{}
+
+	for key, etag := range listed {
+		if cached, ok := c.objects[key]; ok && cached.etag == etag {
+			objects[key] = cached
+			continue
+		}
+
+		obj, err := c.getObject(ctx, key, nil)
+		if err != nil {
+			return nil, "", err
+		}
+
+		objects[key] = obj
+	}
+
+	c.objects = objects
+
+	keys := sortedKeys(objects)
+
+	values := make([][]byte, 0, len(keys))
+
+	for _, key := range keys {
+		values = append(values, objects[key].body)
+	}
+
+	bs, err := reader.JoinRecords(values, c.options.Format)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return bs, signature(objects), nil
+}
+
+func (c *client) getObject(ctx context.Context, key string, cached *object) (*object, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+	}
+
+	if cached != nil {
+		input.IfNoneMatch = aws.String(cached.etag)
+	}
+
+	rsp, err := c.conn.GetObject(ctx, input)
+
+	var respErr *awshttp.ResponseError
+
+	if errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotModified && cached != nil {
+		return cached, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer rsp.Body.Close()
+
+	body, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &object{etag: aws.ToString(rsp.ETag), body: body}, nil
+}
+
+func signature(objects map[string]*object) string {
+	b := &strings.Builder{}
+
+	for _, key := range sortedKeys(objects) {
+		fmt.Fprintf(b, "%s\x00%s\n", key, objects[key].etag)
+	}
+
+	return b.String()
+}
+
+func sortedKeys(objects map[string]*object) []string {
+	keys := make([]string, 0, len(objects))
+
+	for k := range objects {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// parseLocation accepts s3://bucket/key for one object
+// and s3://bucket/prefix/ for every object under a prefix
+func parseLocation(location string) (string, string, bool, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", "", false, err
+	}
+
+	if u.Scheme != "s3" || len(u.Host) == 0 {
+		return "", "", false, fmt.Errorf("location is not an s3 url")
+	}
+
+	key := strings.TrimPrefix(u.Path, "/")
+
+	return u.Host, key, len(key) == 0 || strings.HasSuffix(key, "/"), nil
+}
+
+func NewReader(opts ...reader.Option) reader.Reader {
+	options := reader.NewOptions(opts...)
+
+	if err := options.Validate(); err != nil {
+		detail := "failed to validate s3 reader options"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	bucket, key, prefix, err := parseLocation(options.Location)
+	if err != nil {
+		detail := "failed to parse s3 reader location"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	cfg, err := awsconfig.LoadDefaultConfig(
+		context.Background(),
+		awsconfig.WithRegion(config.Region()),
+	)
+	if err != nil {
+		detail := "failed to load aws config for s3 reader"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	otelaws.AppendMiddlewares(&cfg.APIOptions)
+
+	conn := s3.NewFromConfig(
+		cfg,
+		func(o *s3.Options) {
+			if len(options.Endpoint) == 0 {
+				return
+			}
+
+			// s3 compatible stores are addressed by path and
+			// often reject the newer checksum headers
+			o.BaseEndpoint = aws.String(options.Endpoint)
+			o.UsePathStyle = true
+			o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+			o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+		},
+	)
+
+	c := &client{
+		options: options,
+		conn:    conn,
+		bucket:  bucket,
+		key:     key,
+		prefix:  prefix,
+		objects: map[string]*object{},
+		mtx:     sync.Mutex{},
+	}
+
+	return c
+}
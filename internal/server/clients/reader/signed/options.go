@@ -0,0 +1,50 @@
+package signed
+
+import (
+	"context"
+	"crypto/ed25519"
+
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+)
+
+type liveKey struct{}
+
+// WithReader sets the reader that the flags are read from
+func WithReader(r reader.Reader) reader.Option {
+	return func(o *reader.Options) {
+		o.Context = context.WithValue(o.Context, liveKey{}, r)
+	}
+}
+
+func Reader(ctx context.Context) (reader.Reader, bool) {
+	r, ok := ctx.Value(liveKey{}).(reader.Reader)
+	return r, ok
+}
+
+type signatureKey struct{}
+
+// WithSignature sets the reader that the detached signature is read from
+func WithSignature(r reader.Reader) reader.Option {
+	return func(o *reader.Options) {
+		o.Context = context.WithValue(o.Context, signatureKey{}, r)
+	}
+}
+
+func Signature(ctx context.Context) (reader.Reader, bool) {
+	r, ok := ctx.Value(signatureKey{}).(reader.Reader)
+	return r, ok
+}
+
+type publicKeysKey struct{}
+
+// WithPublicKeys sets the keys that may have signed the flags
+func WithPublicKeys(keys ...ed25519.PublicKey) reader.Option {
+	return func(o *reader.Options) {
+		o.Context = context.WithValue(o.Context, publicKeysKey{}, keys)
+	}
+}
+
+func PublicKeys(ctx context.Context) ([]ed25519.PublicKey, bool) {
+	keys, ok := ctx.Value(publicKeysKey{}).([]ed25519.PublicKey)
+	return keys, ok
+}
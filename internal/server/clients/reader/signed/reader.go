@@ -0,0 +1,154 @@
+package signed
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sync"
+
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+)
+
+// client checks the whole flags document against its signature, so it
+// has no ReadChanges. The row stores that can read incrementally have
+// no document to sign and are refused at startup anyway.
+type client struct {
+	options   reader.Options
+	live      reader.Reader
+	signature reader.Reader
+	keys      []ed25519.PublicKey
+	rejected  error
+	mtx       sync.RWMutex
+}
+
+func (c *client) ReadByKey(ctx context.Context, key string) ([]byte, error) {
+	bs, err := c.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return reader.ExtractKey(bs, key, c.options.Format)
+}
+
+func (c *client) Read(ctx context.Context) ([]byte, error) {
+	bs, err := c.live.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.verify(ctx, bs)
+}
+
+// ReadIfModified reads everything again after a rejection because
+// a corrected signature does not change the flags themselves
+func (c *client) ReadIfModified(ctx context.Context) ([]byte, error) {
+	c.mtx.RLock()
+	rejected := c.rejected
+	c.mtx.RUnlock()
+
+	conditional, ok := c.live.(reader.ConditionalReader)
+	if !ok || rejected != nil {
+		return c.Read(ctx)
+	}
+
+	bs, err := conditional.ReadIfModified(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.verify(ctx, bs)
+}
+
+func (c *client) Ack(ctx context.Context) {
+	if conditional, ok := c.live.(reader.ConditionalReader); ok {
+		conditional.Ack(ctx)
+	}
+}
+
+func (c *client) Watch(ctx context.Context) (<-chan struct{}, error) {
+	watcher, ok := c.live.(reader.Watcher)
+	if !ok {
+		return nil, nil
+	}
+
+	return watcher.Watch(ctx)
+}
+
+// Degraded reports flags that were rejected while
+// the cache keeps serving what it verified before
+func (c *client) Degraded() error {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.rejected
+}
+
+// verify runs before anything parses the flags
+func (c *client) verify(ctx context.Context, bs []byte) ([]byte, error) {
+	signature, err := c.signature.Read(ctx)
+
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, reader.ErrRecordNotFound):
+		err = ErrUnsigned
+	case err != nil:
+		// the source of the signature is down, not the flags tampered with
+		return nil, fmt.Errorf("failed to read flags signature: %w", err)
+	default:
+		err = Verify(bs, signature, c.keys)
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if err != nil {
+		if c.rejected == nil {
+			slog.ErrorContext(ctx, "rejected flags", "error", err)
+		}
+
+		c.rejected = err
+
+		return nil, err
+	}
+
+	c.rejected = nil
+
+	return bs, nil
+}
+
+func NewReader(opts ...reader.Option) reader.Reader {
+	options := reader.NewOptions(opts...)
+
+	live, ok := Reader(options.Context)
+	if !ok {
+		detail := "failed to configure signed reader"
+		slog.ErrorContext(context.Background(), detail, "error", fmt.Errorf("missing reader"))
+		panic(detail)
+	}
+
+	signature, ok := Signature(options.Context)
+	if !ok {
+		detail := "failed to configure signed reader"
+		slog.ErrorContext(context.Background(), detail, "error", fmt.Errorf("missing signature reader"))
+		panic(detail)
+	}
+
+	keys, _ := PublicKeys(options.Context)
+	if len(keys) == 0 {
+		detail := "failed to configure signed reader"
+		slog.ErrorContext(context.Background(), detail, "error", fmt.Errorf("missing public keys"))
+		panic(detail)
+	}
+
+	c := &client{
+		options:   options,
+		live:      live,
+		signature: signature,
+		keys:      keys,
+		mtx:       sync.RWMutex{},
+	}
+
+	return c
+}
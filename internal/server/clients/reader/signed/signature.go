@@ -0,0 +1,80 @@
+package signed
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// signatures sit next to the flags they sign
+	Extension = ".sig"
+)
+
+var (
+	ErrUnsigned         = errors.New("flags are not signed")
+	ErrInvalidSignature = errors.New("flags signature does not match any public key")
+)
+
+// Sign produces the detached signature for a flags document
+// as base64 text so that it can live next to the flags anywhere
+func Sign(bs []byte, key ed25519.PrivateKey) []byte {
+	signature := ed25519.Sign(key, bs)
+
+	return []byte(base64.StdEncoding.EncodeToString(signature) + "\n")
+}
+
+// Verify checks that one of the keys signed the document
+func Verify(bs []byte, signature []byte, keys []ed25519.PublicKey) error {
+	encoded := bytes.TrimSpace(signature)
+	if len(encoded) == 0 {
+		return ErrUnsigned
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(string(encoded))
+	if err != nil || len(decoded) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+
+	for _, key := range keys {
+		if ed25519.Verify(key, bs, decoded) {
+			return nil
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+// ParsePublicKey reads a base64 encoded ed25519 public key
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	bs, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode public key: %v", err)
+	}
+
+	if len(bs) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key has %d bytes instead of %d", len(bs), ed25519.PublicKeySize)
+	}
+
+	return ed25519.PublicKey(bs), nil
+}
+
+// ParsePrivateKey reads a base64 encoded ed25519 private key or its seed
+func ParsePrivateKey(s string) (ed25519.PrivateKey, error) {
+	bs, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode private key: %v", err)
+	}
+
+	switch len(bs) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(bs), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(bs), nil
+	default:
+		return nil, fmt.Errorf("private key has %d bytes instead of %d", len(bs), ed25519.PrivateKeySize)
+	}
+}
@@ -0,0 +1,36 @@
+package snapshot
+
+import (
+	"context"
+
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+)
+
+type liveKey struct{}
+
+type lenientKey struct{}
+
+// WithReader sets the source that snapshots are taken from
+func WithReader(r reader.Reader) reader.Option {
+	return func(o *reader.Options) {
+		o.Context = context.WithValue(o.Context, liveKey{}, r)
+	}
+}
+
+func Reader(ctx context.Context) (reader.Reader, bool) {
+	r, ok := ctx.Value(liveKey{}).(reader.Reader)
+	return r, ok
+}
+
+// WithLenient keeps snapshots of documents with invalid flags the
+// way lenient loading keeps serving the rest of them
+func WithLenient(lenient bool) reader.Option {
+	return func(o *reader.Options) {
+		o.Context = context.WithValue(o.Context, lenientKey{}, lenient)
+	}
+}
+
+func Lenient(ctx context.Context) (bool, bool) {
+	lenient, ok := ctx.Value(lenientKey{}).(bool)
+	return lenient, ok
+}
@@ -0,0 +1,296 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+)
+
+const (
+	// used when the location is a directory
+	defaultName = "flags.snapshot"
+)
+
+type client struct {
+	options  reader.Options
+	live     reader.Reader
+	location string
+	lenient  bool
+	saved    []byte
+	degraded error
+	mtx      sync.RWMutex
+}
+
+func (c *client) ReadByKey(ctx context.Context, key string) ([]byte, error) {
+	bs, err := c.live.ReadByKey(ctx, key)
+	if err == nil || errors.Is(err, reader.ErrRecordNotFound) {
+		return bs, err
+	}
+
+	snapshot, loadErr := c.load()
+	if loadErr != nil {
+		return nil, err
+	}
+
+	return reader.ExtractKey(snapshot, key, c.options.Format)
+}
+
+func (c *client) Read(ctx context.Context) ([]byte, error) {
+	bs, err := c.live.Read(ctx)
+
+	return c.fallback(ctx, bs, err)
+}
+
+func (c *client) ReadIfModified(ctx context.Context) ([]byte, error) {
+	conditional, ok := c.live.(reader.ConditionalReader)
+	if !ok {
+		return c.Read(ctx)
+	}
+
+	bs, err := conditional.ReadIfModified(ctx)
+	if err != nil && errors.Is(err, reader.ErrNotModified) {
+		c.recover()
+		return nil, err
+	}
+
+	return c.fallback(ctx, bs, err)
+}
+
+func (c *client) Ack(ctx context.Context) {
+	if conditional, ok := c.live.(reader.ConditionalReader); ok {
+		conditional.Ack(ctx)
+	}
+}
+
+// ReadChanges keeps the snapshot in step with every batch so that
+// an incremental source can still be served while it is down
+func (c *client) ReadChanges(ctx context.Context, cursor string) (*reader.Changes, error) {
+	incremental, ok := c.live.(reader.IncrementalReader)
+	if !ok {
+		bs, err := c.ReadIfModified(ctx)
+		if err != nil && errors.Is(err, reader.ErrNotModified) {
+			return &reader.Changes{Cursor: cursor}, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		return &reader.Changes{Changed: bs, Full: true}, nil
+	}
+
+	changes, err := incremental.ReadChanges(ctx, cursor)
+	if err != nil {
+		// no cursor so that the source starts over once it is back
+		bs, err := c.fallback(ctx, nil, err)
+		if err != nil {
+			return nil, err
+		}
+
+		return &reader.Changes{Changed: bs, Full: true}, nil
+	}
+
+	c.recover()
+
+	if changes.Full {
+		if saveErr := c.save(changes.Changed); saveErr != nil {
+			slog.WarnContext(ctx, "failed to save flags snapshot", "location", c.location, "error", saveErr)
+		}
+
+		return changes, nil
+	}
+
+	if len(changes.Changed) == 0 && len(changes.Removed) == 0 {
+		return changes, nil
+	}
+
+	if saveErr := c.patch(changes); saveErr != nil {
+		slog.WarnContext(ctx, "failed to save flags snapshot", "location", c.location, "error", saveErr)
+	}
+
+	return changes, nil
+}
+
+func (c *client) Watch(ctx context.Context) (<-chan struct{}, error) {
+	watcher, ok := c.live.(reader.Watcher)
+	if !ok {
+		return nil, nil
+	}
+
+	return watcher.Watch(ctx)
+}
+
+func (c *client) Degraded() error {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.degraded
+}
+
+// fallback saves what the source gave us or serves
+// the last snapshot when the source is down
+func (c *client) fallback(ctx context.Context, bs []byte, err error) ([]byte, error) {
+	if err == nil {
+		c.recover()
+
+		if saveErr := c.save(bs); saveErr != nil {
+			slog.WarnContext(ctx, "failed to save flags snapshot", "location", c.location, "error", saveErr)
+		}
+
+		return bs, nil
+	}
+
+	snapshot, loadErr := c.load()
+	if loadErr != nil {
+		return nil, err
+	}
+
+	c.mtx.Lock()
+	if c.degraded == nil {
+		slog.WarnContext(ctx, "serving flags from snapshot", "location", c.location, "error", err)
+	}
+	c.degraded = err
+	c.mtx.Unlock()
+
+	return snapshot, nil
+}
+
+func (c *client) recover() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.degraded != nil {
+		slog.InfoContext(context.Background(), "flags source recovered", "location", c.location)
+	}
+
+	c.degraded = nil
+}
+
+func (c *client) save(bs []byte) error {
+	c.mtx.RLock()
+	unchanged := c.saved != nil && bytes.Equal(c.saved, bs)
+	c.mtx.RUnlock()
+
+	if unchanged {
+		return nil
+	}
+
+	// only keep what we could serve
+	if err := c.validate(bs); err != nil {
+		return err
+	}
+
+	if err := writeAtomic(c.location, bs); err != nil {
+		return err
+	}
+
+	c.mtx.Lock()
+	c.saved = bs
+	c.mtx.Unlock()
+
+	return nil
+}
+
+// patch applies a batch of changes to the last snapshot
+func (c *client) patch(changes *reader.Changes) error {
+	snapshot, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	bs, err := reader.ApplyChanges(snapshot, changes, c.options.Format)
+	if err != nil {
+		return err
+	}
+
+	return c.save(bs)
+}
+
+// validate checks the document the way the cache will load it
+func (c *client) validate(bs []byte) error {
+	if c.lenient {
+		_, _, err := flags.Partition(bs, c.options.Format)
+		return err
+	}
+
+	_, err := flags.Factory(bs, c.options.Format)
+
+	return err
+}
+
+func (c *client) load() ([]byte, error) {
+	return os.ReadFile(c.location)
+}
+
+// writeAtomic makes sure a crash never leaves a half written snapshot
+func writeAtomic(location string, bs []byte) error {
+	dir, base := filepath.Split(location)
+	if len(dir) == 0 {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+base+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(bs); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), location)
+}
+
+func NewReader(opts ...reader.Option) reader.Reader {
+	options := reader.NewOptions(opts...)
+
+	if err := options.Validate(); err != nil {
+		detail := "failed to configure snapshot reader"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	live, ok := Reader(options.Context)
+	if !ok {
+		detail := "failed to configure snapshot reader"
+		slog.ErrorContext(context.Background(), detail, "error", fmt.Errorf("missing reader"))
+		panic(detail)
+	}
+
+	location := options.Location
+
+	if info, err := os.Stat(location); err == nil && info.IsDir() {
+		location = filepath.Join(location, defaultName)
+	}
+
+	c := &client{
+		options:  options,
+		live:     live,
+		location: location,
+		mtx:      sync.RWMutex{},
+	}
+
+	if lenient, ok := Lenient(options.Context); ok {
+		c.lenient = lenient
+	}
+
+	return c
+}
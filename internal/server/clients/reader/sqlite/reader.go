@@ -0,0 +1,180 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	"go.nhat.io/otelsql"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	_ "modernc.org/sqlite"
+)
+
+var DRIVER string
+
+func init() {
+	driver, err := otelsql.Register(
+		"sqlite",
+		otelsql.TraceQueryWithoutArgs(),
+		otelsql.TraceRowsClose(),
+		otelsql.TraceRowsAffected(),
+		otelsql.WithSystem(semconv.DBSystemSqlite),
+	)
+	if err != nil {
+		detail := "failed to register sqlite reader with otel"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	DRIVER = driver
+}
+
+type client struct {
+	options  reader.Options
+	conn     *sql.DB
+	readOne  *sql.Stmt
+	readMany *sql.Stmt
+	// statements are prepared once the file can be opened
+	ready    bool
+	setupMtx sync.Mutex
+}
+
+func (c *client) ReadByKey(ctx context.Context, key string) ([]byte, error) {
+	if err := c.setup(ctx); err != nil {
+		return nil, err
+	}
+
+	row := c.readOne.QueryRowContext(ctx, key)
+
+	record := &reader.Record{}
+
+	if err := row.Scan(&record.Key, &record.Value); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return []byte{}, reader.ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return record.Value, nil
+}
+
+func (c *client) Read(ctx context.Context) ([]byte, error) {
+	if err := c.setup(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := c.readMany.QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	records := []*reader.Record{}
+
+	for rows.Next() {
+		record := &reader.Record{}
+
+		if err := rows.Scan(&record.Key, &record.Value); err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	values := make([][]byte, 0, len(records))
+
+	for _, record := range records {
+		values = append(values, record.Value)
+	}
+
+	return reader.JoinRecords(values, c.options.Format)
+}
+
+// dsn lets the reader and the writer share the file
+// without failing on each other's locks
+func dsn(location string) string {
+	separator := "?"
+	if strings.Contains(location, "?") {
+		separator = "&"
+	}
+
+	return location + separator + "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+}
+
+func NewReader(opts ...reader.Option) reader.Reader {
+	options := reader.NewOptions(opts...)
+
+	if err := options.Validate(); err != nil {
+		detail := "failed to validate sqlite reader options"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	c := &client{
+		options:  options,
+		setupMtx: sync.Mutex{},
+	}
+
+	// ./flags.db or file:flags.db
+	conn, err := sql.Open(DRIVER, dsn(c.options.Location))
+	if err != nil {
+		detail := "failed to connect with sqlite reader"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	if err := otelsql.RecordStats(conn); err != nil {
+		detail := "failed to initialize sqlite instrumentation for sqlite reader"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	c.conn = conn
+
+	return c
+}
+
+// setup runs on the first read rather than at startup so that
+// a file that cannot be opened does not keep the server from starting
+func (c *client) setup(ctx context.Context) error {
+	c.setupMtx.Lock()
+	defer c.setupMtx.Unlock()
+
+	if c.ready {
+		return nil
+	}
+
+	if err := c.conn.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping with sqlite reader: %w", err)
+	}
+
+	if _, err := c.conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS flags (key TEXT NOT NULL, value BLOB, CONSTRAINT flags_pkey PRIMARY KEY (key));`); err != nil {
+		return fmt.Errorf("failed to create table for sqlite reader: %w", err)
+	}
+
+	readOne, err := c.conn.PrepareContext(ctx, `SELECT key, value FROM flags WHERE key = ?;`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare select statement for sqlite reader: %w", err)
+	}
+	c.readOne = readOne
+
+	readMany, err := c.conn.PrepareContext(ctx, `SELECT key, value FROM flags ORDER BY key;`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare select statement for sqlite reader: %w", err)
+	}
+	c.readMany = readMany
+
+	c.ready = true
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package reader
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExtractKey pulls a single flag out of a whole flags document
+// and returns it as a document of its own in the same format.
+func ExtractKey(bs []byte, key string, format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case "json":
+		doc := map[string]json.RawMessage{}
+
+		if err := json.Unmarshal(bs, &doc); err != nil {
+			return nil, err
+		}
+
+		v, ok := doc[key]
+		if !ok {
+			return nil, ErrRecordNotFound
+		}
+
+		return json.Marshal(map[string]json.RawMessage{key: v})
+	default:
+		doc := map[string]yaml.Node{}
+
+		if err := yaml.Unmarshal(bs, &doc); err != nil {
+			return nil, err
+		}
+
+		v, ok := doc[key]
+		if !ok {
+			return nil, ErrRecordNotFound
+		}
+
+		return yaml.Marshal(map[string]*yaml.Node{key: &v})
+	}
+}
+
+// JoinRecords puts single flag documents back together into one flags document.
+// YAML documents can simply follow each other but JSON objects need merging.
+func JoinRecords(values [][]byte, format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case "json":
+		doc := map[string]json.RawMessage{}
+
+		for _, value := range values {
+			record := map[string]json.RawMessage{}
+
+			if err := json.Unmarshal(value, &record); err != nil {
+				return nil, err
+			}
+
+			for k, v := range record {
+				doc[k] = v
+			}
+		}
+
+		return json.Marshal(doc)
+	default:
+		result := []byte{}
+
+		for _, value := range values {
+			result = append(result, []byte("\n")...)
+			result = append(result, value...)
+		}
+
+		return result, nil
+	}
+}
+
+// ApplyChanges patches a flags document with a batch of changes
+// so that it matches what a full read would have returned
+func ApplyChanges(bs []byte, changes *Changes, format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case "json":
+		doc := map[string]json.RawMessage{}
+
+		if err := json.Unmarshal(bs, &doc); err != nil {
+			return nil, err
+		}
+
+		if len(bytes.TrimSpace(changes.Changed)) > 0 {
+			changed := map[string]json.RawMessage{}
+
+			if err := json.Unmarshal(changes.Changed, &changed); err != nil {
+				return nil, err
+			}
+
+			for k, v := range changed {
+				doc[k] = v
+			}
+		}
+
+		for _, k := range changes.Removed {
+			delete(doc, k)
+		}
+
+		return json.Marshal(doc)
+	default:
+		doc := map[string]yaml.Node{}
+
+		if err := yaml.Unmarshal(bs, &doc); err != nil {
+			return nil, err
+		}
+
+		changed := map[string]yaml.Node{}
+
+		if err := yaml.Unmarshal(changes.Changed, &changed); err != nil {
+			return nil, err
+		}
+
+		for k, v := range changed {
+			doc[k] = v
+		}
+
+		for _, k := range changes.Removed {
+			delete(doc, k)
+		}
+
+		result := map[string]*yaml.Node{}
+
+		for k := range doc {
+			v := doc[k]
+			result[k] = &v
+		}
+
+		return yaml.Marshal(result)
+	}
+}
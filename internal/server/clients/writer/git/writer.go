@@ -0,0 +1,177 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/w-h-a/flags/internal/server/clients/writer"
+)
+
+const (
+	remoteName     = "origin"
+	committerName  = "flags"
+	committerEmail = "flags@localhost"
+)
+
+type client struct {
+	options writer.Options
+	mtx     sync.Mutex
+}
+
+func (c *client) Write(ctx context.Context, key string, bs []byte) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if err := c.sync(ctx); err != nil {
+		return err
+	}
+
+	path := filepath.Join(c.options.Location, c.options.Path)
+
+	doc, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	merged, err := writer.MergeKey(doc, key, bs, c.options.Format)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, merged, 0644); err != nil {
+		return err
+	}
+
+	if _, err := c.git(ctx, "add", "--", c.options.Path); err != nil {
+		return err
+	}
+
+	// nothing to commit
+	if _, err := c.git(ctx, "diff", "--cached", "--quiet"); err == nil {
+		return nil
+	}
+
+	actor, ok := writer.Actor(ctx)
+	if !ok || len(actor) == 0 {
+		actor = committerName
+	}
+
+	if _, err := c.git(
+		ctx,
+		"-c", "user.name="+committerName,
+		"-c", "user.email="+committerEmail,
+		"commit",
+		"--author", author(actor),
+		"-m", fmt.Sprintf("Update flag %s", key),
+		"-m", fmt.Sprintf("Changed through the admin API by %s.", actor),
+	); err != nil {
+		return err
+	}
+
+	if len(c.options.Remote) == 0 {
+		return nil
+	}
+
+	if _, err := c.git(ctx, "push", remoteName, "HEAD:refs/heads/"+c.options.Branch); err != nil {
+		// leave the working copy as the remote has it
+		c.sync(ctx)
+		return err
+	}
+
+	return nil
+}
+
+// sync puts the working copy on the tip of the branch
+func (c *client) sync(ctx context.Context) error {
+	if len(c.options.Remote) == 0 {
+		_, err := c.git(ctx, "checkout", "--quiet", c.options.Branch)
+		return err
+	}
+
+	if _, err := c.git(ctx, "fetch", "--quiet", remoteName, c.options.Branch); err != nil {
+		return err
+	}
+
+	_, err := c.git(ctx, "checkout", "--quiet", "--force", "-B", c.options.Branch, remoteName+"/"+c.options.Branch)
+
+	return err
+}
+
+func (c *client) git(ctx context.Context, args ...string) ([]byte, error) {
+	return run(ctx, c.options.Location, args...)
+}
+
+func run(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+func author(actor string) string {
+	if strings.Contains(actor, "@") {
+		return fmt.Sprintf("%s <%s>", actor, actor)
+	}
+
+	return fmt.Sprintf("%s <>", actor)
+}
+
+func NewWriter(opts ...writer.Option) writer.Writer {
+	options := writer.NewOptions(opts...)
+
+	if err := options.Validate(); err != nil {
+		detail := "failed to validate git writer options"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	if len(options.Branch) == 0 || len(options.Path) == 0 {
+		detail := "failed to validate git writer options"
+		slog.ErrorContext(context.Background(), detail, "error", fmt.Errorf("missing branch or path"))
+		panic(detail)
+	}
+
+	c := &client{
+		options: options,
+		mtx:     sync.Mutex{},
+	}
+
+	// clone the remote if we do not have a working copy yet
+	if _, err := os.Stat(filepath.Join(c.options.Location, ".git")); err != nil {
+		if len(c.options.Remote) == 0 {
+			detail := "failed to find git repository for git writer"
+			slog.ErrorContext(context.Background(), detail, "error", err)
+			panic(detail)
+		}
+
+		if _, err := run(context.Background(), "", "clone", "--quiet", "--origin", remoteName, "--branch", c.options.Branch, c.options.Remote, c.options.Location); err != nil {
+			detail := "failed to clone git repository for git writer"
+			slog.ErrorContext(context.Background(), detail, "error", err)
+			panic(detail)
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,253 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/w-h-a/flags/internal/server/clients/writer"
+)
+
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("received status code %d from github", e.code)
+}
+
+type client struct {
+	options    writer.Options
+	httpClient *http.Client
+	api        string
+	repo       string
+	path       string
+	base       string
+}
+
+func (c *client) Write(ctx context.Context, key string, bs []byte) error {
+	_, err := c.Propose(ctx, key, bs)
+	return err
+}
+
+func (c *client) Propose(ctx context.Context, key string, bs []byte) (string, error) {
+	doc, fileSHA, err := c.getFile(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	merged, err := writer.MergeKey(doc, key, bs, c.options.Format)
+	if err != nil {
+		return "", err
+	}
+
+	baseSHA, err := c.getBaseSHA(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	branch := writer.ProposalBranch(key)
+
+	if err := c.do(ctx, http.MethodPost, "/git/refs", map[string]any{
+		"ref": "refs/heads/" + branch,
+		"sha": baseSHA,
+	}, nil); err != nil {
+		return "", err
+	}
+
+	title, body := writer.ProposalMessage(ctx, key)
+
+	commit := map[string]any{
+		"message": title + "\n\n" + body,
+		"content": base64.StdEncoding.EncodeToString(merged),
+		"branch":  branch,
+	}
+
+	if len(fileSHA) > 0 {
+		commit["sha"] = fileSHA
+	}
+
+	if err := c.do(ctx, http.MethodPut, "/contents/"+c.path, commit, nil); err != nil {
+		c.deleteBranch(ctx, branch)
+		return "", err
+	}
+
+	pull := struct {
+		HTMLURL string `json:"html_url"`
+	}{}
+
+	if err := c.do(ctx, http.MethodPost, "/pulls", map[string]any{
+		"title": title,
+		"body":  body,
+		"head":  branch,
+		"base":  c.base,
+	}, &pull); err != nil {
+		c.deleteBranch(ctx, branch)
+		return "", err
+	}
+
+	return pull.HTMLURL, nil
+}
+
+// deleteBranch cleans up after a proposal that could not be opened so
+// that failed writes do not leave branches behind
+func (c *client) deleteBranch(ctx context.Context, branch string) {
+	ctx = context.WithoutCancel(ctx)
+
+	if err := c.do(ctx, http.MethodDelete, "/git/refs/heads/"+branch, nil, nil); err != nil {
+		slog.WarnContext(ctx, "failed to delete proposal branch", "branch", branch, "error", err)
+	}
+}
+
+func (c *client) getFile(ctx context.Context) ([]byte, string, error) {
+	file := struct {
+		SHA     string `json:"sha"`
+		Content string `json:"content"`
+	}{}
+
+	var statusErr *statusError
+
+	err := c.do(ctx, http.MethodGet, "/contents/"+c.path+"?ref="+url.QueryEscape(c.base), nil, &file)
+	if errors.As(err, &statusErr) && statusErr.code == http.StatusNotFound {
+		// the change creates the file
+		return []byte{}, "", nil
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	// github wraps base64 content across lines
+	bs, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", ""))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return bs, file.SHA, nil
+}
+
+func (c *client) getBaseSHA(ctx context.Context) (string, error) {
+	ref := struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}{}
+
+	if err := c.do(ctx, http.MethodGet, "/git/ref/heads/"+c.base, nil, &ref); err != nil {
+		return "", err
+	}
+
+	return ref.Object.SHA, nil
+}
+
+func (c *client) do(ctx context.Context, method string, path string, in any, out any) error {
+	var body io.Reader
+
+	if in != nil {
+		bs, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(bs)
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		method,
+		fmt.Sprintf("%s/repos/%s%s", c.api, c.repo, path),
+		body,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Add("accept", "application/vnd.github+json")
+
+	if in != nil {
+		req.Header.Add("content-type", "application/json")
+	}
+
+	if len(c.options.Token) > 0 {
+		req.Header.Add("authorization", fmt.Sprintf("Bearer %s", c.options.Token))
+	}
+
+	rsp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %v", err)
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode > 399 {
+		return &statusError{code: rsp.StatusCode}
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(rsp.Body).Decode(out)
+}
+
+// parseLocation takes apart the location the github reader uses:
+// https://api.github.com/repos/:owner/:repo/contents/:filePath?ref=main
+func parseLocation(location string) (api, repo, path, base string, err error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", "", "", "", err
+	}
+
+	before, after, ok := strings.Cut(u.Path, "/repos/")
+	if !ok {
+		return "", "", "", "", fmt.Errorf("location is missing /repos/")
+	}
+
+	parts := strings.SplitN(after, "/", 4)
+	if len(parts) != 4 || parts[2] != "contents" || len(parts[3]) == 0 {
+		return "", "", "", "", fmt.Errorf("location is not a github contents url")
+	}
+
+	base = u.Query().Get("ref")
+	if len(base) == 0 {
+		base = "main"
+	}
+
+	api = fmt.Sprintf("%s://%s%s", u.Scheme, u.Host, before)
+
+	return api, parts[0] + "/" + parts[1], parts[3], base, nil
+}
+
+func NewWriter(opts ...writer.Option) writer.Writer {
+	options := writer.NewOptions(opts...)
+
+	if err := options.Validate(); err != nil {
+		detail := "failed to validate github writer options"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	api, repo, path, base, err := parseLocation(options.Location)
+	if err != nil {
+		detail := "failed to parse github writer location"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	c := &client{
+		options:    options,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		api:        api,
+		repo:       repo,
+		path:       path,
+		base:       base,
+	}
+
+	return c
+}
@@ -0,0 +1,255 @@
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/w-h-a/flags/internal/server/clients/writer"
+)
+
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("received status code %d from gitlab", e.code)
+}
+
+type client struct {
+	options    writer.Options
+	httpClient *http.Client
+	api        string
+	project    string
+	path       string
+	base       string
+}
+
+func (c *client) Write(ctx context.Context, key string, bs []byte) error {
+	_, err := c.Propose(ctx, key, bs)
+	return err
+}
+
+func (c *client) Propose(ctx context.Context, key string, bs []byte) (string, error) {
+	doc, found, err := c.getFile(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	merged, err := writer.MergeKey(doc, key, bs, c.options.Format)
+	if err != nil {
+		return "", err
+	}
+
+	branch := writer.ProposalBranch(key)
+
+	query := url.Values{}
+	query.Set("branch", branch)
+	query.Set("ref", c.base)
+
+	if err := c.do(ctx, http.MethodPost, "/repository/branches?"+query.Encode(), nil, nil); err != nil {
+		return "", err
+	}
+
+	title, description := writer.ProposalMessage(ctx, key)
+
+	action := "update"
+	if !found {
+		action = "create"
+	}
+
+	commit := map[string]any{
+		"branch":         branch,
+		"commit_message": title + "\n\n" + description,
+		"actions": []map[string]any{
+			{
+				"action":    action,
+				"file_path": c.path,
+				"content":   string(merged),
+			},
+		},
+	}
+
+	if err := c.do(ctx, http.MethodPost, "/repository/commits", commit, nil); err != nil {
+		c.deleteBranch(ctx, branch)
+		return "", err
+	}
+
+	mergeRequest := struct {
+		WebURL string `json:"web_url"`
+	}{}
+
+	if err := c.do(ctx, http.MethodPost, "/merge_requests", map[string]any{
+		"title":                title,
+		"description":          description,
+		"source_branch":        branch,
+		"target_branch":        c.base,
+		"remove_source_branch": true,
+	}, &mergeRequest); err != nil {
+		c.deleteBranch(ctx, branch)
+		return "", err
+	}
+
+	return mergeRequest.WebURL, nil
+}
+
+// deleteBranch cleans up after a proposal that could not be opened so
+// that failed writes do not leave branches behind
+func (c *client) deleteBranch(ctx context.Context, branch string) {
+	ctx = context.WithoutCancel(ctx)
+
+	if err := c.do(ctx, http.MethodDelete, "/repository/branches/"+url.PathEscape(branch), nil, nil); err != nil {
+		slog.WarnContext(ctx, "failed to delete proposal branch", "branch", branch, "error", err)
+	}
+}
+
+func (c *client) getFile(ctx context.Context) ([]byte, bool, error) {
+	var statusErr *statusError
+
+	bs, err := c.raw(ctx, http.MethodGet, "/repository/files/"+url.PathEscape(c.path)+"/raw?ref="+url.QueryEscape(c.base), nil)
+	if errors.As(err, &statusErr) && statusErr.code == http.StatusNotFound {
+		// the change creates the file
+		return []byte{}, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	return bs, true, nil
+}
+
+func (c *client) do(ctx context.Context, method string, path string, in any, out any) error {
+	var body io.Reader
+
+	if in != nil {
+		bs, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(bs)
+	}
+
+	bs, err := c.raw(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.Unmarshal(bs, out)
+}
+
+func (c *client) raw(ctx context.Context, method string, path string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		method,
+		fmt.Sprintf("%s/projects/%s%s", c.api, url.PathEscape(c.project), path),
+		body,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	if body != nil {
+		req.Header.Add("content-type", "application/json")
+	}
+
+	if len(c.options.Token) > 0 {
+		req.Header.Add("private-token", c.options.Token)
+	}
+
+	rsp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %v", err)
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode > 399 {
+		return nil, &statusError{code: rsp.StatusCode}
+	}
+
+	return io.ReadAll(rsp.Body)
+}
+
+// parseLocation takes apart the location the gitlab reader uses:
+// https://gitlab.com/api/v4/projects/:id/repository/files/:filePath/raw?ref=main
+func parseLocation(location string) (api, project, path, base string, err error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", "", "", "", err
+	}
+
+	// the project id and file path are url encoded
+	escaped := u.EscapedPath()
+
+	before, after, ok := strings.Cut(escaped, "/projects/")
+	if !ok {
+		return "", "", "", "", fmt.Errorf("location is missing /projects/")
+	}
+
+	project, rest, ok := strings.Cut(after, "/repository/files/")
+	if !ok {
+		return "", "", "", "", fmt.Errorf("location is not a gitlab repository file url")
+	}
+
+	rest = strings.TrimSuffix(rest, "/raw")
+
+	if project, err = url.PathUnescape(project); err != nil {
+		return "", "", "", "", err
+	}
+
+	if path, err = url.PathUnescape(rest); err != nil {
+		return "", "", "", "", err
+	}
+
+	if len(project) == 0 || len(path) == 0 {
+		return "", "", "", "", fmt.Errorf("location is not a gitlab repository file url")
+	}
+
+	base = u.Query().Get("ref")
+	if len(base) == 0 {
+		base = "main"
+	}
+
+	api = fmt.Sprintf("%s://%s%s", u.Scheme, u.Host, before)
+
+	return api, project, path, base, nil
+}
+
+func NewWriter(opts ...writer.Option) writer.Writer {
+	options := writer.NewOptions(opts...)
+
+	if err := options.Validate(); err != nil {
+		detail := "failed to validate gitlab writer options"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	api, project, path, base, err := parseLocation(options.Location)
+	if err != nil {
+		detail := "failed to parse gitlab writer location"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	c := &client{
+		options:    options,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		api:        api,
+		project:    project,
+		path:       path,
+		base:       base,
+	}
+
+	return c
+}
@@ -0,0 +1,101 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/w-h-a/flags/internal/server/clients/writer"
+)
+
+type client struct {
+	options writer.Options
+	mtx     sync.Mutex
+}
+
+func (c *client) Write(ctx context.Context, key string, bs []byte) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	// other processes may be editing the same file
+	unlock, err := lockFile(c.options.Location + ".lock")
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	mode := fs.FileMode(0644)
+
+	doc, err := os.ReadFile(c.options.Location)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if info, err := os.Stat(c.options.Location); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	merged, err := writer.MergeKey(doc, key, bs, c.options.Format)
+	if err != nil {
+		return err
+	}
+
+	return c.writeAtomic(merged, mode)
+}
+
+// writeAtomic makes sure readers never see a half written file
+func (c *client) writeAtomic(bs []byte, mode fs.FileMode) error {
+	dir, base := filepath.Split(c.options.Location)
+	if len(dir) == 0 {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+base+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(bs); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.options.Location)
+}
+
+func NewWriter(opts ...writer.Option) writer.Writer {
+	options := writer.NewOptions(opts...)
+
+	if err := options.Validate(); err != nil {
+		detail := "failed to validate local file writer options"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	c := &client{
+		options: options,
+		mtx:     sync.Mutex{},
+	}
+
+	return c
+}
@@ -10,6 +10,11 @@ type Option func(o *Options)
 type Options struct {
 	Location string
 	Token    string
+	Format   string
+	Remote   string
+	Branch   string
+	Path     string
+	Prefix   string
 	Context  context.Context
 }
 
@@ -33,8 +38,39 @@ func WithToken(token string) Option {
 	}
 }
 
+func WithFormat(format string) Option {
+	return func(o *Options) {
+		o.Format = format
+	}
+}
+
+func WithRemote(remote string) Option {
+	return func(o *Options) {
+		o.Remote = remote
+	}
+}
+
+func WithBranch(branch string) Option {
+	return func(o *Options) {
+		o.Branch = branch
+	}
+}
+
+func WithPath(path string) Option {
+	return func(o *Options) {
+		o.Path = path
+	}
+}
+
+func WithPrefix(prefix string) Option {
+	return func(o *Options) {
+		o.Prefix = prefix
+	}
+}
+
 func NewOptions(opts ...Option) Options {
 	options := Options{
+		Format:  "yaml",
 		Context: context.Background(),
 	}
 
@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server/clients/writer"
+)
+
+// jsonbSchema extends the flags table in place so that
+// blob readers keep working while writers migrate it
+var jsonbSchema = []string{
+	`ALTER TABLE flags ADD COLUMN IF NOT EXISTS definition jsonb;`,
+	`ALTER TABLE flags ADD COLUMN IF NOT EXISTS updated_at timestamptz NOT NULL DEFAULT now();`,
+	`ALTER TABLE flags ADD COLUMN IF NOT EXISTS updated_by text;`,
+	`ALTER TABLE flags ADD COLUMN IF NOT EXISTS tags text[] NOT NULL DEFAULT '{}';`,
+	`CREATE INDEX IF NOT EXISTS updated_at_index_flags ON flags (updated_at);`,
+	`CREATE INDEX IF NOT EXISTS definition_index_flags ON flags USING gin (definition jsonb_path_ops);`,
+	`CREATE INDEX IF NOT EXISTS tags_index_flags ON flags USING gin (tags);`,
+	// updates made by hand or by blob writers still move updated_at, and a
+	// value changed without its definition drops the definition so that
+	// readers derive it from the value again instead of serving it stale
+	`CREATE OR REPLACE FUNCTION flags_touch() RETURNS trigger AS $$ BEGIN NEW.updated_at = now(); IF NEW.value IS DISTINCT FROM OLD.value AND NEW.definition IS NOT DISTINCT FROM OLD.definition THEN NEW.definition = NULL; END IF; RETURN NEW; END; $$ LANGUAGE plpgsql;`,
+	`DO $$ BEGIN IF NOT EXISTS (SELECT 1 FROM pg_trigger WHERE tgname = 'flags_touch') THEN CREATE TRIGGER flags_touch BEFORE UPDATE ON flags FOR EACH ROW EXECUTE PROCEDURE flags_touch(); END IF; END $$;`,
+}
+
+// writeDefinition keeps the blob up to date as well
+// so that readers can move to jsonb after the writers
+func (c *client) writeDefinition(ctx context.Context, key string, bs []byte) error {
+	def, tags, err := flags.Definition(key, bs, c.options.Format)
+	if err != nil {
+		return err
+	}
+
+	actor, _ := writer.Actor(ctx)
+
+	if _, err := c.write.ExecContext(ctx, key, bs, def, pq.Array(tags), sql.NullString{String: actor, Valid: len(actor) > 0}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// backfill gives rows written by blob writers their definition
+func (c *client) backfill(ctx context.Context) error {
+	rows, err := c.conn.QueryContext(ctx, `SELECT key, value FROM flags WHERE definition IS NULL;`)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	values := map[string][]byte{}
+
+	for rows.Next() {
+		var key string
+		var value []byte
+
+		if err := rows.Scan(&key, &value); err != nil {
+			return err
+		}
+
+		values[key] = value
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for key, value := range values {
+		def, tags, err := flags.Definition(key, value, c.options.Format)
+		if err != nil {
+			return err
+		}
+
+		if _, err := c.conn.ExecContext(
+			ctx,
+			`UPDATE flags SET definition = $2::jsonb, tags = $3 WHERE key = $1 AND definition IS NULL;`,
+			key,
+			def,
+			pq.Array(tags),
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
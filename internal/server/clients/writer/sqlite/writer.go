@@ -0,0 +1,128 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/w-h-a/flags/internal/server/clients/writer"
+	"go.nhat.io/otelsql"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	_ "modernc.org/sqlite"
+)
+
+var DRIVER string
+
+func init() {
+	driver, err := otelsql.Register(
+		"sqlite",
+		otelsql.TraceQueryWithoutArgs(),
+		otelsql.TraceRowsClose(),
+		otelsql.TraceRowsAffected(),
+		otelsql.WithSystem(semconv.DBSystemSqlite),
+	)
+	if err != nil {
+		detail := "failed to register sqlite writer with otel"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	DRIVER = driver
+}
+
+type client struct {
+	options writer.Options
+	conn    *sql.DB
+	write   *sql.Stmt
+	// the table is created once the file can be opened
+	ready    bool
+	setupMtx sync.Mutex
+}
+
+func (c *client) Write(ctx context.Context, key string, bs []byte) error {
+	if err := c.setup(ctx); err != nil {
+		return err
+	}
+
+	if _, err := c.write.ExecContext(ctx, key, bs); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// dsn lets the reader and the writer share the file
+// without failing on each other's locks
+func dsn(location string) string {
+	separator := "?"
+	if strings.Contains(location, "?") {
+		separator = "&"
+	}
+
+	return location + separator + "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+}
+
+func NewWriter(opts ...writer.Option) writer.Writer {
+	options := writer.NewOptions(opts...)
+
+	if err := options.Validate(); err != nil {
+		detail := "failed to validate sqlite writer options"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	c := &client{
+		options:  options,
+		setupMtx: sync.Mutex{},
+	}
+
+	// ./flags.db or file:flags.db
+	conn, err := sql.Open(DRIVER, dsn(c.options.Location))
+	if err != nil {
+		detail := "failed to connect with sqlite writer"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	if err := otelsql.RecordStats(conn); err != nil {
+		detail := "failed to initialize sqlite instrumentation for sqlite writer"
+		slog.ErrorContext(context.Background(), detail, "error", err)
+		panic(detail)
+	}
+
+	c.conn = conn
+
+	return c
+}
+
+// setup runs on the first write rather than at startup so that
+// a file that cannot be opened does not keep the server from starting
+func (c *client) setup(ctx context.Context) error {
+	c.setupMtx.Lock()
+	defer c.setupMtx.Unlock()
+
+	if c.ready {
+		return nil
+	}
+
+	if err := c.conn.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping with sqlite writer: %w", err)
+	}
+
+	if _, err := c.conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS flags (key TEXT NOT NULL, value BLOB, CONSTRAINT flags_pkey PRIMARY KEY (key));`); err != nil {
+		return fmt.Errorf("failed to create table for sqlite writer: %w", err)
+	}
+
+	write, err := c.conn.PrepareContext(ctx, `INSERT INTO flags (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value;`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert statement for sqlite writer: %w", err)
+	}
+	c.write = write
+
+	c.ready = true
+
+	return nil
+}
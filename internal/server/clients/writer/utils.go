@@ -0,0 +1,237 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MergeKey puts the single flag document bs into the whole flags document doc.
+// An existing flag is replaced where it is and a new one is appended, so the
+// order of the other flags (and comments in YAML) survive the edit.
+func MergeKey(doc []byte, key string, bs []byte, format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case "json":
+		return mergeJSON(doc, key, bs)
+	default:
+		return mergeYAML(doc, key, bs)
+	}
+}
+
+type jsonMember struct {
+	key   string
+	value json.RawMessage
+}
+
+func mergeJSON(doc []byte, key string, bs []byte) ([]byte, error) {
+	flag := map[string]json.RawMessage{}
+
+	if err := json.Unmarshal(bs, &flag); err != nil {
+		return nil, err
+	}
+
+	value, ok := flag[key]
+	if !ok {
+		return nil, fmt.Errorf("document does not contain flag %q", key)
+	}
+
+	members, err := decodeJSONMembers(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+
+	for _, member := range members {
+		if member.key == key {
+			member.value = value
+			found = true
+		}
+	}
+
+	if !found {
+		members = append(members, &jsonMember{key: key, value: value})
+	}
+
+	compact := &bytes.Buffer{}
+
+	compact.WriteString("{")
+
+	for i, member := range members {
+		if i > 0 {
+			compact.WriteString(",")
+		}
+
+		k, err := json.Marshal(member.key)
+		if err != nil {
+			return nil, err
+		}
+
+		compact.Write(k)
+		compact.WriteString(":")
+		compact.Write(member.value)
+	}
+
+	compact.WriteString("}")
+
+	out := &bytes.Buffer{}
+
+	if err := json.Indent(out, compact.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+
+	out.WriteString("\n")
+
+	return out.Bytes(), nil
+}
+
+// decodeJSONMembers keeps the top level keys in document order
+func decodeJSONMembers(doc []byte) ([]*jsonMember, error) {
+	members := []*jsonMember{}
+
+	if len(bytes.TrimSpace(doc)) == 0 {
+		return members, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(doc))
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("flags document is not a json object")
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		k, _ := tok.(string)
+
+		var value json.RawMessage
+
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+
+		members = append(members, &jsonMember{key: k, value: value})
+	}
+
+	return members, nil
+}
+
+func mergeYAML(doc []byte, key string, bs []byte) ([]byte, error) {
+	flag := &yaml.Node{}
+
+	if err := yaml.Unmarshal(bs, flag); err != nil {
+		return nil, err
+	}
+
+	value := lookupYAML(flag, key)
+	if value == nil {
+		return nil, fmt.Errorf("document does not contain flag %q", key)
+	}
+
+	root := &yaml.Node{}
+
+	if err := yaml.Unmarshal(doc, root); err != nil {
+		return nil, err
+	}
+
+	if root.Kind == 0 {
+		root = &yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+		}
+	}
+
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("flags document is not a yaml mapping")
+	}
+
+	mapping := root.Content[0]
+
+	found := false
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		mapping.Content = append(
+			mapping.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+			value,
+		)
+	}
+
+	out := &bytes.Buffer{}
+
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(root); err != nil {
+		return nil, err
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+func lookupYAML(root *yaml.Node, key string) *yaml.Node {
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return nil
+	}
+
+	mapping := root.Content[0]
+
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+var unsafeRefChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// ProposalBranch names the branch that carries a proposed change to a flag.
+// Anything in the key that git might not accept in a ref name, including
+// the project separator, becomes a dash.
+func ProposalBranch(key string) string {
+	return fmt.Sprintf("flags/%s-%d", unsafeRefChars.ReplaceAllString(key, "-"), time.Now().UnixNano())
+}
+
+// ProposalMessage describes a proposed change to a flag
+func ProposalMessage(ctx context.Context, key string) (string, string) {
+	title := fmt.Sprintf("Update flag %s", key)
+
+	actor, ok := Actor(ctx)
+	if !ok || len(actor) == 0 {
+		return title, "Requested through the admin API."
+	}
+
+	return title, fmt.Sprintf("Requested through the admin API by %s.", actor)
+}
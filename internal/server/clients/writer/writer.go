@@ -7,3 +7,21 @@ import (
 type Writer interface {
 	Write(ctx context.Context, key string, bs []byte) error
 }
+
+// Proposer is implemented by writers that put a change up
+// for review instead of applying it. Propose returns a link
+// to the proposed change.
+type Proposer interface {
+	Propose(ctx context.Context, key string, bs []byte) (string, error)
+}
+
+type actorKey struct{}
+
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func Actor(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(actorKey{}).(string)
+	return actor, ok
+}
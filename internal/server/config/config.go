@@ -1,7 +1,9 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -13,67 +15,164 @@ var (
 )
 
 type config struct {
-	env                 string
-	region              string
+	env                        string
+	region                     string
+	name                       string
+	version                    string
+	httpAddress                string
+	apiKeys                    map[string]*apiKey
+	environments               []*environment
+	projects                   []*project
+	authClient                 string
+	authClientLocation         string
+	authClientIssuer           string
+	authClientAudience         string
+	authClientRolesClaim       string
+	authClientReadRole         string
+	authClientWriteRole        string
+	logsExporter               string
+	logsAddress                string
+	logsUrlPath                string
+	logsAPIToken               string
+	tracesExporter             string
+	tracesAddress              string
+	metricsExporter            string
+	metricsAddress             string
+	flagFormat                 string
+	writeClient                string
+	writeClientLocation        string
+	writeClientToken           string
+	writeClientRemote          string
+	writeClientBranch          string
+	writeClientPath            string
+	writeClientPrefix          string
+	writeClientSchema          string
+	readClient                 string
+	readClientLocation         string
+	readClientToken            string
+	readClientHeaders          map[string]string
+	readClientRemote           string
+	readClientBranch           string
+	readClientPath             string
+	readClientPrefix           string
+	readClientSchema           string
+	readClientStream           bool
+	readClientEndpoint         string
+	readClientTimeout          int
+	readClientRetries          int
+	readClientBreakerThreshold int
+	readClientBreakerCooldown  int
+	readClientLayers           string
+	readClientMerge            string
+	readClientSnapshot         string
+	readClientPublicKeys       string
+	lenientLoading             bool
+	readInterval               int
+	exportReports              bool
+	exportClient               string
+	exportClientDir            string
+	exportInterval             int
+	notifyClient               string
+	notifyURL                  string
+	broadcastClient            string
+	broadcastClientLocation    string
+}
+
+// environment overrides the clients for one of several environments
+type environment struct {
 	name                string
-	version             string
-	httpAddress         string
-	apiKeys             map[string]bool
-	logsExporter        string
-	logsAddress         string
-	logsUrlPath         string
-	logsAPIToken        string
-	tracesExporter      string
-	tracesAddress       string
-	metricsExporter     string
-	metricsAddress      string
-	flagFormat          string
-	writeClient         string
-	writeClientLocation string
-	writeClientToken    string
 	readClient          string
 	readClientLocation  string
-	readClientToken     string
-	readInterval        int
-	exportReports       bool
-	exportClient        string
-	exportClientDir     string
-	exportInterval      int
-	notifyClient        string
-	notifyURL           string
+	readClientSnapshot  string
+	writeClient         string
+	writeClientLocation string
+}
+
+// project groups flags for one team
+type project struct {
+	name         string
+	notifyClient string
+	notifyURL    string
+}
+
+// apiKey is limited to an environment and a project when they are set
+type apiKey struct {
+	environment string
+	project     string
+}
+
+func (c *config) apiKey(key string) *apiKey {
+	if _, ok := c.apiKeys[key]; !ok {
+		c.apiKeys[key] = &apiKey{}
+	}
+
+	return c.apiKeys[key]
 }
 
 func New() {
 	once.Do(func() {
 		instance = &config{
-			env:                 "dev",
-			region:              "local",
-			name:                "flags",
-			version:             "0.1.0-alpha.0",
-			httpAddress:         ":0",
-			apiKeys:             map[string]bool{},
-			logsExporter:        "stdout",
-			logsAddress:         "",
-			logsUrlPath:         "",
-			logsAPIToken:        "",
-			tracesExporter:      "otlp",
-			tracesAddress:       "localhost:4318",
-			metricsExporter:     "otlp",
-			metricsAddress:      "localhost:4318",
-			flagFormat:          "yaml",
-			writeClient:         "noop",
-			writeClientLocation: "noop",
-			writeClientToken:    "",
-			readClient:          "local",
-			readClientLocation:  "./flags.yaml",
-			readClientToken:     "",
-			readInterval:        60,
-			exportReports:       false,
-			exportClient:        "local",
-			exportClientDir:     "/tmp",
-			exportInterval:      120,
-			notifyClient:        "local",
-			notifyURL:           "",
+			env:                        "dev",
+			region:                     "local",
+			name:                       "flags",
+			version:                    "0.1.0-alpha.0",
+			httpAddress:                ":0",
+			apiKeys:                    map[string]*apiKey{},
+			environments:               []*environment{},
+			projects:                   []*project{},
+			authClient:                 "apikey",
+			authClientLocation:         "",
+			authClientIssuer:           "",
+			authClientAudience:         "",
+			authClientRolesClaim:       "roles",
+			authClientReadRole:         "flags:read",
+			authClientWriteRole:        "flags:write",
+			logsExporter:               "stdout",
+			logsAddress:                "",
+			logsUrlPath:                "",
+			logsAPIToken:               "",
+			tracesExporter:             "otlp",
+			tracesAddress:              "localhost:4318",
+			metricsExporter:            "otlp",
+			metricsAddress:             "localhost:4318",
+			flagFormat:                 "yaml",
+			writeClient:                "noop",
+			writeClientLocation:        "noop",
+			writeClientToken:           "",
+			writeClientRemote:          "",
+			writeClientBranch:          "main",
+			writeClientPath:            "flags.yaml",
+			writeClientPrefix:          "flags:",
+			writeClientSchema:          "blob",
+			readClient:                 "local",
+			readClientLocation:         "./flags.yaml",
+			readClientToken:            "",
+			readClientHeaders:          map[string]string{},
+			readClientRemote:           "",
+			readClientBranch:           "main",
+			readClientPath:             "flags.yaml",
+			readClientPrefix:           "flags:",
+			readClientSchema:           "blob",
+			readClientStream:           false,
+			readClientEndpoint:         "",
+			readClientTimeout:          10,
+			readClientRetries:          2,
+			readClientBreakerThreshold: 5,
+			readClientBreakerCooldown:  30,
+			readClientLayers:           "",
+			readClientMerge:            "flag",
+			readClientSnapshot:         "",
+			readClientPublicKeys:       "",
+			lenientLoading:             false,
+			readInterval:               60,
+			exportReports:              false,
+			exportClient:               "local",
+			exportClientDir:            "/tmp",
+			exportInterval:             120,
+			notifyClient:               "local",
+			notifyURL:                  "",
+			broadcastClient:            "local",
+			broadcastClientLocation:    "",
 		}
 
 		env := os.Getenv("ENV")
@@ -105,10 +204,102 @@ func New() {
 		if len(apiKeys) > 0 {
 			keys := strings.Split(apiKeys, ",")
 			for _, k := range keys {
-				instance.apiKeys[k] = true
+				instance.apiKey(k)
+			}
+		}
+
+		// ENVIRONMENTS=dev,staging,prod with STAGING_READ_CLIENT_LOCATION=...
+		environments := os.Getenv("ENVIRONMENTS")
+		if len(environments) > 0 {
+			for _, name := range strings.Split(environments, ",") {
+				name = strings.TrimSpace(name)
+				if len(name) == 0 {
+					continue
+				}
+
+				prefix := strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+
+				instance.environments = append(instance.environments, &environment{
+					name:                name,
+					readClient:          os.Getenv(prefix + "READ_CLIENT"),
+					readClientLocation:  os.Getenv(prefix + "READ_CLIENT_LOCATION"),
+					readClientSnapshot:  os.Getenv(prefix + "READ_CLIENT_SNAPSHOT"),
+					writeClient:         os.Getenv(prefix + "WRITE_CLIENT"),
+					writeClientLocation: os.Getenv(prefix + "WRITE_CLIENT_LOCATION"),
+				})
+
+				apiKeys := os.Getenv(prefix + "API_KEYS")
+				if len(apiKeys) > 0 {
+					keys := strings.Split(apiKeys, ",")
+					for _, k := range keys {
+						instance.apiKey(k).environment = name
+					}
+				}
 			}
 		}
 
+		// PROJECTS=checkout,search with PROJECT_CHECKOUT_API_KEYS=...
+		projects := os.Getenv("PROJECTS")
+		if len(projects) > 0 {
+			for _, name := range strings.Split(projects, ",") {
+				name = strings.TrimSpace(name)
+				if len(name) == 0 {
+					continue
+				}
+
+				prefix := "PROJECT_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+
+				instance.projects = append(instance.projects, &project{
+					name:         name,
+					notifyClient: os.Getenv(prefix + "NOTIFY_CLIENT"),
+					notifyURL:    os.Getenv(prefix + "NOTIFY_URL"),
+				})
+
+				apiKeys := os.Getenv(prefix + "API_KEYS")
+				if len(apiKeys) > 0 {
+					keys := strings.Split(apiKeys, ",")
+					for _, k := range keys {
+						instance.apiKey(k).project = name
+					}
+				}
+			}
+		}
+
+		authClient := os.Getenv("AUTH_CLIENT")
+		if len(authClient) > 0 {
+			instance.authClient = authClient
+		}
+
+		authClientLocation := os.Getenv("AUTH_CLIENT_LOCATION")
+		if len(authClientLocation) > 0 {
+			instance.authClientLocation = authClientLocation
+		}
+
+		authClientIssuer := os.Getenv("AUTH_CLIENT_ISSUER")
+		if len(authClientIssuer) > 0 {
+			instance.authClientIssuer = authClientIssuer
+		}
+
+		authClientAudience := os.Getenv("AUTH_CLIENT_AUDIENCE")
+		if len(authClientAudience) > 0 {
+			instance.authClientAudience = authClientAudience
+		}
+
+		authClientRolesClaim := os.Getenv("AUTH_CLIENT_ROLES_CLAIM")
+		if len(authClientRolesClaim) > 0 {
+			instance.authClientRolesClaim = authClientRolesClaim
+		}
+
+		authClientReadRole := os.Getenv("AUTH_CLIENT_READ_ROLE")
+		if len(authClientReadRole) > 0 {
+			instance.authClientReadRole = authClientReadRole
+		}
+
+		authClientWriteRole := os.Getenv("AUTH_CLIENT_WRITE_ROLE")
+		if len(authClientWriteRole) > 0 {
+			instance.authClientWriteRole = authClientWriteRole
+		}
+
 		logsExporter := os.Getenv("LOGS_EXPORTER")
 		if len(logsExporter) > 0 {
 			instance.logsExporter = logsExporter
@@ -169,6 +360,31 @@ func New() {
 			instance.writeClientToken = writeClientToken
 		}
 
+		writeClientRemote := os.Getenv("WRITE_CLIENT_REMOTE")
+		if len(writeClientRemote) > 0 {
+			instance.writeClientRemote = writeClientRemote
+		}
+
+		writeClientBranch := os.Getenv("WRITE_CLIENT_BRANCH")
+		if len(writeClientBranch) > 0 {
+			instance.writeClientBranch = writeClientBranch
+		}
+
+		writeClientPath := os.Getenv("WRITE_CLIENT_PATH")
+		if len(writeClientPath) > 0 {
+			instance.writeClientPath = writeClientPath
+		}
+
+		writeClientPrefix := os.Getenv("WRITE_CLIENT_PREFIX")
+		if len(writeClientPrefix) > 0 {
+			instance.writeClientPrefix = writeClientPrefix
+		}
+
+		writeClientSchema := os.Getenv("WRITE_CLIENT_SCHEMA")
+		if len(writeClientSchema) > 0 {
+			instance.writeClientSchema = writeClientSchema
+		}
+
 		readClient := os.Getenv("READ_CLIENT")
 		if len(readClient) > 0 {
 			instance.readClient = readClient
@@ -184,6 +400,106 @@ func New() {
 			instance.readClientToken = readClientToken
 		}
 
+		readClientHeaders := os.Getenv("READ_CLIENT_HEADERS")
+		if len(readClientHeaders) > 0 {
+			headers := strings.Split(readClientHeaders, ",")
+			for _, h := range headers {
+				name, value, ok := strings.Cut(h, ":")
+				if !ok || len(strings.TrimSpace(name)) == 0 {
+					continue
+				}
+				instance.readClientHeaders[strings.TrimSpace(name)] = strings.TrimSpace(value)
+			}
+		}
+
+		readClientRemote := os.Getenv("READ_CLIENT_REMOTE")
+		if len(readClientRemote) > 0 {
+			instance.readClientRemote = readClientRemote
+		}
+
+		readClientBranch := os.Getenv("READ_CLIENT_BRANCH")
+		if len(readClientBranch) > 0 {
+			instance.readClientBranch = readClientBranch
+		}
+
+		readClientPath := os.Getenv("READ_CLIENT_PATH")
+		if len(readClientPath) > 0 {
+			instance.readClientPath = readClientPath
+		}
+
+		readClientPrefix := os.Getenv("READ_CLIENT_PREFIX")
+		if len(readClientPrefix) > 0 {
+			instance.readClientPrefix = readClientPrefix
+		}
+
+		readClientSchema := os.Getenv("READ_CLIENT_SCHEMA")
+		if len(readClientSchema) > 0 {
+			instance.readClientSchema = readClientSchema
+		}
+
+		readClientStream := os.Getenv("READ_CLIENT_STREAM")
+		if len(readClientStream) > 0 {
+			instance.readClientStream = readClientStream == "true"
+		}
+
+		readClientEndpoint := os.Getenv("READ_CLIENT_ENDPOINT")
+		if len(readClientEndpoint) > 0 {
+			instance.readClientEndpoint = readClientEndpoint
+		}
+
+		readClientTimeout := os.Getenv("READ_CLIENT_TIMEOUT")
+		if len(readClientTimeout) > 0 {
+			if v, err := strconv.Atoi(readClientTimeout); err == nil && v >= 1 {
+				instance.readClientTimeout = v
+			}
+		}
+
+		readClientRetries := os.Getenv("READ_CLIENT_RETRIES")
+		if len(readClientRetries) > 0 {
+			if v, err := strconv.Atoi(readClientRetries); err == nil && v >= 0 {
+				instance.readClientRetries = v
+			}
+		}
+
+		readClientBreakerThreshold := os.Getenv("READ_CLIENT_BREAKER_THRESHOLD")
+		if len(readClientBreakerThreshold) > 0 {
+			if v, err := strconv.Atoi(readClientBreakerThreshold); err == nil && v >= 0 {
+				instance.readClientBreakerThreshold = v
+			}
+		}
+
+		readClientBreakerCooldown := os.Getenv("READ_CLIENT_BREAKER_COOLDOWN")
+		if len(readClientBreakerCooldown) > 0 {
+			if v, err := strconv.Atoi(readClientBreakerCooldown); err == nil && v >= 0 {
+				instance.readClientBreakerCooldown = v
+			}
+		}
+
+		readClientLayers := os.Getenv("READ_CLIENT_LAYERS")
+		if len(readClientLayers) > 0 {
+			instance.readClientLayers = readClientLayers
+		}
+
+		readClientMerge := os.Getenv("READ_CLIENT_MERGE")
+		if len(readClientMerge) > 0 {
+			instance.readClientMerge = readClientMerge
+		}
+
+		readClientSnapshot := os.Getenv("READ_CLIENT_SNAPSHOT")
+		if len(readClientSnapshot) > 0 {
+			instance.readClientSnapshot = readClientSnapshot
+		}
+
+		readClientPublicKeys := os.Getenv("READ_CLIENT_PUBLIC_KEYS")
+		if len(readClientPublicKeys) > 0 {
+			instance.readClientPublicKeys = readClientPublicKeys
+		}
+
+		lenientLoading := os.Getenv("LENIENT_LOADING")
+		if len(lenientLoading) > 0 {
+			instance.lenientLoading = lenientLoading == "true"
+		}
+
 		readInterval := os.Getenv("READ_INTERVAL")
 		if len(readInterval) > 0 {
 			if interval, err := strconv.Atoi(readInterval); err == nil && interval >= 1 {
@@ -224,6 +540,16 @@ func New() {
 		if len(notifyURL) > 0 {
 			instance.notifyURL = notifyURL
 		}
+
+		broadcastClient := os.Getenv("BROADCAST_CLIENT")
+		if len(broadcastClient) > 0 {
+			instance.broadcastClient = broadcastClient
+		}
+
+		broadcastClientLocation := os.Getenv("BROADCAST_CLIENT_LOCATION")
+		if len(broadcastClientLocation) > 0 {
+			instance.broadcastClientLocation = broadcastClientLocation
+		}
 	})
 }
 
@@ -268,12 +594,266 @@ func HttpAddress() string {
 }
 
 func CheckAPIKey(key string) bool {
+	_, ok := APIKeyEnvironment(key)
+	return ok
+}
+
+// APIKeyEnvironment returns the environment a key is limited to
+// or an empty string for keys that work in every environment
+func APIKeyEnvironment(key string) (string, bool) {
 	if instance == nil {
-		return false
+		return "", false
 	}
 
-	_, ok := instance.apiKeys[key]
-	return ok
+	k, ok := instance.apiKeys[key]
+	if !ok {
+		return "", false
+	}
+
+	return k.environment, true
+}
+
+// APIKeyProject returns the project a key is limited to
+// or an empty string for keys that see every flag
+func APIKeyProject(key string) (string, bool) {
+	if instance == nil {
+		return "", false
+	}
+
+	k, ok := instance.apiKeys[key]
+	if !ok {
+		return "", false
+	}
+
+	return k.project, true
+}
+
+func Projects() []string {
+	if instance == nil {
+		return nil
+	}
+
+	names := []string{}
+
+	for _, p := range instance.projects {
+		names = append(names, p.name)
+	}
+
+	return names
+}
+
+func HasProject(name string) bool {
+	return projectOf(name) != nil
+}
+
+// ProjectNotifyClient is empty for projects notified like the rest
+func ProjectNotifyClient(name string) string {
+	if p := projectOf(name); p != nil {
+		return p.notifyClient
+	}
+
+	return ""
+}
+
+func ProjectNotifyURL(name string) string {
+	if p := projectOf(name); p != nil {
+		return p.notifyURL
+	}
+
+	return ""
+}
+
+func projectOf(name string) *project {
+	if instance == nil {
+		return nil
+	}
+
+	for _, p := range instance.projects {
+		if p.name == name {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// Environments returns the environments served side by side
+// or just the one this instance runs in
+func Environments() []string {
+	if instance == nil {
+		return nil
+	}
+
+	if len(instance.environments) == 0 {
+		return []string{instance.env}
+	}
+
+	names := []string{}
+
+	for _, e := range instance.environments {
+		names = append(names, e.name)
+	}
+
+	return names
+}
+
+// ValidateEnvironments refuses environments that would share a store.
+// They would overwrite each other's flags and promoting between them
+// would change nothing.
+func ValidateEnvironments() error {
+	if instance == nil || len(instance.environments) < 2 {
+		return nil
+	}
+
+	reads := map[string]string{}
+	writes := map[string]string{}
+
+	for _, e := range instance.environments {
+		read := EnvironmentReadClient(e.name) + " " + EnvironmentReadClientLocation(e.name)
+
+		if other, ok := reads[read]; ok {
+			return fmt.Errorf("environments %q and %q read from the same location, set <ENV>_READ_CLIENT_LOCATION for each", other, e.name)
+		}
+
+		reads[read] = e.name
+
+		if EnvironmentWriteClient(e.name) == "noop" {
+			continue
+		}
+
+		write := EnvironmentWriteClient(e.name) + " " + EnvironmentWriteClientLocation(e.name)
+
+		if other, ok := writes[write]; ok {
+			return fmt.Errorf("environments %q and %q write to the same location, set <ENV>_WRITE_CLIENT_LOCATION for each", other, e.name)
+		}
+
+		writes[write] = e.name
+	}
+
+	return nil
+}
+
+func EnvironmentReadClient(env string) string {
+	if e := environmentOf(env); e != nil && len(e.readClient) > 0 {
+		return e.readClient
+	}
+
+	return ReadClient()
+}
+
+func EnvironmentReadClientLocation(env string) string {
+	if e := environmentOf(env); e != nil && len(e.readClientLocation) > 0 {
+		return e.readClientLocation
+	}
+
+	return ReadClientLocation()
+}
+
+// EnvironmentReadClientSnapshot keeps environments from sharing one snapshot
+func EnvironmentReadClientSnapshot(env string) string {
+	if e := environmentOf(env); e != nil && len(e.readClientSnapshot) > 0 {
+		return e.readClientSnapshot
+	}
+
+	snapshot := ReadClientSnapshot()
+
+	if len(snapshot) == 0 || len(instance.environments) < 2 {
+		return snapshot
+	}
+
+	if info, err := os.Stat(snapshot); err == nil && info.IsDir() {
+		return filepath.Join(snapshot, "flags."+env+".snapshot")
+	}
+
+	ext := filepath.Ext(snapshot)
+
+	return strings.TrimSuffix(snapshot, ext) + "." + env + ext
+}
+
+func EnvironmentWriteClient(env string) string {
+	if e := environmentOf(env); e != nil && len(e.writeClient) > 0 {
+		return e.writeClient
+	}
+
+	return WriteClient()
+}
+
+func EnvironmentWriteClientLocation(env string) string {
+	if e := environmentOf(env); e != nil && len(e.writeClientLocation) > 0 {
+		return e.writeClientLocation
+	}
+
+	return WriteClientLocation()
+}
+
+func environmentOf(env string) *environment {
+	if instance == nil {
+		return nil
+	}
+
+	for _, e := range instance.environments {
+		if e.name == env {
+			return e
+		}
+	}
+
+	return nil
+}
+
+func AuthClient() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.authClient
+}
+
+func AuthClientLocation() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.authClientLocation
+}
+
+func AuthClientIssuer() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.authClientIssuer
+}
+
+func AuthClientAudience() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.authClientAudience
+}
+
+func AuthClientRolesClaim() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.authClientRolesClaim
+}
+
+func AuthClientReadRole() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.authClientReadRole
+}
+
+func AuthClientWriteRole() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.authClientWriteRole
 }
 
 func LogsExporter() string {
@@ -372,6 +952,46 @@ func WriteClientToken() string {
 	return instance.writeClientToken
 }
 
+func WriteClientRemote() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.writeClientRemote
+}
+
+func WriteClientBranch() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.writeClientBranch
+}
+
+func WriteClientPath() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.writeClientPath
+}
+
+func WriteClientPrefix() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.writeClientPrefix
+}
+
+func WriteClientSchema() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.writeClientSchema
+}
+
 func ReadClient() string {
 	if instance == nil {
 		return ""
@@ -396,6 +1016,148 @@ func ReadClientToken() string {
 	return instance.readClientToken
 }
 
+func ReadClientHeaders() map[string]string {
+	if instance == nil {
+		return map[string]string{}
+	}
+
+	headers := map[string]string{}
+
+	for k, v := range instance.readClientHeaders {
+		headers[k] = v
+	}
+
+	return headers
+}
+
+func ReadClientRemote() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.readClientRemote
+}
+
+func ReadClientBranch() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.readClientBranch
+}
+
+func ReadClientPath() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.readClientPath
+}
+
+func ReadClientPrefix() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.readClientPrefix
+}
+
+func ReadClientSchema() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.readClientSchema
+}
+
+func ReadClientStream() bool {
+	if instance == nil {
+		return false
+	}
+
+	return instance.readClientStream
+}
+
+func ReadClientEndpoint() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.readClientEndpoint
+}
+
+func ReadClientTimeout() int {
+	if instance == nil {
+		return 0
+	}
+
+	return instance.readClientTimeout
+}
+
+func ReadClientRetries() int {
+	if instance == nil {
+		return 0
+	}
+
+	return instance.readClientRetries
+}
+
+func ReadClientBreakerThreshold() int {
+	if instance == nil {
+		return 0
+	}
+
+	return instance.readClientBreakerThreshold
+}
+
+func ReadClientBreakerCooldown() int {
+	if instance == nil {
+		return 0
+	}
+
+	return instance.readClientBreakerCooldown
+}
+
+func ReadClientLayers() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.readClientLayers
+}
+
+func ReadClientMerge() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.readClientMerge
+}
+
+func ReadClientSnapshot() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.readClientSnapshot
+}
+
+func ReadClientPublicKeys() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.readClientPublicKeys
+}
+
+func LenientLoading() bool {
+	if instance == nil {
+		return false
+	}
+
+	return instance.lenientLoading
+}
+
 func ReadInterval() int {
 	if instance == nil {
 		return 0
@@ -452,31 +1214,76 @@ func NotifyURL() string {
 	return instance.notifyURL
 }
 
+func BroadcastClient() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.broadcastClient
+}
+
+func BroadcastClientLocation() string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.broadcastClientLocation
+}
+
 // used for test purposes only
 func Reset() {
 	instance = &config{
-		env:                 "dev",
-		region:              "local",
-		name:                "flags",
-		version:             "0.1.0-alpha.0",
-		httpAddress:         ":0",
-		apiKeys:             map[string]bool{},
-		tracesAddress:       "localhost:4318",
-		metricsAddress:      "localhost:4318",
-		flagFormat:          "yaml",
-		writeClient:         "noop",
-		writeClientLocation: "noop",
-		writeClientToken:    "",
-		readClient:          "local",
-		readClientLocation:  "./flags.yaml",
-		readClientToken:     "",
-		readInterval:        60,
-		exportReports:       false,
-		exportClient:        "local",
-		exportClientDir:     "/tmp",
-		exportInterval:      120,
-		notifyClient:        "local",
-		notifyURL:           "",
+		env:                        "dev",
+		region:                     "local",
+		name:                       "flags",
+		version:                    "0.1.0-alpha.0",
+		httpAddress:                ":0",
+		apiKeys:                    map[string]*apiKey{},
+		environments:               []*environment{},
+		projects:                   []*project{},
+		authClient:                 "apikey",
+		authClientRolesClaim:       "roles",
+		authClientReadRole:         "flags:read",
+		authClientWriteRole:        "flags:write",
+		tracesAddress:              "localhost:4318",
+		metricsAddress:             "localhost:4318",
+		flagFormat:                 "yaml",
+		writeClient:                "noop",
+		writeClientLocation:        "noop",
+		writeClientToken:           "",
+		writeClientRemote:          "",
+		writeClientBranch:          "main",
+		writeClientPath:            "flags.yaml",
+		writeClientPrefix:          "flags:",
+		writeClientSchema:          "blob",
+		readClient:                 "local",
+		readClientLocation:         "./flags.yaml",
+		readClientToken:            "",
+		readClientHeaders:          map[string]string{},
+		readClientRemote:           "",
+		readClientBranch:           "main",
+		readClientPath:             "flags.yaml",
+		readClientPrefix:           "flags:",
+		readClientSchema:           "blob",
+		readClientStream:           false,
+		readClientEndpoint:         "",
+		readClientTimeout:          10,
+		readClientRetries:          2,
+		readClientBreakerThreshold: 5,
+		readClientBreakerCooldown:  30,
+		readClientLayers:           "",
+		readClientMerge:            "flag",
+		readClientSnapshot:         "",
+		readClientPublicKeys:       "",
+		lenientLoading:             false,
+		readInterval:               60,
+		exportReports:              false,
+		exportClient:               "local",
+		exportClientDir:            "/tmp",
+		exportInterval:             120,
+		notifyClient:               "local",
+		notifyURL:                  "",
+		broadcastClient:            "local",
 	}
 
 	once = sync.Once{}
@@ -1,18 +1,22 @@
 package http
 
 import (
+	"context"
 	"errors"
+	"log/slog"
 	"net/http"
-	"sync"
 
 	"github.com/w-h-a/flags/internal/flags"
 	"github.com/w-h-a/flags/internal/server/services/admin"
+	"github.com/w-h-a/flags/internal/server/services/cache"
+	"github.com/w-h-a/flags/internal/server/services/notify"
 )
 
 type Admin struct {
-	adminService *admin.Service
-	parser       *Parser
-	mtx          sync.RWMutex
+	adminService  *admin.Service
+	cacheService  *cache.Service
+	notifyService *notify.Service
+	parser        *Parser
 }
 
 func (a *Admin) GetOne(w http.ResponseWriter, r *http.Request) {
@@ -77,12 +81,22 @@ func (a *Admin) PutOne(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if a.adminService.Proposes() {
+		a.propose(ctx, w, flagKey, flag)
+		return
+	}
+
 	upserted, err := a.adminService.UpsertFlag(ctx, flagKey, flag)
 	if err != nil {
 		writeRsp(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
 		return
 	}
 
+	// the write succeeded so a failed refresh should not fail the request
+	if _, err := a.refresh(ctx); err != nil {
+		slog.WarnContext(ctx, "failed to refresh the cache after write", "flag", flagKey, "error", err)
+	}
+
 	if found {
 		writeRsp(w, http.StatusOK, upserted)
 	} else {
@@ -90,6 +104,31 @@ func (a *Admin) PutOne(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (a *Admin) Validate(w http.ResponseWriter, r *http.Request) {
+	ctx := reqToCtx(r)
+
+	bs, format, err := a.parser.ParseValidateBody(ctx, r)
+	if err != nil {
+		writeRsp(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+
+	withDiff := a.parser.ParseDiffQuery(ctx, r)
+
+	validation, err := a.adminService.ValidateFlags(ctx, bs, format, withDiff)
+	if err != nil {
+		writeRsp(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+
+	if !validation.Valid {
+		writeRsp(w, http.StatusUnprocessableEntity, validation)
+		return
+	}
+
+	writeRsp(w, http.StatusOK, validation)
+}
+
 func (a *Admin) PatchOne(w http.ResponseWriter, r *http.Request) {
 	ctx := reqToCtx(r)
 
@@ -99,7 +138,7 @@ func (a *Admin) PatchOne(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	disabledPatch, err := a.parser.ParsePatchOneBody(ctx, r)
+	patch, err := a.parser.ParsePatchOneBody(ctx, r)
 	if err != nil {
 		writeRsp(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
 		return
@@ -114,24 +153,85 @@ func (a *Admin) PatchOne(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	a.mtx.Lock()
-	f := flag[flagKey]
-	f.Disabled = disabledPatch.Disabled
-	a.mtx.Unlock()
+	patched, err := flags.ApplyPatch(flagKey, flag[flagKey], patch)
+	if err != nil && errors.Is(err, flags.ErrPatchTestFailed) {
+		writeRsp(w, http.StatusConflict, map[string]any{"error": err.Error()})
+		return
+	} else if err != nil {
+		writeRsp(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
 
-	upserted, err := a.adminService.UpsertFlag(ctx, flagKey, flag)
+	if a.adminService.Proposes() {
+		a.propose(ctx, w, flagKey, patched)
+		return
+	}
+
+	upserted, err := a.adminService.UpsertFlag(ctx, flagKey, patched)
 	if err != nil {
 		writeRsp(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
 		return
 	}
 
+	if _, err := a.refresh(ctx); err != nil {
+		slog.WarnContext(ctx, "failed to refresh the cache after write", "flag", flagKey, "error", err)
+	}
+
 	writeRsp(w, http.StatusOK, upserted)
 }
 
-func NewAdminHandler(adminService *admin.Service) *Admin {
+func (a *Admin) Refresh(w http.ResponseWriter, r *http.Request) {
+	ctx := reqToCtx(r)
+
+	new, err := a.refresh(ctx)
+	if err != nil {
+		writeRsp(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+
+	writeRsp(w, http.StatusOK, map[string]any{"flags": len(new)})
+}
+
+func (a *Admin) LoadErrors(w http.ResponseWriter, r *http.Request) {
+	ctx := reqToCtx(r)
+
+	loadErrors := flags.InProject(flags.Project(ctx), a.cacheService.LoadErrors())
+
+	writeRsp(w, http.StatusOK, map[string]any{"errors": loadErrors})
+}
+
+// propose puts the change up for review so there is nothing to refresh yet
+func (a *Admin) propose(ctx context.Context, w http.ResponseWriter, flagKey string, flag map[string]*flags.Flag) {
+	proposal, err := a.adminService.ProposeFlag(ctx, flagKey, flag)
+	if err != nil {
+		writeRsp(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+
+	writeRsp(w, http.StatusAccepted, proposal)
+}
+
+// refresh reloads this instance's cache and tells the others to do the same
+func (a *Admin) refresh(ctx context.Context) (map[string]*flags.Flag, error) {
+	old, new, err := a.cacheService.RetrieveFlags()
+	if err == nil {
+		a.notifyService.Notify(old, new)
+		a.notifyService.NotifyLoadErrors(a.cacheService.LoadErrors())
+	}
+
+	// the others may still be able to load what we could not
+	if err := a.adminService.Broadcast(ctx); err != nil {
+		slog.WarnContext(ctx, "failed to broadcast cache invalidation", "error", err)
+	}
+
+	return new, err
+}
+
+func NewAdminHandler(adminService *admin.Service, cacheService *cache.Service, notifyService *notify.Service) *Admin {
 	return &Admin{
-		adminService: adminService,
-		parser:       &Parser{},
-		mtx:          sync.RWMutex{},
+		adminService:  adminService,
+		cacheService:  cacheService,
+		notifyService: notifyService,
+		parser:        &Parser{},
 	}
 }
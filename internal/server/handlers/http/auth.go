@@ -4,20 +4,28 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator"
+	"github.com/w-h-a/flags/internal/server/clients/writer"
 	"github.com/w-h-a/flags/internal/server/config"
 	httpserver "github.com/w-h-a/pkg/serverv2/http"
 )
 
 const (
 	BearerScheme = "Bearer "
+	AdminPrefix  = "/admin/"
+	ValidatePath = "/admin/v1/flags/validate"
 )
 
 type AuthMiddleware struct {
-	handler http.Handler
+	handler    http.Handler
+	authClient authenticator.Authenticator
 }
 
 func (m *AuthMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path == "/status" {
+	env, project, path := splitPath(r.URL.Path)
+
+	if path == "/status" {
 		m.handler.ServeHTTP(w, r)
 		return
 	}
@@ -35,16 +43,90 @@ func (m *AuthMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		token = authHeader[len(BearerScheme):]
 	}
 
-	if !config.CheckAPIKey(token) {
+	if strings.HasPrefix(path, AdminPrefix) {
+		m.serveAdmin(w, r, token, env, project, path)
+		return
+	}
+
+	scope, ok := config.APIKeyEnvironment(token)
+	if !ok {
 		writeRsp(w, http.StatusUnauthorized, errBody)
 		return
 	}
 
-	m.handler.ServeHTTP(w, r)
+	projectScope, _ := config.APIKeyProject(token)
+
+	principal := &authenticator.Principal{
+		Environment: scope,
+		Project:     projectScope,
+	}
+
+	m.serveIn(w, r, principal, env, project)
+}
+
+func (m *AuthMiddleware) serveAdmin(w http.ResponseWriter, r *http.Request, token, env, project, path string) {
+	principal, err := m.authClient.Authenticate(r.Context(), token)
+	if err != nil {
+		writeRsp(w, http.StatusUnauthorized, map[string]any{"error": "not authenticated"})
+		return
+	}
+
+	if !principal.HasRole(requiredRole(r.Method, path)) {
+		writeRsp(w, http.StatusForbidden, map[string]any{"error": "not authorized"})
+		return
+	}
+
+	ctx := writer.ContextWithActor(r.Context(), principal.Subject)
+
+	ctx = contextWithPrincipal(ctx, principal)
+
+	m.serveIn(w, r.WithContext(ctx), principal, env, project)
+}
+
+// serveIn keeps principals to their environment and project
+// and picks theirs when the path does not name one
+func (m *AuthMiddleware) serveIn(w http.ResponseWriter, r *http.Request, principal *authenticator.Principal, env, project string) {
+	if len(env) > 0 && !principal.InEnvironment(env) || len(project) > 0 && !principal.InProject(project) {
+		writeRsp(w, http.StatusForbidden, map[string]any{"error": "not authorized"})
+		return
+	}
+
+	ctx := r.Context()
+
+	if len(env) == 0 && len(principal.Environment) > 0 {
+		ctx = ContextWithEnvironment(ctx, principal.Environment)
+	}
+
+	if len(project) == 0 {
+		project = principal.Project
+	}
+
+	if len(project) > 0 {
+		ctx = flags.ContextWithProject(ctx, project)
+	}
+
+	m.handler.ServeHTTP(w, r.WithContext(ctx))
 }
 
-func NewAuthMiddleware() httpserver.Middleware {
+func requiredRole(method, path string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return authenticator.RoleRead
+	}
+
+	// dry runs do not change anything
+	if method == http.MethodPost && path == ValidatePath {
+		return authenticator.RoleRead
+	}
+
+	return authenticator.RoleWrite
+}
+
+func NewAuthMiddleware(authClient authenticator.Authenticator) httpserver.Middleware {
 	return func(h http.Handler) http.Handler {
-		return &AuthMiddleware{h}
+		return &AuthMiddleware{
+			handler:    h,
+			authClient: authClient,
+		}
 	}
 }
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/w-h-a/flags/internal/server/clients/authenticator"
+	"github.com/w-h-a/flags/internal/server/config"
+)
+
+const (
+	EnvironmentPrefix = "/env/"
+	ProjectPrefix     = "/projects/"
+)
+
+type environmentKey struct{}
+
+type principalKey struct{}
+
+func ContextWithEnvironment(ctx context.Context, env string) context.Context {
+	return context.WithValue(ctx, environmentKey{}, env)
+}
+
+func EnvironmentFromContext(ctx context.Context) (string, bool) {
+	env, ok := ctx.Value(environmentKey{}).(string)
+	return env, ok && len(env) > 0
+}
+
+func contextWithPrincipal(ctx context.Context, principal *authenticator.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func principalFromContext(ctx context.Context) *authenticator.Principal {
+	principal, _ := ctx.Value(principalKey{}).(*authenticator.Principal)
+	return principal
+}
+
+// splitPath takes the environment and the project
+// off /env/{env}/projects/{project}/... paths
+func splitPath(path string) (string, string, string) {
+	env, path := cutSegment(path, EnvironmentPrefix)
+	project, path := cutSegment(path, ProjectPrefix)
+
+	return env, project, path
+}
+
+func cutSegment(path, prefix string) (string, string) {
+	rest, ok := strings.CutPrefix(path, prefix)
+	if !ok {
+		return "", path
+	}
+
+	segment, rest, _ := strings.Cut(rest, "/")
+
+	return segment, "/" + rest
+}
+
+// Environments sends each request to the routes of its environment.
+// The environment comes from the path, then from the api key and
+// otherwise falls back to the default one. Projects were already
+// put in the context by the auth middleware.
+type Environments struct {
+	fallback string
+	handlers map[string]http.Handler
+}
+
+func (e *Environments) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	env, project, path := splitPath(r.URL.Path)
+
+	if len(project) > 0 && !config.HasProject(project) {
+		writeRsp(w, http.StatusNotFound, map[string]any{"error": fmt.Sprintf("unknown project %q", project)})
+		return
+	}
+
+	if path != r.URL.Path {
+		r = r.Clone(r.Context())
+		r.URL.Path = path
+		r.URL.RawPath = ""
+	}
+
+	if len(env) == 0 {
+		env = e.fallback
+
+		if scoped, ok := EnvironmentFromContext(r.Context()); ok {
+			env = scoped
+		}
+	}
+
+	handler, ok := e.handlers[env]
+	if !ok {
+		writeRsp(w, http.StatusNotFound, map[string]any{"error": fmt.Sprintf("unknown environment %q", env)})
+		return
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+func NewEnvironmentsHandler(fallback string, handlers map[string]http.Handler) *Environments {
+	return &Environments{
+		fallback: fallback,
+		handlers: handlers,
+	}
+}
@@ -12,6 +12,7 @@ import (
 )
 
 type OFREP struct {
+	env           string
 	cacheService  *cache.Service
 	exportService *export.Service
 	parser        *Parser
@@ -36,6 +37,9 @@ func (o *OFREP) PostOne(w http.ResponseWriter, r *http.Request) {
 	if err != nil && errors.Is(err, flags.ErrNotFound) {
 		writeRsp(w, http.StatusNotFound, flagState)
 		return
+	} else if err != nil && errors.Is(err, flags.ErrParse) {
+		writeRsp(w, http.StatusBadRequest, flagState)
+		return
 	} else if err != nil {
 		writeRsp(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
 		return
@@ -44,6 +48,8 @@ func (o *OFREP) PostOne(w http.ResponseWriter, r *http.Request) {
 	if config.ExportReports() {
 		event := export.Event{
 			CreationDate: time.Now().Unix(),
+			Environment:  o.env,
+			Project:      flags.Project(ctx),
 			Key:          flagState.Key,
 			Value:        flagState.Value,
 			Variant:      flagState.Variant,
@@ -67,10 +73,12 @@ func (o *OFREP) PostAll(w http.ResponseWriter, r *http.Request) {
 }
 
 func NewOFREPHandler(
+	env string,
 	cacheService *cache.Service,
 	exportService *export.Service,
 ) *OFREP {
 	return &OFREP{
+		env:           env,
 		cacheService:  cacheService,
 		exportService: exportService,
 		parser:        &Parser{},
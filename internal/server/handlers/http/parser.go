@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/w-h-a/flags/internal/flags"
@@ -62,7 +64,28 @@ func (p *Parser) ParsePutOneBody(ctx context.Context, r *http.Request) (map[stri
 	return flags.Factory(bs, "json")
 }
 
-func (p *Parser) ParsePatchOneBody(ctx context.Context, r *http.Request) (*flags.DisabledPatch, error) {
+func (p *Parser) ParseValidateBody(ctx context.Context, r *http.Request) ([]byte, string, error) {
+	bs, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	defer r.Body.Close()
+
+	format := "json"
+
+	if strings.Contains(strings.ToLower(r.Header.Get("content-type")), "yaml") {
+		format = "yaml"
+	}
+
+	return bs, format, nil
+}
+
+func (p *Parser) ParseDiffQuery(ctx context.Context, r *http.Request) bool {
+	return r.URL.Query().Get("diff") == "true"
+}
+
+func (p *Parser) ParsePatchOneBody(ctx context.Context, r *http.Request) (flags.Patch, error) {
 	bs, err := io.ReadAll(r.Body)
 	if err != nil {
 		return nil, err
@@ -70,6 +93,15 @@ func (p *Parser) ParsePatchOneBody(ctx context.Context, r *http.Request) (*flags
 
 	defer r.Body.Close()
 
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("content-type"))
+
+	switch mediaType {
+	case "application/json-patch+json":
+		return flags.NewJSONPatch(bs)
+	case "application/merge-patch+json":
+		return flags.NewMergePatch(bs)
+	}
+
 	var disabledPatch *flags.DisabledPatch
 
 	if err := json.Unmarshal(bs, &disabledPatch); err != nil {
@@ -82,3 +114,28 @@ func (p *Parser) ParsePatchOneBody(ctx context.Context, r *http.Request) (*flags
 
 	return disabledPatch, nil
 }
+
+type PromoteRequest struct {
+	To string `json:"to"`
+}
+
+func (p *Parser) ParsePromoteBody(ctx context.Context, r *http.Request) (string, error) {
+	bs, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+
+	defer r.Body.Close()
+
+	var req PromoteRequest
+
+	if err := json.Unmarshal(bs, &req); err != nil {
+		return "", err
+	}
+
+	if len(req.To) == 0 {
+		return "", fmt.Errorf("body missing `to` environment")
+	}
+
+	return req.To, nil
+}
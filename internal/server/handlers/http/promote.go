@@ -0,0 +1,86 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/w-h-a/flags/internal/flags"
+)
+
+// Promote copies a flag from one environment into another
+type Promote struct {
+	source       *Admin
+	environments map[string]*Admin
+	parser       *Parser
+}
+
+func (p *Promote) PostOne(w http.ResponseWriter, r *http.Request) {
+	ctx := reqToCtx(r)
+
+	flagKey, err := p.parser.ParseFlagKey(ctx, r)
+	if err != nil {
+		writeRsp(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+
+	to, err := p.parser.ParsePromoteBody(ctx, r)
+	if err != nil {
+		writeRsp(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+
+	target, ok := p.environments[to]
+	if !ok {
+		writeRsp(w, http.StatusBadRequest, map[string]any{"error": fmt.Sprintf("unknown environment %q", to)})
+		return
+	}
+
+	if target == p.source {
+		writeRsp(w, http.StatusBadRequest, map[string]any{"error": "cannot promote a flag to its own environment"})
+		return
+	}
+
+	// the caller has to be allowed to write to both sides
+	if !principalFromContext(ctx).InEnvironment(to) {
+		writeRsp(w, http.StatusForbidden, map[string]any{"error": "not authorized"})
+		return
+	}
+
+	flag, err := p.source.adminService.RetrieveFlag(ctx, flagKey)
+	if err != nil && errors.Is(err, flags.ErrNotFound) {
+		writeRsp(w, http.StatusNotFound, map[string]any{"error": err.Error()})
+		return
+	} else if err != nil {
+		writeRsp(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+
+	if target.adminService.Proposes() {
+		target.propose(ctx, w, flagKey, flag)
+		return
+	}
+
+	upserted, err := target.adminService.UpsertFlag(ctx, flagKey, flag)
+	if err != nil {
+		writeRsp(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+
+	if _, err := target.refresh(ctx); err != nil {
+		slog.WarnContext(ctx, "failed to refresh the cache after promotion", "flag", flagKey, "to", to, "error", err)
+	}
+
+	writeRsp(w, http.StatusOK, upserted)
+}
+
+// NewPromoteHandler takes the admin handlers of every environment by
+// reference since they are filled in as the environments are set up
+func NewPromoteHandler(source *Admin, environments map[string]*Admin) *Promote {
+	return &Promote{
+		source:       source,
+		environments: environments,
+		parser:       &Parser{},
+	}
+}
@@ -8,22 +8,34 @@ import (
 )
 
 type Status struct {
+	env          string
 	cacheService *cache.Service
 }
 
 func (s *Status) GetStatus(w http.ResponseWriter, r *http.Request) {
 	status := map[string]any{
-		"env":        config.Env(),
+		"env":        s.env,
 		"name":       config.Name(),
 		"version":    config.Version(),
 		"lastUpdate": s.cacheService.LastUpdate(),
+		"degraded":   false,
+	}
+
+	if loadErrors := s.cacheService.LoadErrors(); len(loadErrors) > 0 {
+		status["loadErrors"] = loadErrors
+	}
+
+	if err := s.cacheService.Degraded(); err != nil {
+		status["degraded"] = true
+		status["degradedReason"] = err.Error()
 	}
 
 	writeRsp(w, http.StatusOK, status)
 }
 
-func NewStatusHandler(cacheService *cache.Service) *Status {
+func NewStatusHandler(env string, cacheService *cache.Service) *Status {
 	return &Status{
+		env:          env,
 		cacheService: cacheService,
 	}
 }
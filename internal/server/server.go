@@ -2,11 +2,14 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator"
+	"github.com/w-h-a/flags/internal/server/clients/broadcaster"
 	"github.com/w-h-a/flags/internal/server/clients/exporter"
 	"github.com/w-h-a/flags/internal/server/clients/notifier"
 	"github.com/w-h-a/flags/internal/server/clients/reader"
@@ -23,54 +26,127 @@ import (
 	"go.opentelemetry.io/otel"
 )
 
+// Environment is one set of flags served next to the others
+type Environment struct {
+	Name        string
+	WriteClient writer.Writer
+	ReadClient  reader.Reader
+}
+
 func Factory(
 	writeClient writer.Writer,
 	readClient reader.Reader,
 	exportClient exporter.Exporter,
 	notifyClient notifier.Notifier,
+	authClient authenticator.Authenticator,
+	broadcastClient broadcaster.Broadcaster,
 ) (serverv2.Server, *cache.Service, *export.Service, *notify.Service, error) {
-	// services
-	adminService := admin.New(writeClient, readClient)
-	cacheService := cache.New(readClient)
-	exportService := export.New(exportClient)
-	notifyService := notify.New(notifyClient)
+	env := config.Env()
 
-	old, new, err := cacheService.RetrieveFlags()
+	httpServer, cacheServices, exportService, notifyServices, err := EnvironmentsFactory(
+		[]Environment{{Name: env, WriteClient: writeClient, ReadClient: readClient}},
+		exportClient,
+		notifyClient,
+		authClient,
+		broadcastClient,
+	)
 	if err != nil {
 		return nil, nil, nil, nil, err
 	}
 
-	notifyService.Notify(old, new)
+	return httpServer, cacheServices[env], exportService, notifyServices[env], nil
+}
 
-	// base server options
-	opts := []serverv2.ServerOption{
-		serverv2.ServerWithNamespace(config.Env()),
-		serverv2.ServerWithName(config.Name()),
-		serverv2.ServerWithVersion(config.Version()),
+// EnvironmentsFactory serves every environment from one server. Each gets
+// its own services while the export, notify, auth and broadcast clients
+// are shared. The cache and notify services are returned by environment.
+func EnvironmentsFactory(
+	environments []Environment,
+	exportClient exporter.Exporter,
+	notifyClient notifier.Notifier,
+	authClient authenticator.Authenticator,
+	broadcastClient broadcaster.Broadcaster,
+) (serverv2.Server, map[string]*cache.Service, *export.Service, map[string]*notify.Service, error) {
+	if len(environments) == 0 {
+		return nil, nil, nil, nil, fmt.Errorf("no environments to serve")
 	}
 
-	// create http server
-	router := mux.NewRouter()
+	// services
+	exportService := export.New(exportClient)
+	cacheServices := map[string]*cache.Service{}
+	notifyServices := map[string]*notify.Service{}
 
-	httpAdmin := httphandlers.NewAdminHandler(adminService)
+	// requests that do not name an environment go to this one
+	fallback := environments[0].Name
 
-	router.Methods(http.MethodGet).Path("/admin/v1/flags/{key}").HandlerFunc(httpAdmin.GetOne)
-	router.Methods(http.MethodGet).Path("/admin/v1/flags").HandlerFunc(httpAdmin.GetAll)
-	router.Methods(http.MethodPut).Path("/admin/v1/flags").HandlerFunc(httpAdmin.PutOne)
-	router.Methods(http.MethodPatch).Path("/admin/v1/flags/{key}").HandlerFunc(httpAdmin.PatchOne)
+	// routes per environment
+	admins := map[string]*httphandlers.Admin{}
+	handlers := map[string]http.Handler{}
 
-	httpOFREP := httphandlers.NewOFREPHandler(cacheService, exportService)
+	for _, environment := range environments {
+		if _, ok := handlers[environment.Name]; ok {
+			return nil, nil, nil, nil, fmt.Errorf("environment %q is defined twice", environment.Name)
+		}
+
+		if environment.Name == config.Env() {
+			fallback = environment.Name
+		}
 
-	router.Methods(http.MethodPost).Path("/ofrep/v1/evaluate/flags/{key}").HandlerFunc(httpOFREP.PostOne)
-	router.Methods(http.MethodPost).Path("/ofrep/v1/evaluate/flags").HandlerFunc(httpOFREP.PostAll)
+		adminService := admin.New(environment.WriteClient, environment.ReadClient, broadcastClient)
+		cacheService := cache.NewForEnvironment(environment.Name, environment.ReadClient)
+		notifyService := notify.New(notifyClient)
+
+		old, new, err := cacheService.RetrieveFlags()
+		if err != nil {
+			slog.ErrorContext(context.Background(), "failed to load flags", "env", environment.Name, "error", err)
+			return nil, nil, nil, nil, err
+		}
 
-	httpStatus := httphandlers.NewStatusHandler(cacheService)
+		notifyService.Notify(old, new)
+		notifyService.NotifyLoadErrors(cacheService.LoadErrors())
 
-	router.Methods(http.MethodGet).Path("/status").HandlerFunc(httpStatus.GetStatus)
+		router := mux.NewRouter()
 
+		httpAdmin := httphandlers.NewAdminHandler(adminService, cacheService, notifyService)
+
+		router.Methods(http.MethodPost).Path(httphandlers.ValidatePath).HandlerFunc(httpAdmin.Validate)
+		router.Methods(http.MethodGet).Path("/admin/v1/flags/{key}").HandlerFunc(httpAdmin.GetOne)
+		router.Methods(http.MethodGet).Path("/admin/v1/flags").HandlerFunc(httpAdmin.GetAll)
+		router.Methods(http.MethodPut).Path("/admin/v1/flags").HandlerFunc(httpAdmin.PutOne)
+		router.Methods(http.MethodPatch).Path("/admin/v1/flags/{key}").HandlerFunc(httpAdmin.PatchOne)
+		router.Methods(http.MethodPost).Path("/admin/v1/refresh").HandlerFunc(httpAdmin.Refresh)
+		router.Methods(http.MethodGet).Path("/admin/v1/load-errors").HandlerFunc(httpAdmin.LoadErrors)
+
+		httpPromote := httphandlers.NewPromoteHandler(httpAdmin, admins)
+
+		router.Methods(http.MethodPost).Path("/admin/v1/flags/{key}/promote").HandlerFunc(httpPromote.PostOne)
+
+		httpOFREP := httphandlers.NewOFREPHandler(environment.Name, cacheService, exportService)
+
+		router.Methods(http.MethodPost).Path("/ofrep/v1/evaluate/flags/{key}").HandlerFunc(httpOFREP.PostOne)
+		router.Methods(http.MethodPost).Path("/ofrep/v1/evaluate/flags").HandlerFunc(httpOFREP.PostAll)
+
+		httpStatus := httphandlers.NewStatusHandler(environment.Name, cacheService)
+
+		router.Methods(http.MethodGet).Path("/status").HandlerFunc(httpStatus.GetStatus)
+
+		admins[environment.Name] = httpAdmin
+		handlers[environment.Name] = router
+		cacheServices[environment.Name] = cacheService
+		notifyServices[environment.Name] = notifyService
+	}
+
+	// base server options
+	opts := []serverv2.ServerOption{
+		serverv2.ServerWithNamespace(config.Env()),
+		serverv2.ServerWithName(config.Name()),
+		serverv2.ServerWithVersion(config.Version()),
+	}
+
+	// create http server
 	httpOpts := []serverv2.ServerOption{
 		serverv2.ServerWithAddress(config.HttpAddress()),
-		httpserver.HttpServerWithMiddleware(httphandlers.NewAuthMiddleware()),
+		httpserver.HttpServerWithMiddleware(httphandlers.NewAuthMiddleware(authClient)),
 	}
 
 	httpOpts = append(httpOpts, opts...)
@@ -78,7 +154,7 @@ func Factory(
 	httpServer := httpserver.NewServer(httpOpts...)
 
 	handler := otelhttp.NewHandler(
-		router,
+		httphandlers.NewEnvironmentsHandler(fallback, handlers),
 		"",
 		otelhttp.WithSpanNameFormatter(func(operation string, r *http.Request) string { return r.URL.Path }),
 		otelhttp.WithTracerProvider(otel.GetTracerProvider()),
@@ -88,26 +164,77 @@ func Factory(
 
 	httpServer.Handle(handler)
 
-	return httpServer, cacheService, exportService, notifyService, nil
+	return httpServer, cacheServices, exportService, notifyServices, nil
 }
 
+const (
+	minRewatch = time.Second
+	maxRewatch = time.Minute
+)
+
 func UpdateCache(
 	cacheService *cache.Service,
 	notifyService *notify.Service,
 	stop chan struct{},
 	dur time.Duration,
 ) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	update := func() {
+		old, new, err := cacheService.RetrieveFlags()
+		if err != nil {
+			slog.WarnContext(ctx, "failed to update the cache", "error", err)
+			return
+		}
+
+		notifyService.Notify(old, new)
+		notifyService.NotifyLoadErrors(cacheService.LoadErrors())
+	}
+
+	// readers that can push changes refresh the cache right away
+	// while polling stays on as a safety net
+	var rewatch <-chan time.Time
+
+	backoff := minRewatch
+
+	changes, err := cacheService.Watch(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to watch for flag changes", "error", err, "retry", backoff)
+		rewatch = time.After(backoff)
+	}
+
 	ticker := time.NewTicker(dur)
 
 	for {
 		select {
 		case <-ticker.C:
-			old, new, err := cacheService.RetrieveFlags()
+			update()
+		case _, ok := <-changes:
+			if !ok {
+				slog.WarnContext(ctx, "stopped watching for flag changes", "retry", backoff)
+				changes = nil
+				rewatch = time.After(backoff)
+				continue
+			}
+
+			backoff = minRewatch
+
+			update()
+		case <-rewatch:
+			changes, err = cacheService.Watch(ctx)
+			backoff = min(backoff*2, maxRewatch)
+
 			if err != nil {
-				slog.WarnContext(context.TODO(), "failed to update the cache", "error", err)
+				slog.WarnContext(ctx, "failed to watch for flag changes", "error", err, "retry", backoff)
+				rewatch = time.After(backoff)
+				continue
 			}
 
-			notifyService.Notify(old, new)
+			rewatch = nil
+
+			// changes may have been missed while we were not watching
+			update()
 		case <-stop:
 			ticker.Stop()
 			notifyService.Close()
@@ -116,6 +243,46 @@ func UpdateCache(
 	}
 }
 
+func ListenForInvalidations(
+	cacheService *cache.Service,
+	notifyService *notify.Service,
+	broadcastClient broadcaster.Broadcaster,
+	stop chan struct{},
+) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	invalidations, err := broadcastClient.Listen(ctx)
+	if err != nil {
+		// fall back to the regular cache updates
+		slog.WarnContext(ctx, "failed to listen for cache invalidations", "error", err)
+		invalidations = nil
+	}
+
+	for {
+		select {
+		case _, ok := <-invalidations:
+			if !ok {
+				// fall back to the regular cache updates
+				slog.WarnContext(ctx, "stopped listening for cache invalidations")
+				invalidations = nil
+				continue
+			}
+
+			old, new, err := cacheService.RetrieveFlags()
+			if err != nil {
+				slog.WarnContext(ctx, "failed to update the cache after invalidation", "error", err)
+				continue
+			}
+
+			notifyService.Notify(old, new)
+			notifyService.NotifyLoadErrors(cacheService.LoadErrors())
+		case <-stop:
+			return nil
+		}
+	}
+}
+
 func ExportReports(
 	exportService *export.Service,
 	stop chan struct{},
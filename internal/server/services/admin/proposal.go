@@ -0,0 +1,9 @@
+package admin
+
+import "github.com/w-h-a/flags/internal/flags"
+
+// Proposal is a change that is not served until it is reviewed and merged
+type Proposal struct {
+	URL   string                 `json:"url"`
+	Flags map[string]*flags.Flag `json:"flags"`
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
 	"strings"
 
 	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server/clients/broadcaster"
 	"github.com/w-h-a/flags/internal/server/clients/reader"
 	"github.com/w-h-a/flags/internal/server/clients/writer"
 	"github.com/w-h-a/flags/internal/server/config"
@@ -14,19 +17,27 @@ import (
 )
 
 type Service struct {
-	writeClient writer.Writer
-	readClient  reader.Reader
+	writeClient     writer.Writer
+	readClient      reader.Reader
+	broadcastClient broadcaster.Broadcaster
 }
 
 func (s *Service) RetrieveFlag(ctx context.Context, key string) (map[string]*flags.Flag, error) {
-	bs, err := s.readClient.ReadByKey(ctx, key)
+	project := flags.Project(ctx)
+
+	bs, err := s.readClient.ReadByKey(ctx, flags.QualifyKey(project, key))
 	if err != nil && errors.Is(err, reader.ErrRecordNotFound) {
 		return nil, flags.ErrNotFound
 	} else if err != nil {
 		return nil, err
 	}
 
-	return flags.Factory(bs, config.FlagFormat())
+	flag, err := flags.Factory(bs, config.FlagFormat())
+	if err != nil {
+		return nil, err
+	}
+
+	return flags.InProject(project, flag), nil
 }
 
 func (s *Service) RetrieveFlags(ctx context.Context) (map[string]*flags.Flag, error) {
@@ -35,34 +46,144 @@ func (s *Service) RetrieveFlags(ctx context.Context) (map[string]*flags.Flag, er
 		return nil, err
 	}
 
-	return flags.Factory(bs, config.FlagFormat())
+	var all map[string]*flags.Flag
+
+	// the flags that failed to load are reported on their own
+	if config.LenientLoading() {
+		all, _, err = flags.Partition(bs, config.FlagFormat())
+	} else {
+		all, err = flags.Factory(bs, config.FlagFormat())
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return flags.InProject(flags.Project(ctx), all), nil
 }
 
-func (s *Service) UpsertFlag(ctx context.Context, key string, flag map[string]*flags.Flag) (map[string]*flags.Flag, error) {
-	var bs []byte
-	var err error
+func (s *Service) ValidateFlags(ctx context.Context, bs []byte, format string, withDiff bool) (*Validation, error) {
+	candidates, errs := flags.Validate(bs, format)
 
-	switch strings.ToLower(config.FlagFormat()) {
-	case "json":
-		bs, err = json.Marshal(flag)
-	default:
-		bs, err = yaml.Marshal(flag)
+	validation := &Validation{
+		Valid:  len(errs) == 0,
+		Errors: errs,
 	}
 
+	if !validation.Valid || !withDiff {
+		return validation, nil
+	}
+
+	stored, err := s.RetrieveFlags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// only compare against what the candidate document touches
+	old := map[string]*flags.Flag{}
+
+	for k := range candidates {
+		if f, ok := stored[k]; ok {
+			old[k] = f
+		}
+	}
+
+	diff := flags.NewDiff(old, candidates)
+
+	validation.Diff = &diff
+
+	return validation, nil
+}
+
+func (s *Service) UpsertFlag(ctx context.Context, key string, flag map[string]*flags.Flag) (map[string]*flags.Flag, error) {
+	project := flags.Project(ctx)
+
+	bs, err := encode(flags.Qualify(project, flag))
 	if err != nil {
 		return nil, err
 	}
 
+	key = flags.QualifyKey(project, key)
+
 	if err := s.writeClient.Write(ctx, key, bs); err != nil {
 		return nil, err
 	}
 
+	actor, _ := writer.Actor(ctx)
+
+	slog.InfoContext(ctx, "flag upserted", "flag", key, "actor", actor)
+
 	return flag, nil
 }
 
-func New(writeClient writer.Writer, readClient reader.Reader) *Service {
+// Proposes reports whether writes go up for review instead of being applied
+func (s *Service) Proposes() bool {
+	_, ok := s.writeClient.(writer.Proposer)
+	return ok
+}
+
+func (s *Service) ProposeFlag(ctx context.Context, key string, flag map[string]*flags.Flag) (*Proposal, error) {
+	proposer, ok := s.writeClient.(writer.Proposer)
+	if !ok {
+		return nil, fmt.Errorf("write client does not support proposals")
+	}
+
+	project := flags.Project(ctx)
+
+	bs, err := encode(flags.Qualify(project, flag))
+	if err != nil {
+		return nil, err
+	}
+
+	key = flags.QualifyKey(project, key)
+
+	url, err := proposer.Propose(ctx, key, bs)
+	if err != nil {
+		return nil, err
+	}
+
+	actor, _ := writer.Actor(ctx)
+
+	slog.InfoContext(ctx, "flag change proposed", "flag", key, "actor", actor, "url", url)
+
+	return &Proposal{
+		URL:   url,
+		Flags: flag,
+	}, nil
+}
+
+// Broadcast tells the other instances to reload their caches
+func (s *Service) Broadcast(ctx context.Context) error {
+	return s.broadcastClient.Broadcast(ctx)
+}
+
+func encode(flag map[string]*flags.Flag) ([]byte, error) {
+	// sources are worked out when reading so they are never stored
+	stripped := map[string]*flags.Flag{}
+
+	for k, f := range flag {
+		if f != nil {
+			c := *f
+			c.Sources = nil
+			f = &c
+		}
+		stripped[k] = f
+	}
+
+	flag = stripped
+
+	switch strings.ToLower(config.FlagFormat()) {
+	case "json":
+		return json.Marshal(flag)
+	default:
+		return yaml.Marshal(flag)
+	}
+}
+
+func New(writeClient writer.Writer, readClient reader.Reader, broadcastClient broadcaster.Broadcaster) *Service {
 	return &Service{
-		writeClient: writeClient,
-		readClient:  readClient,
+		writeClient:     writeClient,
+		readClient:      readClient,
+		broadcastClient: broadcastClient,
 	}
 }
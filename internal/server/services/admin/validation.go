@@ -0,0 +1,9 @@
+package admin
+
+import "github.com/w-h-a/flags/internal/flags"
+
+type Validation struct {
+	Valid  bool                     `json:"valid"`
+	Errors []*flags.ValidationError `json:"errors"`
+	Diff   *flags.Diff              `json:"diff,omitempty"`
+}
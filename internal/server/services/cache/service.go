@@ -1,7 +1,10 @@
 package cache
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"maps"
 	"sort"
@@ -15,19 +18,36 @@ import (
 
 type Service struct {
 	readClient reader.Reader
+	// the environment whose overrides are applied
+	env        string
 	store      map[string]*flags.Flag
+	loadErrors map[string][]*flags.ValidationError
+	// the document the store was loaded from
+	hash *[sha256.Size]byte
+	// where incremental readers left off
+	cursor     string
 	lastUpdate time.Time
 	mtx        sync.RWMutex
+	// keeps a slow read from replacing a newer one
+	refreshMtx sync.Mutex
 }
 
 func (s *Service) EvaluateFlag(ctx context.Context, flagKey string, evalCtx map[string]any) (FlagState, error) {
 	var flag *flags.Flag
 	var ok bool
 
+	// callers only see the flags of their project
+	key := flags.QualifyKey(flags.Project(ctx), flagKey)
+
 	s.mtx.RLock()
-	flag, ok = s.store[flagKey]
+	flag, ok = s.store[key]
+	loadErrs := s.loadErrors[key]
 	s.mtx.RUnlock()
 
+	if !ok && len(loadErrs) > 0 {
+		return parseErrorState(flagKey, loadErrs), flags.ErrParse
+	}
+
 	if !ok {
 		result := FlagState{
 			Key:          flagKey,
@@ -51,14 +71,28 @@ func (s *Service) EvaluateFlag(ctx context.Context, flagKey string, evalCtx map[
 }
 
 func (s *Service) EvaluateFlags(ctx context.Context) AllFlags {
-	flags := map[string]*flags.Flag{}
+	project := flags.Project(ctx)
+
+	loadErrs := flags.InProject(project, s.LoadErrors())
+
+	store := map[string]*flags.Flag{}
 
 	s.mtx.RLock()
-	maps.Copy(flags, s.store)
+	maps.Copy(store, s.store)
 	s.mtx.RUnlock()
 
+	flags := flags.InProject(project, store)
+
 	allFlags := NewAllFlags()
 
+	for k, errs := range loadErrs {
+		if _, ok := flags[k]; ok || len(errs) == 0 {
+			continue
+		}
+
+		allFlags.AddFlag(parseErrorState(k, errs))
+	}
+
 	for k, flag := range flags {
 		flagValue, resolutionDetails := flag.Evaluate(map[string]any{})
 
@@ -77,13 +111,71 @@ func (s *Service) EvaluateFlags(ctx context.Context) AllFlags {
 	return allFlags
 }
 
+func parseErrorState(flagKey string, errs []*flags.ValidationError) FlagState {
+	return FlagState{
+		Key:          flagKey,
+		ErrorCode:    flags.ErrorParse,
+		ErrorMessage: fmt.Sprintf("flag for key '%s' could not be parsed: %s", flagKey, errs[0].Message),
+	}
+}
+
 func (s *Service) RetrieveFlags() (map[string]*flags.Flag, map[string]*flags.Flag, error) {
-	bs, err := s.readClient.Read(context.TODO())
+	s.refreshMtx.Lock()
+	defer s.refreshMtx.Unlock()
+
+	if incremental, ok := s.readClient.(reader.IncrementalReader); ok {
+		return s.retrieveChanges(context.TODO(), incremental)
+	}
+
+	bs, err := s.read(context.TODO())
+	if err != nil && errors.Is(err, reader.ErrNotModified) {
+		return s.unchanged()
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	old, new, err := s.load(bs)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	new, err := flags.Factory(bs, config.FlagFormat())
+	s.ack(context.TODO())
+
+	return old, new, nil
+}
+
+// load replaces every flag unless the document is the one we loaded last
+func (s *Service) load(bs []byte) (map[string]*flags.Flag, map[string]*flags.Flag, error) {
+	hash := sha256.Sum256(bs)
+
+	s.mtx.RLock()
+	same := s.hash != nil && *s.hash == hash
+	s.mtx.RUnlock()
+
+	if same {
+		return s.unchanged()
+	}
+
+	if !config.LenientLoading() {
+		new, err := flags.FactoryIn(bs, config.FlagFormat(), s.env)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var old map[string]*flags.Flag
+
+		s.mtx.Lock()
+		old = s.store
+		s.store = new
+		s.loadErrors = map[string][]*flags.ValidationError{}
+		s.hash = &hash
+		s.lastUpdate = time.Now()
+		s.mtx.Unlock()
+
+		return old, new, nil
+	}
+
+	new, loadErrors, err := flags.PartitionIn(bs, config.FlagFormat(), s.env)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -92,23 +184,181 @@ func (s *Service) RetrieveFlags() (map[string]*flags.Flag, map[string]*flags.Fla
 
 	s.mtx.Lock()
 	old = s.store
+
+	// an invalid flag keeps its last good definition
+	for k := range loadErrors {
+		if f, ok := old[k]; ok {
+			new[k] = f
+		}
+	}
+
 	s.store = new
+	s.loadErrors = loadErrors
+	s.hash = &hash
+	// keep reporting invalid flags until they are fixed
+	if len(loadErrors) > 0 {
+		s.hash = nil
+	}
 	s.lastUpdate = time.Now()
 	s.mtx.Unlock()
 
 	return old, new, nil
 }
 
+// retrieveChanges parses only the flags that changed and patches the store
+func (s *Service) retrieveChanges(ctx context.Context, incremental reader.IncrementalReader) (map[string]*flags.Flag, map[string]*flags.Flag, error) {
+	s.mtx.RLock()
+	cursor := s.cursor
+	s.mtx.RUnlock()
+
+	changes, err := incremental.ReadChanges(ctx, cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if changes.Full {
+		old, new, err := s.load(changes.Changed)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		s.mtx.Lock()
+		s.cursor = changes.Cursor
+		s.mtx.Unlock()
+
+		s.ack(ctx)
+
+		return old, new, nil
+	}
+
+	changed := map[string]*flags.Flag{}
+	invalid := map[string][]*flags.ValidationError{}
+
+	if len(bytes.TrimSpace(changes.Changed)) > 0 {
+		if !config.LenientLoading() {
+			changed, err = flags.FactoryIn(changes.Changed, config.FlagFormat(), s.env)
+		} else {
+			changed, invalid, err = flags.PartitionIn(changes.Changed, config.FlagFormat(), s.env)
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.cursor = changes.Cursor
+
+	old := s.store
+
+	if len(changed) == 0 && len(invalid) == 0 && len(changes.Removed) == 0 {
+		return old, old, nil
+	}
+
+	s.lastUpdate = time.Now()
+
+	new := maps.Clone(old)
+	loadErrors := maps.Clone(s.loadErrors)
+
+	for k, f := range changed {
+		new[k] = f
+		delete(loadErrors, k)
+	}
+
+	// an invalid flag keeps its last good definition
+	for k, errs := range invalid {
+		loadErrors[k] = errs
+	}
+
+	for _, k := range changes.Removed {
+		delete(new, k)
+		delete(loadErrors, k)
+	}
+
+	s.store = new
+	s.loadErrors = loadErrors
+	// the store no longer matches a whole document
+	s.hash = nil
+
+	return old, new, nil
+}
+
+func (s *Service) unchanged() (map[string]*flags.Flag, map[string]*flags.Flag, error) {
+	s.mtx.RLock()
+	current := s.store
+	s.mtx.RUnlock()
+
+	return current, current, nil
+}
+
+// LoadErrors reports the flags that failed to load with the last read
+func (s *Service) LoadErrors() map[string][]*flags.ValidationError {
+	loadErrors := map[string][]*flags.ValidationError{}
+
+	s.mtx.RLock()
+	maps.Copy(loadErrors, s.loadErrors)
+	s.mtx.RUnlock()
+
+	return loadErrors
+}
+
+// ack tells conditional readers the cache accepted what they served
+func (s *Service) ack(ctx context.Context) {
+	if conditional, ok := s.readClient.(reader.ConditionalReader); ok {
+		conditional.Ack(ctx)
+	}
+}
+
+func (s *Service) read(ctx context.Context) ([]byte, error) {
+	if conditional, ok := s.readClient.(reader.ConditionalReader); ok {
+		return conditional.ReadIfModified(ctx)
+	}
+
+	return s.readClient.Read(ctx)
+}
+
+// Watch passes on changes from readers that can push them. Other
+// readers get a nil channel so that polling is all that happens.
+func (s *Service) Watch(ctx context.Context) (<-chan struct{}, error) {
+	watcher, ok := s.readClient.(reader.Watcher)
+	if !ok {
+		return nil, nil
+	}
+
+	return watcher.Watch(ctx)
+}
+
+// Degraded reports why flags are served from a fallback
+// instead of the source, or nil when they are not
+func (s *Service) Degraded() error {
+	degradable, ok := s.readClient.(reader.Degradable)
+	if !ok {
+		return nil
+	}
+
+	return degradable.Degraded()
+}
+
 func (s *Service) LastUpdate() time.Time {
 	s.mtx.RLock()
 	defer s.mtx.RUnlock()
 	return s.lastUpdate
 }
 
+// New serves the flags as they are in the environment of this instance
 func New(readClient reader.Reader) *Service {
+	return NewForEnvironment(config.Env(), readClient)
+}
+
+func NewForEnvironment(env string, readClient reader.Reader) *Service {
 	return &Service{
 		readClient: readClient,
+		env:        env,
 		store:      map[string]*flags.Flag{},
+		loadErrors: map[string][]*flags.ValidationError{},
 		mtx:        sync.RWMutex{},
+		refreshMtx: sync.Mutex{},
 	}
 }
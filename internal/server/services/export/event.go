@@ -6,6 +6,8 @@ const (
 
 type Event struct {
 	CreationDate int64  `json:"creationDate"`
+	Environment  string `json:"environment,omitempty"`
+	Project      string `json:"project,omitempty"`
 	Key          string `json:"key"`
 	Value        any    `json:"value,omitempty"`
 	Variant      string `json:"variant,omitempty"`
@@ -38,6 +38,8 @@ func (s *Service) Flush() {
 	for _, event := range s.store {
 		record := exporter.Record{
 			CreationDate: event.CreationDate,
+			Environment:  event.Environment,
+			Project:      event.Project,
 			Key:          event.Key,
 			Value:        event.Value,
 			Variant:      event.Variant,
@@ -3,9 +3,9 @@ package notify
 import (
 	"context"
 	"log/slog"
+	"reflect"
 	"sync"
 
-	"github.com/google/go-cmp/cmp"
 	"github.com/w-h-a/flags/internal/flags"
 	"github.com/w-h-a/flags/internal/server/clients/notifier"
 )
@@ -13,15 +13,42 @@ import (
 type Service struct {
 	notifyClient notifier.Notifier
 	waitGroup    *sync.WaitGroup
+	// the load errors we already alerted on
+	loadErrors map[string][]*flags.ValidationError
+	mtx        sync.Mutex
 }
 
 func (s *Service) Notify(old, new map[string]*flags.Flag) {
-	diff := s.diff(old, new)
+	diff := flags.NewDiff(old, new)
 
 	if !diff.HasDiff() {
 		return
 	}
 
+	s.send(diff)
+}
+
+// NotifyLoadErrors alerts on flags that failed to load
+// but only when they differ from the last alert
+func (s *Service) NotifyLoadErrors(loadErrors map[string][]*flags.ValidationError) {
+	s.mtx.Lock()
+	unchanged := reflect.DeepEqual(s.loadErrors, loadErrors)
+	s.loadErrors = loadErrors
+	s.mtx.Unlock()
+
+	if unchanged || len(loadErrors) == 0 {
+		return
+	}
+
+	s.send(flags.Diff{
+		Deleted: map[string]*flags.Flag{},
+		Added:   map[string]*flags.Flag{},
+		Updated: map[string]flags.DiffUpdated{},
+		Failed:  loadErrors,
+	})
+}
+
+func (s *Service) send(diff flags.Diff) {
 	s.waitGroup.Add(1)
 
 	go func() {
@@ -38,46 +65,11 @@ func (s *Service) Close() {
 	s.waitGroup.Wait()
 }
 
-func (s *Service) diff(old, new map[string]*flags.Flag) flags.Diff {
-	diff := flags.Diff{
-		Deleted: map[string]*flags.Flag{},
-		Added:   map[string]*flags.Flag{},
-		Updated: map[string]flags.DiffUpdated{},
-	}
-
-	for k := range old {
-		nf, ok := new[k]
-		of := old[k]
-
-		// if it's not in new, it needs to be shown as deleted
-		if !ok {
-			diff.Deleted[k] = of
-			continue
-		}
-
-		// if it's not equal, it needs to be shown as updated
-		if !cmp.Equal(of, nf) {
-			diff.Updated[k] = flags.DiffUpdated{
-				Before: of,
-				After:  nf,
-			}
-		}
-	}
-
-	for k := range new {
-		// if not in old, it needs to be shown as added
-		if _, ok := old[k]; !ok {
-			f := new[k]
-			diff.Added[k] = f
-		}
-	}
-
-	return diff
-}
-
 func New(notifyClient notifier.Notifier) *Service {
 	return &Service{
 		notifyClient: notifyClient,
 		waitGroup:    &sync.WaitGroup{},
+		loadErrors:   map[string][]*flags.ValidationError{},
+		mtx:          sync.Mutex{},
 	}
 }
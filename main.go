@@ -67,6 +67,37 @@ func main() {
 						Usage:    "Provide the format of the flags (yaml or json)",
 						Required: true,
 					},
+					&cli.StringFlag{
+						Name:  "env",
+						Usage: "Provide to print the flags as they are in this environment",
+					},
+				},
+			},
+			{
+				Name:  "sign",
+				Usage: "sign flags so that a server with the public key will load them",
+				Action: func(ctx *cli.Context) error {
+					return cmd.Sign(ctx)
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "filePath",
+						Usage: "Provide the file path to the flags (a file or a directory)",
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Provide the format of the flags (yaml or json)",
+						Value: "yaml",
+					},
+					&cli.StringFlag{
+						Name:     "key",
+						Usage:    "Provide the file path to the ed25519 private key",
+						Required: true,
+					},
+					&cli.BoolFlag{
+						Name:  "generate",
+						Usage: "Provide to generate a new private key at the key path instead of signing",
+					},
 				},
 			},
 		},
@@ -0,0 +1,351 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	postgresreader "github.com/w-h-a/flags/internal/server/clients/reader/postgres"
+	"github.com/w-h-a/flags/internal/server/clients/writer"
+	postgreswriter "github.com/w-h-a/flags/internal/server/clients/writer/postgres"
+	"github.com/w-h-a/flags/tests/unit"
+)
+
+const (
+	format = "yaml"
+)
+
+func TestMain(m *testing.M) {
+	if len(os.Getenv("POSTGRES")) == 0 {
+		log.Print("SKIPPING POSTGRES TEST")
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestPostgres_JSONBMigration(t *testing.T) {
+	conn := reset(t)
+
+	// rows written before the migration
+	blobWriter := postgreswriter.NewWriter(
+		writer.WithLocation(os.Getenv("POSTGRES")),
+		writer.WithFormat(format),
+	)
+
+	for key, flag := range unit.DefaultFlags() {
+		require.NoError(t, blobWriter.Write(context.TODO(), key, encode(t, key, flag)))
+	}
+
+	// the first write of a jsonb writer migrates the table and backfills it
+	jsonbWriter := postgreswriter.NewWriter(
+		writer.WithLocation(os.Getenv("POSTGRES")),
+		writer.WithFormat(format),
+		postgreswriter.WithSchema(postgreswriter.SchemaJSONB),
+	)
+
+	require.NoError(t, jsonbWriter.Write(context.TODO(), "flag1", encode(t, "flag1", unit.DefaultFlags()["flag1"])))
+
+	rows, err := conn.Query(`SELECT key, definition FROM flags;`)
+	require.NoError(t, err)
+
+	defer rows.Close()
+
+	definitions := map[string]*flags.Flag{}
+
+	for rows.Next() {
+		var key string
+		var def []byte
+
+		require.NoError(t, rows.Scan(&key, &def))
+		require.NotNil(t, def)
+
+		flag := &flags.Flag{}
+		require.NoError(t, json.Unmarshal(def, flag))
+
+		definitions[key] = flag
+	}
+
+	require.NoError(t, rows.Err())
+	require.Equal(t, unit.DefaultFlags(), definitions)
+
+	// blob and jsonb readers both read the migrated table
+	for _, schema := range []string{postgresreader.SchemaBlob, postgresreader.SchemaJSONB} {
+		readClient := postgresreader.NewReader(
+			reader.WithLocation(os.Getenv("POSTGRES")),
+			reader.WithFormat(format),
+			postgresreader.WithSchema(schema),
+		)
+
+		require.Equal(t, want(t, unit.DefaultFlags()), read(t, readClient))
+	}
+}
+
+func TestPostgres_JSONBReaderFirst(t *testing.T) {
+	reset(t)
+
+	// the reader migrates the table when no writer has written yet
+	readClient := postgresreader.NewReader(
+		reader.WithLocation(os.Getenv("POSTGRES")),
+		reader.WithFormat(format),
+		postgresreader.WithSchema(postgresreader.SchemaJSONB),
+	)
+
+	require.Equal(t, want(t, map[string]*flags.Flag{}), read(t, readClient))
+
+	blobWriter := postgreswriter.NewWriter(
+		writer.WithLocation(os.Getenv("POSTGRES")),
+		writer.WithFormat(format),
+	)
+
+	for key, flag := range unit.DefaultFlags() {
+		require.NoError(t, blobWriter.Write(context.TODO(), key, encode(t, key, flag)))
+	}
+
+	require.Equal(t, want(t, unit.DefaultFlags()), read(t, readClient))
+}
+
+func TestPostgres_JSONBHandEdit(t *testing.T) {
+	conn := reset(t)
+
+	writeClient := postgreswriter.NewWriter(
+		writer.WithLocation(os.Getenv("POSTGRES")),
+		writer.WithFormat(format),
+		postgreswriter.WithSchema(postgreswriter.SchemaJSONB),
+	)
+
+	readClient := postgresreader.NewReader(
+		reader.WithLocation(os.Getenv("POSTGRES")),
+		reader.WithFormat(format),
+		postgresreader.WithSchema(postgresreader.SchemaJSONB),
+	)
+
+	for key, flag := range unit.DefaultFlags() {
+		require.NoError(t, writeClient.Write(context.TODO(), key, encode(t, key, flag)))
+	}
+
+	require.Equal(t, want(t, unit.DefaultFlags()), read(t, readClient))
+
+	// someone changes the value without touching the definition
+	edited := unit.DefaultFlags()
+	edited["flag1"].Variants["default"] = "C"
+
+	_, err := conn.Exec(`UPDATE flags SET value = $2 WHERE key = $1;`, "flag1", encode(t, "flag1", edited["flag1"]))
+	require.NoError(t, err)
+
+	var def []byte
+
+	require.NoError(t, conn.QueryRow(`SELECT definition FROM flags WHERE key = $1;`, "flag1").Scan(&def))
+	require.Nil(t, def)
+
+	require.Equal(t, want(t, edited), read(t, readClient))
+}
+
+func TestPostgres_JSONBUpdatedAt(t *testing.T) {
+	conn := reset(t)
+
+	writeClient := postgreswriter.NewWriter(
+		writer.WithLocation(os.Getenv("POSTGRES")),
+		writer.WithFormat(format),
+		postgreswriter.WithSchema(postgreswriter.SchemaJSONB),
+	)
+
+	readClient := postgresreader.NewReader(
+		reader.WithLocation(os.Getenv("POSTGRES")),
+		reader.WithFormat(format),
+		postgresreader.WithSchema(postgresreader.SchemaJSONB),
+	)
+
+	conditional, ok := readClient.(reader.ConditionalReader)
+	require.True(t, ok)
+
+	for key, flag := range unit.DefaultFlags() {
+		require.NoError(t, writeClient.Write(context.TODO(), key, encode(t, key, flag)))
+	}
+
+	bs, err := conditional.ReadIfModified(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, want(t, unit.DefaultFlags()), parse(t, bs))
+
+	conditional.Ack(context.TODO())
+
+	// nothing moved updated_at
+	_, err = conditional.ReadIfModified(context.TODO())
+	require.ErrorIs(t, err, reader.ErrNotModified)
+
+	// a write moves updated_at
+	updated := unit.DefaultFlags()
+	updated["flag2"].Disabled = unit.Bool(true)
+
+	require.NoError(t, writeClient.Write(context.TODO(), "flag2", encode(t, "flag2", updated["flag2"])))
+
+	bs, err = conditional.ReadIfModified(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, want(t, updated), parse(t, bs))
+
+	conditional.Ack(context.TODO())
+
+	// deletes do not move updated_at but are still picked up
+	_, err = conn.Exec(`DELETE FROM flags WHERE key = $1;`, "flag1")
+	require.NoError(t, err)
+
+	delete(updated, "flag1")
+
+	bs, err = conditional.ReadIfModified(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, want(t, updated), parse(t, bs))
+}
+
+func TestPostgres_JSONBDeleteAndInsert(t *testing.T) {
+	conn := reset(t)
+
+	writeClient := postgreswriter.NewWriter(
+		writer.WithLocation(os.Getenv("POSTGRES")),
+		writer.WithFormat(format),
+		postgreswriter.WithSchema(postgreswriter.SchemaJSONB),
+	)
+
+	readClient := postgresreader.NewReader(
+		reader.WithLocation(os.Getenv("POSTGRES")),
+		reader.WithFormat(format),
+		postgresreader.WithSchema(postgresreader.SchemaJSONB),
+	)
+
+	for key, flag := range unit.DefaultFlags() {
+		require.NoError(t, writeClient.Write(context.TODO(), key, encode(t, key, flag)))
+	}
+
+	require.Equal(t, want(t, unit.DefaultFlags()), read(t, readClient))
+
+	// a delete and an insert between reads leave the count as it was
+	_, err := conn.Exec(`DELETE FROM flags WHERE key = $1;`, "flag1")
+	require.NoError(t, err)
+
+	updated := unit.DefaultFlags()
+	updated["flag3"] = updated["flag1"]
+
+	delete(updated, "flag1")
+
+	require.NoError(t, writeClient.Write(context.TODO(), "flag3", encode(t, "flag3", updated["flag3"])))
+
+	require.Equal(t, want(t, updated), read(t, readClient))
+}
+
+func TestPostgres_JSONBReadChanges(t *testing.T) {
+	conn := reset(t)
+
+	writeClient := postgreswriter.NewWriter(
+		writer.WithLocation(os.Getenv("POSTGRES")),
+		writer.WithFormat(format),
+		postgreswriter.WithSchema(postgreswriter.SchemaJSONB),
+	)
+
+	readClient := postgresreader.NewReader(
+		reader.WithLocation(os.Getenv("POSTGRES")),
+		reader.WithFormat(format),
+		postgresreader.WithSchema(postgresreader.SchemaJSONB),
+	)
+
+	incremental, ok := readClient.(reader.IncrementalReader)
+	require.True(t, ok)
+
+	for key, flag := range unit.DefaultFlags() {
+		require.NoError(t, writeClient.Write(context.TODO(), key, encode(t, key, flag)))
+	}
+
+	full, err := incremental.ReadChanges(context.TODO(), "")
+	require.NoError(t, err)
+	require.True(t, full.Full)
+	require.Equal(t, want(t, unit.DefaultFlags()), parse(t, full.Changed))
+
+	// only the flag that changed comes back
+	updated := unit.DefaultFlags()
+	updated["flag2"].Variants["default"] = "C"
+
+	require.NoError(t, writeClient.Write(context.TODO(), "flag2", encode(t, "flag2", updated["flag2"])))
+
+	first, err := incremental.ReadChanges(context.TODO(), full.Cursor)
+	require.NoError(t, err)
+	require.False(t, first.Full)
+	require.Equal(t, want(t, map[string]*flags.Flag{"flag2": updated["flag2"]}), parse(t, first.Changed))
+
+	// a batch the cache refused comes back for the old cursor
+	again, err := incremental.ReadChanges(context.TODO(), full.Cursor)
+	require.NoError(t, err)
+	require.Equal(t, first.Changed, again.Changed)
+	require.Equal(t, first.Cursor, again.Cursor)
+
+	// nothing changed since the batch the cache took
+	none, err := incremental.ReadChanges(context.TODO(), first.Cursor)
+	require.NoError(t, err)
+	require.False(t, none.Full)
+	require.Empty(t, none.Changed)
+	require.Empty(t, none.Removed)
+
+	// a delete starts over
+	_, err = conn.Exec(`DELETE FROM flags WHERE key = $1;`, "flag1")
+	require.NoError(t, err)
+
+	delete(updated, "flag1")
+
+	reload, err := incremental.ReadChanges(context.TODO(), none.Cursor)
+	require.NoError(t, err)
+	require.True(t, reload.Full)
+	require.Equal(t, want(t, updated), parse(t, reload.Changed))
+}
+
+// reset starts every test from an empty database
+func reset(t *testing.T) *sql.DB {
+	conn, err := sql.Open("postgres", os.Getenv("POSTGRES"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	for _, statement := range []string{
+		`DROP TABLE IF EXISTS flags;`,
+		`DROP FUNCTION IF EXISTS flags_touch() CASCADE;`,
+		`DROP FUNCTION IF EXISTS flags_notify_change() CASCADE;`,
+	} {
+		_, err := conn.Exec(statement)
+		require.NoError(t, err)
+	}
+
+	return conn
+}
+
+func encode(t *testing.T, key string, flag *flags.Flag) []byte {
+	bs, err := unit.Encode(map[string]*flags.Flag{key: flag}, format)
+	require.NoError(t, err)
+
+	return bs
+}
+
+// want is what reading the flags back gives
+func want(t *testing.T, fs map[string]*flags.Flag) map[string]*flags.Flag {
+	bs, err := unit.Encode(fs, format)
+	require.NoError(t, err)
+
+	return parse(t, bs)
+}
+
+func read(t *testing.T, readClient reader.Reader) map[string]*flags.Flag {
+	bs, err := readClient.Read(context.TODO())
+	require.NoError(t, err)
+
+	return parse(t, bs)
+}
+
+func parse(t *testing.T, bs []byte) map[string]*flags.Flag {
+	fs, err := flags.Factory(bs, format)
+	require.NoError(t, err)
+
+	return fs
+}
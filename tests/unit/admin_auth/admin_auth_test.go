@@ -0,0 +1,318 @@
+package adminauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/oidc"
+	localbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/local"
+	"github.com/w-h-a/flags/internal/server/clients/exporter"
+	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
+	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
+	"github.com/w-h-a/flags/internal/server/clients/writereader"
+	mockwritereader "github.com/w-h-a/flags/internal/server/clients/writereader/mock"
+	"github.com/w-h-a/flags/internal/server/config"
+	"github.com/w-h-a/flags/tests/unit"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	tok    = "mytoken"
+	kid    = "test-key"
+	issuer = "https://idp.example.com"
+)
+
+func TestAdminAuth(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+
+	err = os.WriteFile(jwksPath, jwks(t, &key.PublicKey), 0o644)
+	require.NoError(t, err)
+
+	readToken := sign(t, key, jwt.MapClaims{"sub": "reader@example.com", "roles": []string{"flags:read"}})
+	writeToken := sign(t, key, jwt.MapClaims{"sub": "writer@example.com", "roles": []string{"flags:write"}})
+	expiredToken := sign(t, key, jwt.MapClaims{"sub": "writer@example.com", "roles": []string{"flags:write"}, "exp": time.Now().Add(-time.Minute).Unix()})
+	foreignToken := sign(t, otherKey, jwt.MapClaims{"sub": "writer@example.com", "roles": []string{"flags:write"}})
+
+	updated, err := os.ReadFile("../testdata/upsert_flag/valid_response_updated.json")
+	require.NoError(t, err)
+
+	type inputs struct {
+		method string
+		path   string
+		body   []byte
+		token  string
+	}
+
+	type want struct {
+		httpCode int
+		bodyFile string
+	}
+
+	tests := []struct {
+		name   string
+		inputs inputs
+		want   want
+	}{
+		{
+			name: "200 for read with read role",
+			inputs: inputs{
+				method: http.MethodGet,
+				path:   "/admin/v1/flags/flag2",
+				token:  readToken,
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/get_flag/valid_response_flag2.json",
+			},
+		},
+		{
+			name: "200 for write with write role",
+			inputs: inputs{
+				method: http.MethodPut,
+				path:   "/admin/v1/flags",
+				body:   updated,
+				token:  writeToken,
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/upsert_flag/valid_response_updated.json",
+			},
+		},
+		{
+			name: "403 for write with read role",
+			inputs: inputs{
+				method: http.MethodPut,
+				path:   "/admin/v1/flags",
+				body:   updated,
+				token:  readToken,
+			},
+			want: want{
+				httpCode: http.StatusForbidden,
+				bodyFile: "../testdata/forbidden.json",
+			},
+		},
+		{
+			name: "403 for patch of a flag called validate with read role",
+			inputs: inputs{
+				method: http.MethodPatch,
+				path:   "/admin/v1/flags/validate",
+				body:   []byte(`{"disabled":true}`),
+				token:  readToken,
+			},
+			want: want{
+				httpCode: http.StatusForbidden,
+				bodyFile: "../testdata/forbidden.json",
+			},
+		},
+		{
+			name: "401 for expired token",
+			inputs: inputs{
+				method: http.MethodGet,
+				path:   "/admin/v1/flags/flag2",
+				token:  expiredToken,
+			},
+			want: want{
+				httpCode: http.StatusUnauthorized,
+				bodyFile: "../testdata/unauthorized.json",
+			},
+		},
+		{
+			name: "401 for token signed by unknown key",
+			inputs: inputs{
+				method: http.MethodGet,
+				path:   "/admin/v1/flags/flag2",
+				token:  foreignToken,
+			},
+			want: want{
+				httpCode: http.StatusUnauthorized,
+				bodyFile: "../testdata/unauthorized.json",
+			},
+		},
+		{
+			name: "401 for static api key on admin",
+			inputs: inputs{
+				method: http.MethodGet,
+				path:   "/admin/v1/flags/flag2",
+				token:  tok,
+			},
+			want: want{
+				httpCode: http.StatusUnauthorized,
+				bodyFile: "../testdata/unauthorized.json",
+			},
+		},
+		{
+			name: "200 for evaluation with static api key",
+			inputs: inputs{
+				method: http.MethodPost,
+				path:   "/ofrep/v1/evaluate/flags/flag2",
+				token:  tok,
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/admin_auth/valid_eval_response.json",
+			},
+		},
+		{
+			name: "401 for evaluation with jwt",
+			inputs: inputs{
+				method: http.MethodPost,
+				path:   "/ofrep/v1/evaluate/flags/flag2",
+				token:  writeToken,
+			},
+			want: want{
+				httpCode: http.StatusUnauthorized,
+				bodyFile: "../testdata/unauthorized.json",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		// env vars
+		os.Setenv("API_KEYS", tok)
+		os.Setenv("FLAG_FORMAT", "yaml")
+		os.Setenv("WRITE_CLIENT_LOCATION", "any")
+
+		// config
+		config.New()
+
+		// clients
+		writereadClient := mockwritereader.NewWriteReader(
+			writereader.WithLocation(config.WriteClientLocation()),
+		)
+
+		for k, v := range unit.DefaultFlags() {
+			bs, err := yaml.Marshal(map[string]*flags.Flag{
+				k: v,
+			})
+			require.NoError(t, err)
+
+			err = writereadClient.Write(context.TODO(), k, bs)
+			require.NoError(t, err)
+		}
+
+		exportClient := localexporter.NewExporter(
+			exporter.WithDir(config.ExportClientDir()),
+		)
+
+		notifyClient := localnotifier.NewNotifier()
+
+		authClient := oidc.NewAuthenticator(
+			authenticator.WithLocation(jwksPath),
+			authenticator.WithIssuer(issuer),
+		)
+
+		broadcastClient := localbroadcaster.NewBroadcaster()
+
+		// servers and services
+		httpServer, _, exportService, notifyService, err := server.Factory(
+			writereadClient,
+			writereadClient,
+			exportClient,
+			notifyClient,
+			authClient,
+			broadcastClient,
+		)
+		require.NoError(t, err)
+
+		t.Run(test.name, func(t *testing.T) {
+			err = httpServer.Run()
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(
+				test.inputs.method,
+				fmt.Sprintf("http://%s%s", httpServer.Options().Address, test.inputs.path),
+				bytes.NewReader(test.inputs.body),
+			)
+			require.NoError(t, err)
+
+			req.Header.Set("content-type", "application/json")
+			req.Header.Set("authorization", fmt.Sprintf("Bearer %s", test.inputs.token))
+
+			client := &http.Client{}
+
+			rsp, err := client.Do(req)
+			require.NoError(t, err)
+
+			want, err := os.ReadFile(test.want.bodyFile)
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(rsp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, string(want), string(got))
+
+			require.Equal(t, test.want.httpCode, rsp.StatusCode)
+
+			t.Cleanup(func() {
+				rsp.Body.Close()
+				notifyService.Close()
+				exportService.Close()
+				err = httpServer.Stop()
+				require.NoError(t, err)
+				config.Reset()
+			})
+		})
+	}
+}
+
+func jwks(t *testing.T, key *rsa.PublicKey) []byte {
+	bs, err := json.Marshal(map[string]any{
+		"keys": []map[string]any{
+			{
+				"kid": kid,
+				"kty": "RSA",
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	return bs
+}
+
+func sign(t *testing.T, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	claims["iss"] = issuer
+
+	if _, ok := claims["exp"]; !ok {
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	return signed
+}
@@ -0,0 +1,313 @@
+package dynamodbstream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	dynamodbreader "github.com/w-h-a/flags/internal/server/clients/reader/dynamodb"
+	"github.com/w-h-a/flags/internal/server/config"
+	"github.com/w-h-a/flags/internal/server/services/cache"
+	"github.com/w-h-a/flags/tests/unit"
+)
+
+const (
+	shard = "shardId-000001"
+)
+
+func TestDynamoDBStream_ReadChanges(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	// the reader's config was loaded before the test could set credentials
+	dynamodbreader.AWSCFG.Credentials = aws.AnonymousCredentials{}
+
+	table := newTable(t)
+	defer table.Close()
+
+	readClient := dynamodbreader.NewReader(
+		reader.WithLocation(table.URL),
+		reader.WithFormat("yaml"),
+		dynamodbreader.WithStream(true),
+	)
+
+	incremental, ok := readClient.(reader.IncrementalReader)
+	require.True(t, ok)
+
+	full, err := incremental.ReadChanges(context.TODO(), "")
+	require.NoError(t, err)
+	require.True(t, full.Full)
+	require.Equal(t, parse(t, encode(t, unit.DefaultFlags())), parse(t, full.Changed))
+
+	updated := unit.DefaultFlags()
+	updated["flag2"].Variants["default"] = "C"
+
+	table.put(t, "flag2", updated["flag2"])
+
+	first, err := incremental.ReadChanges(context.TODO(), full.Cursor)
+	require.NoError(t, err)
+	require.False(t, first.Full)
+	require.Equal(t, parse(t, encode(t, map[string]*flags.Flag{"flag2": updated["flag2"]})), parse(t, first.Changed))
+	require.NotEqual(t, full.Cursor, first.Cursor)
+
+	// the cache refused the batch so it comes back with the old cursor
+	again, err := incremental.ReadChanges(context.TODO(), full.Cursor)
+	require.NoError(t, err)
+	require.False(t, again.Full)
+	require.Equal(t, first.Changed, again.Changed)
+	require.Equal(t, first.Cursor, again.Cursor)
+
+	// the cache took it so the stream moves on
+	table.remove("flag1")
+
+	next, err := incremental.ReadChanges(context.TODO(), again.Cursor)
+	require.NoError(t, err)
+	require.False(t, next.Full)
+	require.Empty(t, next.Changed)
+	require.Equal(t, []string{"flag1"}, next.Removed)
+
+	// an unknown cursor reads the table again
+	reload, err := incremental.ReadChanges(context.TODO(), "unknown")
+	require.NoError(t, err)
+	require.True(t, reload.Full)
+	require.Equal(t, parse(t, encode(t, map[string]*flags.Flag{"flag2": updated["flag2"]})), parse(t, reload.Changed))
+}
+
+func TestDynamoDBStream_RefusedBatch(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	// the reader's config was loaded before the test could set credentials
+	dynamodbreader.AWSCFG.Credentials = aws.AnonymousCredentials{}
+	os.Setenv("FLAG_FORMAT", "yaml")
+
+	config.New()
+	defer config.Reset()
+
+	table := newTable(t)
+	defer table.Close()
+
+	cacheService := cache.New(dynamodbreader.NewReader(
+		reader.WithLocation(table.URL),
+		reader.WithFormat(config.FlagFormat()),
+		dynamodbreader.WithStream(true),
+	))
+
+	_, _, err := cacheService.RetrieveFlags()
+	require.NoError(t, err)
+
+	// one good change and one the cache refuses
+	updated := unit.DefaultFlags()
+	updated["flag1"].Variants["variant2"] = "C"
+
+	table.put(t, "flag1", updated["flag1"])
+	table.putRaw("flag2", []byte("flag2: [not, a, flag]\n"))
+
+	_, _, err = cacheService.RetrieveFlags()
+	require.Error(t, err)
+
+	flag1, err := cacheService.EvaluateFlag(context.TODO(), "flag1", map[string]any{})
+	require.NoError(t, err)
+	require.Equal(t, "B", flag1.Value)
+
+	// the fix comes with the change that was refused alongside it
+	table.put(t, "flag2", updated["flag2"])
+
+	_, _, err = cacheService.RetrieveFlags()
+	require.NoError(t, err)
+
+	flag1, err = cacheService.EvaluateFlag(context.TODO(), "flag1", map[string]any{})
+	require.NoError(t, err)
+	require.Equal(t, "C", flag1.Value)
+}
+
+type record struct {
+	name  string
+	key   string
+	value []byte
+}
+
+// table stands in for a dynamodb table with a stream of one open shard
+type table struct {
+	*httptest.Server
+	items   map[string][]byte
+	records []record
+	mtx     sync.Mutex
+}
+
+func (tb *table) put(t *testing.T, key string, flag *flags.Flag) {
+	tb.putRaw(key, encode(t, map[string]*flags.Flag{key: flag}))
+}
+
+func (tb *table) putRaw(key string, bs []byte) {
+	tb.mtx.Lock()
+	defer tb.mtx.Unlock()
+
+	tb.items[key] = bs
+	tb.records = append(tb.records, record{name: "MODIFY", key: key, value: bs})
+}
+
+func (tb *table) remove(key string) {
+	tb.mtx.Lock()
+	defer tb.mtx.Unlock()
+
+	delete(tb.items, key)
+	tb.records = append(tb.records, record{name: "REMOVE", key: key})
+}
+
+func (tb *table) handle(w http.ResponseWriter, r *http.Request) {
+	tb.mtx.Lock()
+	defer tb.mtx.Unlock()
+
+	var rsp any
+
+	switch target := r.Header.Get("X-Amz-Target"); target {
+	case "DynamoDB_20120810.CreateTable":
+		rsp = map[string]any{"TableDescription": map[string]any{}}
+	case "DynamoDB_20120810.DescribeTable":
+		rsp = map[string]any{
+			"Table": map[string]any{
+				"LatestStreamArn": "arn:aws:dynamodb:us-east-1:000000000000:table/flags/stream/1",
+				"StreamSpecification": map[string]any{
+					"StreamEnabled":  true,
+					"StreamViewType": "NEW_IMAGE",
+				},
+			},
+		}
+	case "DynamoDB_20120810.Scan":
+		keys := []string{}
+
+		for key := range tb.items {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		items := []any{}
+
+		for _, key := range keys {
+			items = append(items, map[string]any{
+				"Key":   map[string]any{"S": key},
+				"Value": map[string]any{"B": tb.items[key]},
+			})
+		}
+
+		rsp = map[string]any{"Items": items, "Count": len(items)}
+	case "DynamoDBStreams_20120810.DescribeStream":
+		rsp = map[string]any{
+			"StreamDescription": map[string]any{
+				"Shards": []any{
+					map[string]any{
+						"ShardId": shard,
+						"SequenceNumberRange": map[string]any{
+							"StartingSequenceNumber": "1",
+						},
+					},
+				},
+			},
+		}
+	case "DynamoDBStreams_20120810.GetShardIterator":
+		input := struct {
+			ShardIteratorType string
+		}{}
+
+		json.NewDecoder(r.Body).Decode(&input)
+
+		at := 0
+		if input.ShardIteratorType == "LATEST" {
+			at = len(tb.records)
+		}
+
+		rsp = map[string]any{"ShardIterator": iterator(at)}
+	case "DynamoDBStreams_20120810.GetRecords":
+		input := struct {
+			ShardIterator string
+		}{}
+
+		json.NewDecoder(r.Body).Decode(&input)
+
+		at, _ := strconv.Atoi(strings.TrimPrefix(input.ShardIterator, shard+":"))
+
+		records := []any{}
+
+		for i, rec := range tb.records[at:] {
+			image := map[string]any{
+				"Keys": map[string]any{"Key": map[string]any{"S": rec.key}},
+			}
+
+			if rec.name != "REMOVE" {
+				image["NewImage"] = map[string]any{
+					"Key":   map[string]any{"S": rec.key},
+					"Value": map[string]any{"B": rec.value},
+				}
+			}
+
+			records = append(records, map[string]any{
+				"eventID":   strconv.Itoa(at + i),
+				"eventName": rec.name,
+				"dynamodb":  image,
+			})
+		}
+
+		rsp = map[string]any{
+			"Records":           records,
+			"NextShardIterator": iterator(len(tb.records)),
+		}
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"__type":"UnknownOperationException","message":"%s"}`, target)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	json.NewEncoder(w).Encode(rsp)
+}
+
+func newTable(t *testing.T) *table {
+	tb := &table{
+		items: map[string][]byte{},
+		mtx:   sync.Mutex{},
+	}
+
+	for key, flag := range unit.DefaultFlags() {
+		tb.items[key] = encode(t, map[string]*flags.Flag{key: flag})
+	}
+
+	tb.Server = httptest.NewServer(http.HandlerFunc(tb.handle))
+
+	return tb
+}
+
+func iterator(at int) string {
+	return fmt.Sprintf("%s:%d", shard, at)
+}
+
+func encode(t *testing.T, fs map[string]*flags.Flag) []byte {
+	bs, err := unit.Encode(fs, "yaml")
+	require.NoError(t, err)
+
+	return bs
+}
+
+func parse(t *testing.T, bs []byte) map[string]*flags.Flag {
+	fs, err := flags.Factory(bs, "yaml")
+	require.NoError(t, err)
+
+	return fs
+}
@@ -0,0 +1,169 @@
+package environmentoverrides
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	localreader "github.com/w-h-a/flags/internal/server/clients/reader/local"
+	"github.com/w-h-a/flags/internal/server/config"
+	"github.com/w-h-a/flags/internal/server/services/cache"
+	"gopkg.in/yaml.v3"
+)
+
+func TestEnvironmentOverrides(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	bs, err := os.ReadFile("../testdata/environment_overrides/flags.yaml")
+	require.NoError(t, err)
+
+	type want struct {
+		disabled bool
+		variants map[string]any
+		rules    int
+	}
+
+	tests := []struct {
+		name string
+		env  string
+		want map[string]want
+	}{
+		{
+			name: "overrides disabled, variants and rules",
+			env:  "prod",
+			want: map[string]want{
+				"flag1": {disabled: true, variants: map[string]any{"default": "C", "variant2": "B"}, rules: 0},
+				"flag2": {disabled: true, variants: map[string]any{"default": "A"}, rules: 0},
+			},
+		},
+		{
+			name: "overrides one variant",
+			env:  "staging",
+			want: map[string]want{
+				"flag1": {disabled: false, variants: map[string]any{"default": "A", "variant2": "D"}, rules: 1},
+				"flag2": {disabled: true, variants: map[string]any{"default": "A"}, rules: 0},
+			},
+		},
+		{
+			name: "overrides disabled only",
+			env:  "dev",
+			want: map[string]want{
+				"flag1": {disabled: false, variants: map[string]any{"default": "A", "variant2": "B"}, rules: 1},
+				"flag2": {disabled: false, variants: map[string]any{"default": "A"}, rules: 0},
+			},
+		},
+		{
+			name: "no overrides",
+			env:  "qa",
+			want: map[string]want{
+				"flag1": {disabled: false, variants: map[string]any{"default": "A", "variant2": "B"}, rules: 1},
+				"flag2": {disabled: true, variants: map[string]any{"default": "A"}, rules: 0},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := flags.FactoryIn(bs, "yaml", test.env)
+			require.NoError(t, err)
+
+			require.Len(t, got, len(test.want))
+
+			for k, want := range test.want {
+				require.Equal(t, want.disabled, got[k].IsDisabled())
+				require.Equal(t, want.variants, got[k].Variants)
+				require.Len(t, got[k].Rules, want.rules)
+				require.Nil(t, got[k].Environments)
+			}
+		})
+	}
+
+	// the definitions keep their overrides when written back
+	defs, err := flags.Factory(bs, "yaml")
+	require.NoError(t, err)
+
+	written, err := yaml.Marshal(defs)
+	require.NoError(t, err)
+
+	reread, err := flags.FactoryIn(written, "yaml", "prod")
+	require.NoError(t, err)
+
+	require.True(t, reread["flag1"].IsDisabled())
+	require.Empty(t, reread["flag1"].Rules)
+	require.Equal(t, "C", reread["flag1"].Variants["default"])
+}
+
+func TestEnvironmentOverrides_Validate(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	bs, err := os.ReadFile("../testdata/parse_flags/unknown_variant_override_rule.yaml")
+	require.NoError(t, err)
+
+	_, errs := flags.Validate(bs, "yaml")
+	require.Len(t, errs, 1)
+
+	require.Equal(t, "/test/environments/prod/rules/0/variant", errs[0].Path)
+	require.Equal(t, "test", errs[0].Key())
+}
+
+func TestEnvironmentOverrides_Cache(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	// env vars
+	os.Setenv("ENV", "prod")
+	os.Setenv("FLAG_FORMAT", "yaml")
+
+	// config
+	config.New()
+	defer config.Reset()
+
+	readClient := localreader.NewReader(
+		reader.WithLocation("../testdata/environment_overrides/flags.yaml"),
+		reader.WithFormat(config.FlagFormat()),
+	)
+
+	tests := []struct {
+		name         string
+		cacheService *cache.Service
+		value        any
+		reason       string
+	}{
+		{
+			name:         "environment of this instance",
+			cacheService: cache.New(readClient),
+			value:        "C",
+			reason:       flags.ReasonDisabled,
+		},
+		{
+			name:         "chosen environment",
+			cacheService: cache.NewForEnvironment("staging", readClient),
+			value:        "D",
+			reason:       flags.ReasonTargetingMatch,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := test.cacheService.RetrieveFlags()
+			require.NoError(t, err)
+
+			flagState, err := test.cacheService.EvaluateFlag(context.TODO(), "flag1", map[string]any{})
+			require.NoError(t, err)
+
+			require.Equal(t, test.value, flagState.Value)
+			require.Equal(t, test.reason, flagState.Reason)
+		})
+	}
+}
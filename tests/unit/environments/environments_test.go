@@ -0,0 +1,391 @@
+package environments
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/apikey"
+	localbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/local"
+	"github.com/w-h-a/flags/internal/server/clients/exporter"
+	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
+	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	localreader "github.com/w-h-a/flags/internal/server/clients/reader/local"
+	"github.com/w-h-a/flags/internal/server/clients/writer"
+	localwriter "github.com/w-h-a/flags/internal/server/clients/writer/local"
+	"github.com/w-h-a/flags/internal/server/config"
+	"github.com/w-h-a/flags/tests/unit"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	tok        = "mytoken"
+	stagingTok = "mystagingtoken"
+)
+
+func TestEnvironments(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	// flags files
+	dir := t.TempDir()
+
+	staging, err := yaml.Marshal(unit.DefaultFlags())
+	require.NoError(t, err)
+
+	prod, err := yaml.Marshal(map[string]*flags.Flag{"flag1": unit.DefaultFlags()["flag1"]})
+	require.NoError(t, err)
+
+	err = os.WriteFile(filepath.Join(dir, "staging.yaml"), staging, 0644)
+	require.NoError(t, err)
+
+	err = os.WriteFile(filepath.Join(dir, "prod.yaml"), prod, 0644)
+	require.NoError(t, err)
+
+	// env vars
+	os.Setenv("ENV", "prod")
+	os.Setenv("API_KEYS", tok)
+	os.Setenv("FLAG_FORMAT", "yaml")
+	os.Setenv("ENVIRONMENTS", "staging,prod")
+	os.Setenv("STAGING_API_KEYS", stagingTok)
+	os.Setenv("STAGING_READ_CLIENT_LOCATION", filepath.Join(dir, "staging.yaml"))
+	os.Setenv("STAGING_WRITE_CLIENT_LOCATION", filepath.Join(dir, "staging.yaml"))
+	os.Setenv("PROD_READ_CLIENT_LOCATION", filepath.Join(dir, "prod.yaml"))
+	os.Setenv("PROD_WRITE_CLIENT_LOCATION", filepath.Join(dir, "prod.yaml"))
+
+	// config
+	config.New()
+	defer config.Reset()
+
+	require.Equal(t, []string{"staging", "prod"}, config.Environments())
+
+	// clients
+	environments := []server.Environment{}
+
+	for _, env := range config.Environments() {
+		environments = append(environments, server.Environment{
+			Name: env,
+			WriteClient: localwriter.NewWriter(
+				writer.WithLocation(config.EnvironmentWriteClientLocation(env)),
+				writer.WithFormat(config.FlagFormat()),
+			),
+			ReadClient: localreader.NewReader(
+				reader.WithLocation(config.EnvironmentReadClientLocation(env)),
+				reader.WithFormat(config.FlagFormat()),
+			),
+		})
+	}
+
+	exportClient := localexporter.NewExporter(
+		exporter.WithDir(config.ExportClientDir()),
+	)
+
+	notifyClient := localnotifier.NewNotifier()
+
+	authClient := apikey.NewAuthenticator()
+
+	broadcastClient := localbroadcaster.NewBroadcaster()
+
+	// servers and services
+	httpServer, cacheServices, exportService, notifyServices, err := server.EnvironmentsFactory(
+		environments,
+		exportClient,
+		notifyClient,
+		authClient,
+		broadcastClient,
+	)
+	require.NoError(t, err)
+
+	require.Len(t, cacheServices, 2)
+
+	err = httpServer.Run()
+	require.NoError(t, err)
+
+	defer func() {
+		for _, notifyService := range notifyServices {
+			notifyService.Close()
+		}
+		exportService.Close()
+		err := httpServer.Stop()
+		require.NoError(t, err)
+	}()
+
+	address := httpServer.Options().Address
+
+	type inputs struct {
+		path  string
+		body  string
+		token string
+	}
+
+	type want struct {
+		httpCode int
+		body     map[string]any
+	}
+
+	tests := []struct {
+		name   string
+		inputs inputs
+		want   want
+	}{
+		{
+			name: "404 for flag missing from the default environment",
+			inputs: inputs{
+				path:  "/ofrep/v1/evaluate/flags/flag2",
+				token: tok,
+			},
+			want: want{
+				httpCode: http.StatusNotFound,
+				body:     map[string]any{"errorCode": "FLAG_NOT_FOUND"},
+			},
+		},
+		{
+			name: "200 for environment taken from the path",
+			inputs: inputs{
+				path:  "/env/staging/ofrep/v1/evaluate/flags/flag2",
+				token: tok,
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				body:     map[string]any{"value": "B"},
+			},
+		},
+		{
+			name: "200 for environment taken from the key",
+			inputs: inputs{
+				path:  "/ofrep/v1/evaluate/flags/flag2",
+				token: stagingTok,
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				body:     map[string]any{"value": "B"},
+			},
+		},
+		{
+			name: "403 for key used outside its environment",
+			inputs: inputs{
+				path:  "/env/prod/ofrep/v1/evaluate/flags/flag1",
+				token: stagingTok,
+			},
+			want: want{
+				httpCode: http.StatusForbidden,
+				body:     map[string]any{"error": "not authorized"},
+			},
+		},
+		{
+			name: "404 for unknown environment",
+			inputs: inputs{
+				path:  "/env/qa/ofrep/v1/evaluate/flags/flag1",
+				token: tok,
+			},
+			want: want{
+				httpCode: http.StatusNotFound,
+				body:     map[string]any{"error": `unknown environment "qa"`},
+			},
+		},
+		{
+			name: "403 for promotion out of the key's environment",
+			inputs: inputs{
+				path:  "/admin/v1/flags/flag2/promote",
+				body:  `{"to": "prod"}`,
+				token: stagingTok,
+			},
+			want: want{
+				httpCode: http.StatusForbidden,
+				body:     map[string]any{"error": "not authorized"},
+			},
+		},
+		{
+			name: "400 for promotion to unknown environment",
+			inputs: inputs{
+				path:  "/env/staging/admin/v1/flags/flag2/promote",
+				body:  `{"to": "qa"}`,
+				token: tok,
+			},
+			want: want{
+				httpCode: http.StatusBadRequest,
+				body:     map[string]any{"error": `unknown environment "qa"`},
+			},
+		},
+		{
+			name: "200 for promotion",
+			inputs: inputs{
+				path:  "/env/staging/admin/v1/flags/flag2/promote",
+				body:  `{"to": "prod"}`,
+				token: tok,
+			},
+			want: want{
+				httpCode: http.StatusOK,
+			},
+		},
+		{
+			name: "200 for promoted flag in the default environment",
+			inputs: inputs{
+				path:  "/ofrep/v1/evaluate/flags/flag2",
+				token: tok,
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				body:     map[string]any{"value": "B"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(
+				http.MethodPost,
+				fmt.Sprintf("http://%s%s", address, test.inputs.path),
+				strings.NewReader(test.inputs.body),
+			)
+			require.NoError(t, err)
+
+			req.Header.Set("content-type", "application/json")
+			req.Header.Set("authorization", fmt.Sprintf("Bearer %s", test.inputs.token))
+
+			rsp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+
+			defer rsp.Body.Close()
+
+			bs, err := io.ReadAll(rsp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, test.want.httpCode, rsp.StatusCode, string(bs))
+
+			got := map[string]any{}
+
+			err = json.Unmarshal(bs, &got)
+			require.NoError(t, err)
+
+			for k, v := range test.want.body {
+				require.Equal(t, v, got[k])
+			}
+		})
+	}
+
+	// the promotion was written to the prod file
+	bs, err := os.ReadFile(filepath.Join(dir, "prod.yaml"))
+	require.NoError(t, err)
+
+	promoted, err := flags.Factory(bs, config.FlagFormat())
+	require.NoError(t, err)
+	require.Contains(t, promoted, "flag2")
+
+	// each environment reports its own status
+	rsp, err := http.Get(fmt.Sprintf("http://%s%s", address, "/env/staging/status"))
+	require.NoError(t, err)
+
+	defer rsp.Body.Close()
+
+	status := map[string]any{}
+
+	err = json.NewDecoder(rsp.Body).Decode(&status)
+	require.NoError(t, err)
+
+	require.Equal(t, "staging", status["env"])
+}
+
+func TestEnvironments_SharedLocation(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		err  string
+	}{
+		{
+			name: "each environment has its own store",
+			env: map[string]string{
+				"STAGING_READ_CLIENT_LOCATION":  "./staging.yaml",
+				"STAGING_WRITE_CLIENT_LOCATION": "./staging.yaml",
+				"PROD_READ_CLIENT_LOCATION":     "./prod.yaml",
+				"PROD_WRITE_CLIENT_LOCATION":    "./prod.yaml",
+			},
+		},
+		{
+			name: "both read the global location",
+			env: map[string]string{
+				"STAGING_READ_CLIENT_LOCATION":  "",
+				"STAGING_WRITE_CLIENT_LOCATION": "./staging.yaml",
+				"PROD_READ_CLIENT_LOCATION":     "",
+				"PROD_WRITE_CLIENT_LOCATION":    "./prod.yaml",
+			},
+			err: `environments "staging" and "prod" read from the same location, set <ENV>_READ_CLIENT_LOCATION for each`,
+		},
+		{
+			name: "both write one file",
+			env: map[string]string{
+				"STAGING_READ_CLIENT_LOCATION":  "./staging.yaml",
+				"STAGING_WRITE_CLIENT_LOCATION": "./flags.yaml",
+				"PROD_READ_CLIENT_LOCATION":     "./prod.yaml",
+				"PROD_WRITE_CLIENT_LOCATION":    "./flags.yaml",
+			},
+			err: `environments "staging" and "prod" write to the same location, set <ENV>_WRITE_CLIENT_LOCATION for each`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// env vars
+			os.Setenv("ENVIRONMENTS", "staging,prod")
+			os.Setenv("STAGING_WRITE_CLIENT", "local")
+			os.Setenv("PROD_WRITE_CLIENT", "local")
+
+			defer os.Unsetenv("STAGING_WRITE_CLIENT")
+			defer os.Unsetenv("PROD_WRITE_CLIENT")
+
+			for k, v := range test.env {
+				os.Setenv(k, v)
+			}
+
+			// config
+			config.New()
+			defer config.Reset()
+
+			err := config.ValidateEnvironments()
+			if len(test.err) == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestEnvironments_SnapshotDir(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	dir := t.TempDir()
+
+	// env vars
+	os.Setenv("ENVIRONMENTS", "staging,prod")
+	os.Setenv("READ_CLIENT_SNAPSHOT", dir)
+
+	defer os.Unsetenv("READ_CLIENT_SNAPSHOT")
+
+	// config
+	config.New()
+	defer config.Reset()
+
+	require.Equal(t, filepath.Join(dir, "flags.staging.snapshot"), config.EnvironmentReadClientSnapshot("staging"))
+	require.Equal(t, filepath.Join(dir, "flags.prod.snapshot"), config.EnvironmentReadClientSnapshot("prod"))
+}
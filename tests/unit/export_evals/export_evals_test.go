@@ -34,6 +34,7 @@ func TestExportEvals_FlushWithTime(t *testing.T) {
 	want := []export.Event{
 		{
 			CreationDate: time.Now().Unix(),
+			Environment:  "prod",
 			Key:          "random-key",
 			Value:        "YO",
 			Variant:      "default",
@@ -53,6 +54,7 @@ func TestExportEvals_FlushWithTime(t *testing.T) {
 
 	for i, event := range want {
 		require.Equal(t, event.CreationDate, got[i].CreationDate)
+		require.Equal(t, event.Environment, got[i].Environment)
 		require.Equal(t, event.Key, got[i].Key)
 	}
 
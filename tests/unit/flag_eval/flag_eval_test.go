@@ -10,6 +10,8 @@ import (
 
 	"github.com/stretchr/testify/require"
 	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/apikey"
+	localbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/local"
 	"github.com/w-h-a/flags/internal/server/clients/exporter"
 	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
 	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
@@ -132,12 +134,18 @@ func TestFlagEval_YAML(t *testing.T) {
 
 		notifyClient := localnotifier.NewNotifier()
 
+		authClient := apikey.NewAuthenticator()
+
+		broadcastClient := localbroadcaster.NewBroadcaster()
+
 		// servers
 		httpServer, _, exportService, notifyService, err := server.Factory(
 			writeClient,
 			readClient,
 			exportClient,
 			notifyClient,
+			authClient,
+			broadcastClient,
 		)
 		require.NoError(t, err)
 
@@ -297,12 +305,18 @@ func TestFlagEval_JSON(t *testing.T) {
 
 		notifyClient := localnotifier.NewNotifier()
 
+		authClient := apikey.NewAuthenticator()
+
+		broadcastClient := localbroadcaster.NewBroadcaster()
+
 		// servers
 		httpServer, _, exportService, notifyService, err := server.Factory(
 			writeClient,
 			readClient,
 			exportClient,
 			notifyClient,
+			authClient,
+			broadcastClient,
 		)
 		require.NoError(t, err)
 
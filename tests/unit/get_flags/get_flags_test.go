@@ -12,6 +12,8 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/w-h-a/flags/internal/flags"
 	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/apikey"
+	localbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/local"
 	"github.com/w-h-a/flags/internal/server/clients/exporter"
 	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
 	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
@@ -128,12 +130,18 @@ func TestGetFlags(t *testing.T) {
 
 		notifyClient := localnotifier.NewNotifier()
 
+		authClient := apikey.NewAuthenticator()
+
+		broadcastClient := localbroadcaster.NewBroadcaster()
+
 		// servers and services
 		httpServer, _, exportService, notifyService, err := server.Factory(
 			writereadClient,
 			writereadClient,
 			exportClient,
 			notifyClient,
+			authClient,
+			broadcastClient,
 		)
 		require.NoError(t, err)
 
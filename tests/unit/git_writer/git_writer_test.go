@@ -0,0 +1,254 @@
+package gitwriter
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/apikey"
+	localbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/local"
+	"github.com/w-h-a/flags/internal/server/clients/exporter"
+	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
+	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	gitreader "github.com/w-h-a/flags/internal/server/clients/reader/git"
+	"github.com/w-h-a/flags/internal/server/clients/writer"
+	gitwriter "github.com/w-h-a/flags/internal/server/clients/writer/git"
+	"github.com/w-h-a/flags/internal/server/config"
+)
+
+const (
+	tok = "mytoken"
+)
+
+func TestGitWriter(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	type inputs struct {
+		withRemote bool
+		key        string
+		body       string
+	}
+
+	type want struct {
+		httpCode int
+		commit   string
+		filePath string
+		bodyFile string
+	}
+
+	tests := []struct {
+		name   string
+		inputs inputs
+		want   want
+	}{
+		{
+			name: "update is pushed to the remote",
+			inputs: inputs{
+				withRemote: true,
+				key:        "flag1",
+				body:       `{"flag1":{"disabled":true,"variants":{"default":"A","variant2":"B"}}}`,
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				commit:   "api-key <> Update flag flag1",
+				filePath: "../testdata/local_writer/updated.yaml",
+				bodyFile: "../testdata/git_writer/valid_response_updated.json",
+			},
+		},
+		{
+			name: "create is committed to the local repository",
+			inputs: inputs{
+				withRemote: false,
+				key:        "flag3",
+				body:       `{"flag3":{"disabled":false,"variants":{"default":"C"}}}`,
+			},
+			want: want{
+				httpCode: http.StatusCreated,
+				commit:   "api-key <> Update flag flag3",
+				filePath: "../testdata/local_writer/created.yaml",
+				bodyFile: "../testdata/git_writer/valid_response_created.json",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		// repositories
+		dir := t.TempDir()
+
+		origin := seed(t, dir)
+
+		// env vars
+		os.Setenv("API_KEYS", tok)
+		os.Setenv("FLAG_FORMAT", "yaml")
+
+		// config
+		config.New()
+
+		// clients
+		var readClient reader.Reader
+		var writeClient writer.Writer
+
+		if test.inputs.withRemote {
+			readClient = gitreader.NewReader(
+				reader.WithLocation(filepath.Join(dir, "reader")),
+				reader.WithFormat(config.FlagFormat()),
+				reader.WithRemote(origin),
+				reader.WithBranch("main"),
+				reader.WithPath("flags.yaml"),
+			)
+
+			writeClient = gitwriter.NewWriter(
+				writer.WithLocation(filepath.Join(dir, "writer")),
+				writer.WithFormat(config.FlagFormat()),
+				writer.WithRemote(origin),
+				writer.WithBranch("main"),
+				writer.WithPath("flags.yaml"),
+			)
+		} else {
+			local := filepath.Join(dir, "local")
+
+			git(t, dir, "clone", "--quiet", origin, local)
+
+			readClient = gitreader.NewReader(
+				reader.WithLocation(local),
+				reader.WithFormat(config.FlagFormat()),
+				reader.WithBranch("main"),
+				reader.WithPath("flags.yaml"),
+			)
+
+			writeClient = gitwriter.NewWriter(
+				writer.WithLocation(local),
+				writer.WithFormat(config.FlagFormat()),
+				writer.WithBranch("main"),
+				writer.WithPath("flags.yaml"),
+			)
+
+			origin = filepath.Join(local, ".git")
+		}
+
+		exportClient := localexporter.NewExporter(
+			exporter.WithDir(config.ExportClientDir()),
+		)
+
+		notifyClient := localnotifier.NewNotifier()
+
+		authClient := apikey.NewAuthenticator()
+
+		broadcastClient := localbroadcaster.NewBroadcaster()
+
+		// servers and services
+		httpServer, _, exportService, notifyService, err := server.Factory(
+			writeClient,
+			readClient,
+			exportClient,
+			notifyClient,
+			authClient,
+			broadcastClient,
+		)
+		require.NoError(t, err)
+
+		t.Run(test.name, func(t *testing.T) {
+			err = httpServer.Run()
+			require.NoError(t, err)
+
+			rsp := do(t, http.MethodPut, httpServer.Options().Address, "/admin/v1/flags", test.inputs.body)
+			rsp.Body.Close()
+
+			require.Equal(t, test.want.httpCode, rsp.StatusCode)
+
+			commit := git(t, dir, "--git-dir", origin, "log", "-1", "--format=%an <%ae> %s", "main")
+			require.Equal(t, test.want.commit, strings.TrimSpace(commit))
+
+			want, err := os.ReadFile(test.want.filePath)
+			require.NoError(t, err)
+
+			got := git(t, dir, "--git-dir", origin, "show", "main:flags.yaml")
+			require.Equal(t, string(want), got)
+
+			// the reader sees the commit
+			rsp = do(t, http.MethodGet, httpServer.Options().Address, "/admin/v1/flags/"+test.inputs.key, "")
+
+			want, err = os.ReadFile(test.want.bodyFile)
+			require.NoError(t, err)
+
+			body, err := io.ReadAll(rsp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, string(want), string(body))
+
+			t.Cleanup(func() {
+				rsp.Body.Close()
+				notifyService.Close()
+				exportService.Close()
+				err = httpServer.Stop()
+				require.NoError(t, err)
+				config.Reset()
+			})
+		})
+	}
+}
+
+// seed creates a bare repository with a flags file on main
+func seed(t *testing.T, dir string) string {
+	origin := filepath.Join(dir, "origin.git")
+	work := filepath.Join(dir, "seed")
+
+	git(t, dir, "init", "--quiet", "--bare", "--initial-branch", "main", origin)
+	git(t, dir, "clone", "--quiet", origin, work)
+
+	bs, err := os.ReadFile("../testdata/local_writer/flags.yaml")
+	require.NoError(t, err)
+
+	err = os.WriteFile(filepath.Join(work, "flags.yaml"), bs, 0644)
+	require.NoError(t, err)
+
+	git(t, work, "checkout", "--quiet", "-b", "main")
+	git(t, work, "add", "flags.yaml")
+	git(t, work, "-c", "user.name=test", "-c", "user.email=test@localhost", "commit", "--quiet", "-m", "Add flags")
+	git(t, work, "push", "--quiet", "origin", "main")
+
+	return origin
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	return string(out)
+}
+
+func do(t *testing.T, method, address, path, body string) *http.Response {
+	req, err := http.NewRequest(
+		method,
+		fmt.Sprintf("http://%s%s", address, path),
+		strings.NewReader(body),
+	)
+	require.NoError(t, err)
+
+	req.Header.Set("authorization", fmt.Sprintf("Bearer %s", tok))
+
+	client := &http.Client{}
+
+	rsp, err := client.Do(req)
+	require.NoError(t, err)
+
+	return rsp
+}
@@ -0,0 +1,287 @@
+package httpreader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/apikey"
+	localbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/local"
+	"github.com/w-h-a/flags/internal/server/clients/exporter"
+	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
+	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	httpreader "github.com/w-h-a/flags/internal/server/clients/reader/http"
+	"github.com/w-h-a/flags/internal/server/clients/writer"
+	"github.com/w-h-a/flags/internal/server/clients/writer/noop"
+	"github.com/w-h-a/flags/internal/server/config"
+	"github.com/w-h-a/flags/tests/unit"
+)
+
+const (
+	tok = "mytoken"
+)
+
+func TestHTTPReader(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	type inputs struct {
+		format   string
+		headers  string
+		failures int64
+		path     string
+	}
+
+	type want struct {
+		httpCode int
+		bodyFile string
+	}
+
+	tests := []struct {
+		name   string
+		inputs inputs
+		want   want
+	}{
+		{
+			name: "200 for get all in yaml",
+			inputs: inputs{
+				format:  "yaml",
+				headers: "X-Api-Key: secret, X-Team: flags",
+				path:    "/admin/v1/flags",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/get_flags/valid_response.json",
+			},
+		},
+		{
+			name: "200 for get one in json",
+			inputs: inputs{
+				format:  "json",
+				headers: "X-Api-Key: secret",
+				path:    "/admin/v1/flags/flag2",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/get_flag/valid_response_flag2.json",
+			},
+		},
+		{
+			name: "200 for get all after retries",
+			inputs: inputs{
+				format:   "yaml",
+				headers:  "X-Api-Key: secret",
+				failures: 2,
+				path:     "/admin/v1/flags",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/get_flags/valid_response.json",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		// origin
+		bs, err := unit.Encode(unit.DefaultFlags(), test.inputs.format)
+		require.NoError(t, err)
+
+		failures := &atomic.Int64{}
+		failures.Store(test.inputs.failures)
+
+		origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Api-Key") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			if failures.Add(-1) >= 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			w.Write(bs)
+		}))
+
+		// env vars
+		os.Setenv("API_KEYS", tok)
+		os.Setenv("FLAG_FORMAT", test.inputs.format)
+		os.Setenv("READ_CLIENT_HEADERS", test.inputs.headers)
+
+		// config
+		config.New()
+
+		// clients
+		readClient := httpreader.NewReader(
+			reader.WithLocation(origin.URL),
+			reader.WithFormat(config.FlagFormat()),
+			reader.WithHeaders(config.ReadClientHeaders()),
+			reader.WithTimeout(time.Duration(config.ReadClientTimeout())*time.Second),
+			reader.WithRetries(config.ReadClientRetries()),
+		)
+
+		writeClient := noop.NewWriter(
+			writer.WithLocation(config.WriteClientLocation()),
+		)
+
+		exportClient := localexporter.NewExporter(
+			exporter.WithDir(config.ExportClientDir()),
+		)
+
+		notifyClient := localnotifier.NewNotifier()
+
+		authClient := apikey.NewAuthenticator()
+
+		broadcastClient := localbroadcaster.NewBroadcaster()
+
+		// servers and services
+		httpServer, _, exportService, notifyService, err := server.Factory(
+			writeClient,
+			readClient,
+			exportClient,
+			notifyClient,
+			authClient,
+			broadcastClient,
+		)
+		require.NoError(t, err)
+
+		t.Run(test.name, func(t *testing.T) {
+			err = httpServer.Run()
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(
+				http.MethodGet,
+				fmt.Sprintf("http://%s%s", httpServer.Options().Address, test.inputs.path),
+				nil,
+			)
+			require.NoError(t, err)
+
+			req.Header.Set("authorization", fmt.Sprintf("Bearer %s", tok))
+
+			client := &http.Client{}
+
+			rsp, err := client.Do(req)
+			require.NoError(t, err)
+
+			want, err := os.ReadFile(test.want.bodyFile)
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(rsp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, string(want), string(got))
+
+			require.Equal(t, test.want.httpCode, rsp.StatusCode)
+
+			t.Cleanup(func() {
+				rsp.Body.Close()
+				origin.Close()
+				notifyService.Close()
+				exportService.Close()
+				err = httpServer.Stop()
+				require.NoError(t, err)
+				os.Unsetenv("READ_CLIENT_HEADERS")
+				config.Reset()
+			})
+		})
+	}
+}
+
+func TestHTTPReader_ClientError(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	requests := &atomic.Int64{}
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer origin.Close()
+
+	readClient := httpreader.NewReader(
+		reader.WithLocation(origin.URL),
+		reader.WithRetries(3),
+	)
+
+	_, err := readClient.Read(context.TODO())
+	require.EqualError(t, err, "received status code 401")
+
+	// retrying will not fix a client error
+	require.Equal(t, int64(1), requests.Load())
+}
+
+func TestHTTPReader_NotModified(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	bs, err := unit.Encode(unit.DefaultFlags(), "yaml")
+	require.NoError(t, err)
+
+	etag := `"v1"`
+	lastModified := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat)
+
+	downloads := &atomic.Int64{}
+	conditionals := &atomic.Int64{}
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+
+		if r.Header.Get("If-None-Match") == etag && r.Header.Get("If-Modified-Since") == lastModified {
+			conditionals.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		downloads.Add(1)
+
+		w.Write(bs)
+	}))
+	defer origin.Close()
+
+	readClient := httpreader.NewReader(
+		reader.WithLocation(origin.URL),
+	)
+
+	conditional, ok := readClient.(reader.ConditionalReader)
+	require.True(t, ok)
+
+	first, err := conditional.ReadIfModified(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, bs, first)
+
+	// the cache has not accepted it so it is served again
+	again, err := conditional.ReadIfModified(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, bs, again)
+
+	conditional.Ack(context.TODO())
+
+	// the origin answers 304 so there is nothing to parse
+	_, err = conditional.ReadIfModified(context.TODO())
+	require.ErrorIs(t, err, reader.ErrNotModified)
+
+	// a plain read still has the document
+	second, err := readClient.Read(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, bs, second)
+
+	require.Equal(t, int64(1), downloads.Load())
+	require.Equal(t, int64(3), conditionals.Load())
+}
@@ -0,0 +1,136 @@
+package incrementalrefresh
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	localreader "github.com/w-h-a/flags/internal/server/clients/reader/local"
+	"github.com/w-h-a/flags/internal/server/config"
+	"github.com/w-h-a/flags/internal/server/services/cache"
+	"github.com/w-h-a/flags/tests/unit"
+	"gopkg.in/yaml.v3"
+)
+
+func TestIncrementalRefresh(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	// env vars
+	os.Setenv("FLAG_FORMAT", "yaml")
+
+	// config
+	config.New()
+	defer config.Reset()
+
+	all, err := yaml.Marshal(unit.DefaultFlags())
+	require.NoError(t, err)
+
+	readClient := &changesReader{
+		changes: []*reader.Changes{
+			{Changed: all, Full: true, Cursor: "1"},
+			{Changed: []byte("flag2:\n  variants:\n    default: C\n"), Cursor: "2"},
+			{Cursor: "2"},
+			{Removed: []string{"flag1"}, Cursor: "3"},
+		},
+	}
+
+	cacheService := cache.New(readClient)
+
+	_, first, err := cacheService.RetrieveFlags()
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+
+	// only the changed flag is parsed
+	old, new, err := cacheService.RetrieveFlags()
+	require.NoError(t, err)
+	require.Same(t, old["flag1"], new["flag1"])
+	require.NotSame(t, old["flag2"], new["flag2"])
+
+	flag2, err := cacheService.EvaluateFlag(context.TODO(), "flag2", map[string]any{})
+	require.NoError(t, err)
+	require.Equal(t, "C", flag2.Value)
+
+	// nothing changed
+	old, new, err = cacheService.RetrieveFlags()
+	require.NoError(t, err)
+	require.Equal(t, old, new)
+
+	old, new, err = cacheService.RetrieveFlags()
+	require.NoError(t, err)
+	require.Contains(t, old, "flag1")
+	require.NotContains(t, new, "flag1")
+	require.Contains(t, new, "flag2")
+
+	require.Equal(t, []string{"", "1", "2", "2"}, readClient.cursors)
+}
+
+func TestIncrementalRefresh_SameDocument(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	// env vars
+	os.Setenv("FLAG_FORMAT", "yaml")
+
+	// config
+	config.New()
+	defer config.Reset()
+
+	bs, err := yaml.Marshal(unit.DefaultFlags())
+	require.NoError(t, err)
+
+	location := filepath.Join(t.TempDir(), "flags.yaml")
+
+	err = os.WriteFile(location, bs, 0644)
+	require.NoError(t, err)
+
+	cacheService := cache.New(localreader.NewReader(
+		reader.WithLocation(location),
+		reader.WithFormat(config.FlagFormat()),
+	))
+
+	_, _, err = cacheService.RetrieveFlags()
+	require.NoError(t, err)
+
+	// the same bytes are not parsed again
+	old, new, err := cacheService.RetrieveFlags()
+	require.NoError(t, err)
+	require.Same(t, old["flag1"], new["flag1"])
+
+	err = os.WriteFile(location, append(bs, []byte("\n")...), 0644)
+	require.NoError(t, err)
+
+	old, new, err = cacheService.RetrieveFlags()
+	require.NoError(t, err)
+	require.NotSame(t, old["flag1"], new["flag1"])
+}
+
+// changesReader hands out scripted changes and remembers the cursors it got
+type changesReader struct {
+	changes []*reader.Changes
+	cursors []string
+}
+
+func (r *changesReader) ReadByKey(ctx context.Context, key string) ([]byte, error) {
+	return nil, reader.ErrRecordNotFound
+}
+
+func (r *changesReader) Read(ctx context.Context) ([]byte, error) {
+	return nil, nil
+}
+
+func (r *changesReader) ReadChanges(ctx context.Context, cursor string) (*reader.Changes, error) {
+	r.cursors = append(r.cursors, cursor)
+
+	changes := r.changes[0]
+	r.changes = r.changes[1:]
+
+	return changes, nil
+}
@@ -0,0 +1,280 @@
+package layeredreader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/apikey"
+	localbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/local"
+	"github.com/w-h-a/flags/internal/server/clients/exporter"
+	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
+	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	"github.com/w-h-a/flags/internal/server/clients/reader/layered"
+	localreader "github.com/w-h-a/flags/internal/server/clients/reader/local"
+	"github.com/w-h-a/flags/internal/server/clients/writer"
+	"github.com/w-h-a/flags/internal/server/clients/writer/noop"
+	"github.com/w-h-a/flags/internal/server/config"
+	"github.com/w-h-a/flags/internal/server/services/cache"
+)
+
+const (
+	tok = "mytoken"
+)
+
+func TestLayeredReader(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	type inputs struct {
+		merge  string
+		layers []string
+		path   string
+	}
+
+	type want struct {
+		httpCode int
+		bodyFile string
+	}
+
+	tests := []struct {
+		name   string
+		inputs inputs
+		want   want
+	}{
+		{
+			name: "200 for get all with whole flags from later layers",
+			inputs: inputs{
+				merge:  "flag",
+				layers: []string{"base.yaml", "override_flag.yaml"},
+				path:   "/admin/v1/flags",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/layered_reader/flag_response.json",
+			},
+		},
+		{
+			name: "200 for get all with fields from later layers",
+			inputs: inputs{
+				merge:  "field",
+				layers: []string{"base.yaml", "override.yaml", "developer.yaml"},
+				path:   "/admin/v1/flags",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/layered_reader/field_response.json",
+			},
+		},
+		{
+			name: "200 for get one with fields from later layers",
+			inputs: inputs{
+				merge:  "field",
+				layers: []string{"base.yaml", "override.yaml"},
+				path:   "/admin/v1/flags/flag2",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/layered_reader/field_flag2_response.json",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		// env vars
+		os.Setenv("API_KEYS", tok)
+		os.Setenv("FLAG_FORMAT", "yaml")
+
+		// config
+		config.New()
+
+		// clients
+		opts := []reader.Option{
+			reader.WithFormat(config.FlagFormat()),
+			layered.WithMerge(test.inputs.merge),
+		}
+
+		for _, layer := range test.inputs.layers {
+			opts = append(opts, layered.WithLayer(strings.TrimSuffix(layer, ".yaml"), localreader.NewReader(
+				reader.WithLocation(filepath.Join("../testdata/layered_reader", layer)),
+				reader.WithFormat(config.FlagFormat()),
+			)))
+		}
+
+		readClient := layered.NewReader(opts...)
+
+		writeClient := noop.NewWriter(
+			writer.WithLocation(config.WriteClientLocation()),
+		)
+
+		exportClient := localexporter.NewExporter(
+			exporter.WithDir(config.ExportClientDir()),
+		)
+
+		notifyClient := localnotifier.NewNotifier()
+
+		authClient := apikey.NewAuthenticator()
+
+		broadcastClient := localbroadcaster.NewBroadcaster()
+
+		// servers and services
+		httpServer, _, exportService, notifyService, err := server.Factory(
+			writeClient,
+			readClient,
+			exportClient,
+			notifyClient,
+			authClient,
+			broadcastClient,
+		)
+		require.NoError(t, err)
+
+		t.Run(test.name, func(t *testing.T) {
+			err = httpServer.Run()
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(
+				http.MethodGet,
+				fmt.Sprintf("http://%s%s", httpServer.Options().Address, test.inputs.path),
+				nil,
+			)
+			require.NoError(t, err)
+
+			req.Header.Set("authorization", fmt.Sprintf("Bearer %s", tok))
+
+			client := &http.Client{}
+
+			rsp, err := client.Do(req)
+			require.NoError(t, err)
+
+			want, err := os.ReadFile(test.want.bodyFile)
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(rsp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, string(want), string(got))
+
+			require.Equal(t, test.want.httpCode, rsp.StatusCode)
+
+			t.Cleanup(func() {
+				rsp.Body.Close()
+				notifyService.Close()
+				exportService.Close()
+				err = httpServer.Stop()
+				require.NoError(t, err)
+				config.Reset()
+			})
+		})
+	}
+}
+
+func TestLayeredReader_Diff(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	config.New()
+	defer config.Reset()
+
+	dir := t.TempDir()
+
+	base, err := os.ReadFile("../testdata/layered_reader/base.yaml")
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "base.yaml"), base, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "override.yaml"), []byte{}, 0644))
+
+	readClient := layered.NewReader(
+		layered.WithMerge("field"),
+		layered.WithLayer("git", localreader.NewReader(
+			reader.WithLocation(filepath.Join(dir, "base.yaml")),
+		)),
+		layered.WithLayer("postgres", localreader.NewReader(
+			reader.WithLocation(filepath.Join(dir, "override.yaml")),
+		)),
+	)
+
+	cacheService := cache.New(readClient)
+
+	_, _, err = cacheService.RetrieveFlags()
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "override.yaml"), []byte("flag1:\n  disabled: true\n"), 0644))
+
+	old, new, err := cacheService.RetrieveFlags()
+	require.NoError(t, err)
+
+	diff := flags.NewDiff(old, new)
+
+	updated, ok := diff.Updated["flag1"]
+	require.True(t, ok)
+	require.Equal(t, []string{"git"}, updated.Before.Sources)
+	require.Equal(t, []string{"git", "postgres"}, updated.After.Sources)
+
+	state, err := cacheService.EvaluateFlag(context.TODO(), "flag1", map[string]any{})
+	require.NoError(t, err)
+	require.Equal(t, flags.ReasonDisabled, state.Reason)
+}
+
+func TestLayeredReader_Watch(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	dir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "base.yaml"), []byte{}, 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "override"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "override", "flags.yaml"), []byte{}, 0644))
+
+	readClient := layered.NewReader(
+		layered.WithLayer("base", localreader.NewReader(
+			reader.WithLocation(filepath.Join(dir, "base.yaml")),
+		)),
+		layered.WithLayer("override", localreader.NewReader(
+			reader.WithLocation(filepath.Join(dir, "override", "flags.yaml")),
+		)),
+	)
+
+	watcher, ok := readClient.(reader.Watcher)
+	require.True(t, ok)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	changes, err := watcher.Watch(ctx)
+	require.NoError(t, err)
+
+	// a change in any layer is a change
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "override", "flags.yaml"), []byte("flag1:\n  disabled: true\n"), 0644))
+
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("change was not reported")
+	}
+
+	cancel()
+
+	select {
+	case _, ok := <-changes:
+		for ok {
+			_, ok = <-changes
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch was not closed")
+	}
+}
@@ -0,0 +1,264 @@
+package lenientloading
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/apikey"
+	localbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/local"
+	"github.com/w-h-a/flags/internal/server/clients/exporter"
+	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
+	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
+	mocknotifier "github.com/w-h-a/flags/internal/server/clients/notifier/mock"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	localreader "github.com/w-h-a/flags/internal/server/clients/reader/local"
+	"github.com/w-h-a/flags/internal/server/clients/writer"
+	"github.com/w-h-a/flags/internal/server/clients/writer/noop"
+	"github.com/w-h-a/flags/internal/server/config"
+	"github.com/w-h-a/flags/internal/server/services/cache"
+	"github.com/w-h-a/flags/internal/server/services/notify"
+)
+
+const (
+	tok = "mytoken"
+)
+
+func TestLenientLoading(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	type inputs struct {
+		method string
+		path   string
+		body   string
+	}
+
+	type want struct {
+		httpCode int
+		bodyFile string
+	}
+
+	tests := []struct {
+		name   string
+		inputs inputs
+		want   want
+	}{
+		{
+			name: "200 for a valid flag next to an invalid one",
+			inputs: inputs{
+				method: http.MethodPost,
+				path:   "/ofrep/v1/evaluate/flags/flag2",
+				body:   `{"context":{}}`,
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/lenient_loading/valid_response.json",
+			},
+		},
+		{
+			name: "400 for an invalid flag",
+			inputs: inputs{
+				method: http.MethodPost,
+				path:   "/ofrep/v1/evaluate/flags/flag3",
+				body:   `{"context":{}}`,
+			},
+			want: want{
+				httpCode: http.StatusBadRequest,
+				bodyFile: "../testdata/lenient_loading/parse_error_response.json",
+			},
+		},
+		{
+			name: "200 for all flags with the invalid one as an error",
+			inputs: inputs{
+				method: http.MethodPost,
+				path:   "/ofrep/v1/evaluate/flags",
+				body:   `{"context":{}}`,
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/lenient_loading/all_response.json",
+			},
+		},
+		{
+			name: "200 for get all without the invalid flag",
+			inputs: inputs{
+				method: http.MethodGet,
+				path:   "/admin/v1/flags",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/lenient_loading/get_all_response.json",
+			},
+		},
+		{
+			name: "200 for the load errors",
+			inputs: inputs{
+				method: http.MethodGet,
+				path:   "/admin/v1/load-errors",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/lenient_loading/load_errors_response.json",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		// env vars
+		os.Setenv("API_KEYS", tok)
+		os.Setenv("FLAG_FORMAT", "yaml")
+		os.Setenv("LENIENT_LOADING", "true")
+
+		// config
+		config.New()
+
+		// clients
+		readClient := localreader.NewReader(
+			reader.WithLocation("../testdata/lenient_loading/flags.yaml"),
+			reader.WithFormat(config.FlagFormat()),
+		)
+
+		writeClient := noop.NewWriter(
+			writer.WithLocation(config.WriteClientLocation()),
+		)
+
+		exportClient := localexporter.NewExporter(
+			exporter.WithDir(config.ExportClientDir()),
+		)
+
+		notifyClient := localnotifier.NewNotifier()
+
+		authClient := apikey.NewAuthenticator()
+
+		broadcastClient := localbroadcaster.NewBroadcaster()
+
+		// servers and services
+		httpServer, _, exportService, notifyService, err := server.Factory(
+			writeClient,
+			readClient,
+			exportClient,
+			notifyClient,
+			authClient,
+			broadcastClient,
+		)
+		require.NoError(t, err)
+
+		t.Run(test.name, func(t *testing.T) {
+			err = httpServer.Run()
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(
+				test.inputs.method,
+				fmt.Sprintf("http://%s%s", httpServer.Options().Address, test.inputs.path),
+				strings.NewReader(test.inputs.body),
+			)
+			require.NoError(t, err)
+
+			req.Header.Set("authorization", fmt.Sprintf("Bearer %s", tok))
+
+			client := &http.Client{}
+
+			rsp, err := client.Do(req)
+			require.NoError(t, err)
+
+			want, err := os.ReadFile(test.want.bodyFile)
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(rsp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, string(want), string(got))
+
+			require.Equal(t, test.want.httpCode, rsp.StatusCode)
+
+			t.Cleanup(func() {
+				rsp.Body.Close()
+				notifyService.Close()
+				exportService.Close()
+				err = httpServer.Stop()
+				require.NoError(t, err)
+				os.Unsetenv("LENIENT_LOADING")
+				config.Reset()
+			})
+		})
+	}
+}
+
+func TestLenientLoading_LastGood(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	os.Setenv("LENIENT_LOADING", "true")
+	defer os.Unsetenv("LENIENT_LOADING")
+
+	config.New()
+	defer config.Reset()
+
+	location := filepath.Join(t.TempDir(), "flags.yaml")
+
+	require.NoError(t, os.WriteFile(location, []byte("flag1:\n  disabled: false\n  variants:\n    default: A\nflag2:\n  disabled: false\n  variants:\n    default: A\n"), 0644))
+
+	cacheService := cache.New(localreader.NewReader(
+		reader.WithLocation(location),
+	))
+
+	notifyClient := mocknotifier.NewNotifier()
+
+	notifyService := notify.New(notifyClient)
+
+	_, _, err := cacheService.RetrieveFlags()
+	require.NoError(t, err)
+
+	// a typo in one flag does not hold back the other
+	require.NoError(t, os.WriteFile(location, []byte("flag1:\n  disabled: false\n  variants:\n    defualt: B\nflag2:\n  disabled: false\n  variants:\n    default: B\n"), 0644))
+
+	old, new, err := cacheService.RetrieveFlags()
+	require.NoError(t, err)
+
+	notifyService.Notify(old, new)
+	notifyService.NotifyLoadErrors(cacheService.LoadErrors())
+	notifyService.Close()
+
+	flag1, err := cacheService.EvaluateFlag(context.TODO(), "flag1", map[string]any{})
+	require.NoError(t, err)
+	require.Equal(t, "A", flag1.Value)
+
+	flag2, err := cacheService.EvaluateFlag(context.TODO(), "flag2", map[string]any{})
+	require.NoError(t, err)
+	require.Equal(t, "B", flag2.Value)
+
+	n := notifyClient.(*mocknotifier.Client)
+
+	failed := map[string][]*flags.ValidationError{}
+
+	for _, diff := range n.Diffs() {
+		for k, v := range diff.Failed {
+			failed[k] = v
+		}
+	}
+
+	require.Equal(t, map[string][]*flags.ValidationError{
+		"flag1": {{Path: "/flag1/variants", Message: "flag missing default variant"}},
+	}, failed)
+
+	// strict loading keeps everything back
+	os.Setenv("LENIENT_LOADING", "false")
+	config.Reset()
+	config.New()
+
+	_, _, err = cacheService.RetrieveFlags()
+	require.EqualError(t, err, "flag missing default variant")
+}
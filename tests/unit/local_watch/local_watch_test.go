@@ -0,0 +1,240 @@
+package localwatch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server"
+	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	localreader "github.com/w-h-a/flags/internal/server/clients/reader/local"
+	"github.com/w-h-a/flags/internal/server/config"
+	"github.com/w-h-a/flags/internal/server/services/cache"
+	"github.com/w-h-a/flags/internal/server/services/notify"
+)
+
+const (
+	before = "flag1:\n  disabled: false\n  variants:\n    default: A\n"
+	after  = "flag1:\n  disabled: false\n  variants:\n    default: B\n"
+)
+
+func TestLocalWatch(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	type inputs struct {
+		setup  func(t *testing.T, dir string) string
+		change func(t *testing.T, dir string)
+	}
+
+	tests := []struct {
+		name   string
+		inputs inputs
+	}{
+		{
+			name: "write in place",
+			inputs: inputs{
+				setup: func(t *testing.T, dir string) string {
+					require.NoError(t, os.WriteFile(filepath.Join(dir, "flags.yaml"), []byte(before), 0644))
+					return filepath.Join(dir, "flags.yaml")
+				},
+				change: func(t *testing.T, dir string) {
+					require.NoError(t, os.WriteFile(filepath.Join(dir, "flags.yaml"), []byte(after), 0644))
+				},
+			},
+		},
+		{
+			name: "atomic rename",
+			inputs: inputs{
+				setup: func(t *testing.T, dir string) string {
+					require.NoError(t, os.WriteFile(filepath.Join(dir, "flags.yaml"), []byte(before), 0644))
+					return filepath.Join(dir, "flags.yaml")
+				},
+				change: func(t *testing.T, dir string) {
+					require.NoError(t, os.WriteFile(filepath.Join(dir, ".flags.yaml.swp"), []byte(after), 0644))
+					require.NoError(t, os.Rename(filepath.Join(dir, ".flags.yaml.swp"), filepath.Join(dir, "flags.yaml")))
+				},
+			},
+		},
+		{
+			name: "config map symlink swap",
+			inputs: inputs{
+				setup: func(t *testing.T, dir string) string {
+					require.NoError(t, os.Mkdir(filepath.Join(dir, "..v1"), 0755))
+					require.NoError(t, os.WriteFile(filepath.Join(dir, "..v1", "flags.yaml"), []byte(before), 0644))
+					require.NoError(t, os.Symlink("..v1", filepath.Join(dir, "..data")))
+					require.NoError(t, os.Symlink(filepath.Join("..data", "flags.yaml"), filepath.Join(dir, "flags.yaml")))
+					return filepath.Join(dir, "flags.yaml")
+				},
+				change: func(t *testing.T, dir string) {
+					require.NoError(t, os.Mkdir(filepath.Join(dir, "..v2"), 0755))
+					require.NoError(t, os.WriteFile(filepath.Join(dir, "..v2", "flags.yaml"), []byte(after), 0644))
+					require.NoError(t, os.Symlink("..v2", filepath.Join(dir, "..data_tmp")))
+					require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+					require.NoError(t, os.RemoveAll(filepath.Join(dir, "..v1")))
+				},
+			},
+		},
+		{
+			name: "glob into subdirectories",
+			inputs: inputs{
+				setup: func(t *testing.T, dir string) string {
+					require.NoError(t, os.Mkdir(filepath.Join(dir, "prod"), 0755))
+					require.NoError(t, os.WriteFile(filepath.Join(dir, "prod", "flags.yaml"), []byte(before), 0644))
+					return filepath.Join(dir, "*", "flags.yaml")
+				},
+				change: func(t *testing.T, dir string) {
+					require.NoError(t, os.WriteFile(filepath.Join(dir, "prod", "flags.yaml"), []byte(after), 0644))
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			location := test.inputs.setup(t, dir)
+
+			readClient := localreader.NewReader(
+				reader.WithLocation(location),
+			)
+
+			watcher, ok := readClient.(reader.Watcher)
+			require.True(t, ok)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			changes, err := watcher.Watch(ctx)
+			require.NoError(t, err)
+
+			test.inputs.change(t, dir)
+
+			select {
+			case <-changes:
+			case <-time.After(5 * time.Second):
+				t.Fatal("change was not reported")
+			}
+
+			bs, err := readClient.Read(context.TODO())
+			require.NoError(t, err)
+
+			got, err := flags.Factory(bs, "yaml")
+			require.NoError(t, err)
+
+			want, err := flags.Factory([]byte(after), "yaml")
+			require.NoError(t, err)
+
+			require.Equal(t, want, got)
+
+			// bursts of writes are reported once
+			select {
+			case <-changes:
+				t.Fatal("change was reported more than once")
+			case <-time.After(300 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestLocalWatch_NewDirectory(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	dir := t.TempDir()
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "prod"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "prod", "flags.yaml"), []byte(before), 0644))
+
+	readClient := localreader.NewReader(
+		reader.WithLocation(filepath.Join(dir, "*", "flags.yaml")),
+	)
+
+	watcher, ok := readClient.(reader.Watcher)
+	require.True(t, ok)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, err := watcher.Watch(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "staging"), 0755))
+
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("new directory was not reported")
+	}
+
+	// files in the new directory are watched too
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "staging", "flags.yaml"), []byte(after), 0644))
+
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("change in the new directory was not reported")
+	}
+}
+
+func TestLocalWatch_UpdateCache(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	config.New()
+	defer config.Reset()
+
+	location := filepath.Join(t.TempDir(), "flags.yaml")
+
+	require.NoError(t, os.WriteFile(location, []byte(before), 0644))
+
+	readClient := localreader.NewReader(
+		reader.WithLocation(location),
+	)
+
+	cacheService := cache.New(readClient)
+
+	_, _, err := cacheService.RetrieveFlags()
+	require.NoError(t, err)
+
+	notifyClient := localnotifier.NewNotifier()
+
+	notifyService := notify.New(notifyClient)
+
+	stop := make(chan struct{})
+
+	done := make(chan error)
+
+	// the interval is far too long to be what refreshes the cache
+	go func() {
+		done <- server.UpdateCache(cacheService, notifyService, stop, time.Hour)
+	}()
+
+	// give the watch a moment to start
+	time.Sleep(100 * time.Millisecond)
+
+	for range 3 {
+		require.NoError(t, os.WriteFile(location, []byte(after), 0644))
+	}
+
+	require.Eventually(t, func() bool {
+		state, err := cacheService.EvaluateFlag(context.TODO(), "flag1", map[string]any{})
+		return err == nil && state.Value == "B" && state.Reason == flags.ReasonDefault
+	}, 5*time.Second, 50*time.Millisecond)
+
+	close(stop)
+
+	require.NoError(t, <-done)
+}
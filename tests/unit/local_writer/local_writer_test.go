@@ -0,0 +1,262 @@
+package localwriter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/apikey"
+	localbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/local"
+	"github.com/w-h-a/flags/internal/server/clients/exporter"
+	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
+	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	localreader "github.com/w-h-a/flags/internal/server/clients/reader/local"
+	"github.com/w-h-a/flags/internal/server/clients/writer"
+	localwriter "github.com/w-h-a/flags/internal/server/clients/writer/local"
+	"github.com/w-h-a/flags/internal/server/config"
+)
+
+const (
+	tok = "mytoken"
+)
+
+func TestLocalWriter(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	type inputs struct {
+		format   string
+		filePath string
+		body     string
+	}
+
+	type want struct {
+		httpCode int
+		filePath string
+	}
+
+	tests := []struct {
+		name   string
+		inputs inputs
+		want   want
+	}{
+		{
+			name: "yaml update keeps order and comments",
+			inputs: inputs{
+				format:   "yaml",
+				filePath: "../testdata/local_writer/flags.yaml",
+				body:     `{"flag1":{"disabled":true,"variants":{"default":"A","variant2":"B"}}}`,
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				filePath: "../testdata/local_writer/updated.yaml",
+			},
+		},
+		{
+			name: "yaml create appends",
+			inputs: inputs{
+				format:   "yaml",
+				filePath: "../testdata/local_writer/flags.yaml",
+				body:     `{"flag3":{"disabled":false,"variants":{"default":"C"}}}`,
+			},
+			want: want{
+				httpCode: http.StatusCreated,
+				filePath: "../testdata/local_writer/created.yaml",
+			},
+		},
+		{
+			name: "json update keeps order",
+			inputs: inputs{
+				format:   "json",
+				filePath: "../testdata/local_writer/flags.json",
+				body:     `{"flag2":{"disabled":true,"variants":{"default":"A","variant2":"B"}}}`,
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				filePath: "../testdata/local_writer/updated.json",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		// flags file
+		dir := t.TempDir()
+
+		bs, err := os.ReadFile(test.inputs.filePath)
+		require.NoError(t, err)
+
+		location := filepath.Join(dir, filepath.Base(test.inputs.filePath))
+
+		err = os.WriteFile(location, bs, 0644)
+		require.NoError(t, err)
+
+		// env vars
+		os.Setenv("API_KEYS", tok)
+		os.Setenv("FLAG_FORMAT", test.inputs.format)
+
+		// config
+		config.New()
+
+		// clients
+		readClient := localreader.NewReader(
+			reader.WithLocation(location),
+			reader.WithFormat(config.FlagFormat()),
+		)
+
+		writeClient := localwriter.NewWriter(
+			writer.WithLocation(location),
+			writer.WithFormat(config.FlagFormat()),
+		)
+
+		exportClient := localexporter.NewExporter(
+			exporter.WithDir(config.ExportClientDir()),
+		)
+
+		notifyClient := localnotifier.NewNotifier()
+
+		authClient := apikey.NewAuthenticator()
+
+		broadcastClient := localbroadcaster.NewBroadcaster()
+
+		// servers and services
+		httpServer, _, exportService, notifyService, err := server.Factory(
+			writeClient,
+			readClient,
+			exportClient,
+			notifyClient,
+			authClient,
+			broadcastClient,
+		)
+		require.NoError(t, err)
+
+		t.Run(test.name, func(t *testing.T) {
+			err = httpServer.Run()
+			require.NoError(t, err)
+
+			rsp := put(t, httpServer.Options().Address, test.inputs.body)
+
+			require.Equal(t, test.want.httpCode, rsp.StatusCode)
+
+			want, err := os.ReadFile(test.want.filePath)
+			require.NoError(t, err)
+
+			got, err := os.ReadFile(location)
+			require.NoError(t, err)
+
+			require.Equal(t, string(want), string(got))
+
+			t.Cleanup(func() {
+				rsp.Body.Close()
+				notifyService.Close()
+				exportService.Close()
+				err = httpServer.Stop()
+				require.NoError(t, err)
+				config.Reset()
+			})
+		})
+	}
+}
+
+func TestLocalWriter_Concurrent(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	// flags file
+	dir := t.TempDir()
+
+	bs, err := os.ReadFile("../testdata/local_writer/flags.yaml")
+	require.NoError(t, err)
+
+	location := filepath.Join(dir, "flags.yaml")
+
+	err = os.WriteFile(location, bs, 0644)
+	require.NoError(t, err)
+
+	// env vars
+	os.Setenv("API_KEYS", tok)
+	os.Setenv("FLAG_FORMAT", "yaml")
+
+	// config
+	config.New()
+
+	// clients
+	readClient := localreader.NewReader(
+		reader.WithLocation(location),
+		reader.WithFormat(config.FlagFormat()),
+	)
+
+	// two writers on one file stand in for two processes
+	writeClients := []writer.Writer{
+		localwriter.NewWriter(
+			writer.WithLocation(location),
+			writer.WithFormat(config.FlagFormat()),
+		),
+		localwriter.NewWriter(
+			writer.WithLocation(location),
+			writer.WithFormat(config.FlagFormat()),
+		),
+	}
+
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			key := fmt.Sprintf("flag%d", i+3)
+			doc := fmt.Sprintf("%s:\n  variants:\n    default: %d\n", key, i)
+
+			err := writeClients[i%2].Write(context.TODO(), key, []byte(doc))
+			require.NoError(t, err)
+		}(i)
+	}
+
+	wg.Wait()
+
+	doc, err := readClient.Read(context.TODO())
+	require.NoError(t, err)
+
+	got, err := flags.Factory(doc, config.FlagFormat())
+	require.NoError(t, err)
+
+	require.Equal(t, 22, len(got))
+
+	config.Reset()
+}
+
+func put(t *testing.T, address, body string) *http.Response {
+	req, err := http.NewRequest(
+		http.MethodPut,
+		fmt.Sprintf("http://%s%s", address, "/admin/v1/flags"),
+		strings.NewReader(body),
+	)
+	require.NoError(t, err)
+
+	req.Header.Set("authorization", fmt.Sprintf("Bearer %s", tok))
+
+	client := &http.Client{}
+
+	rsp, err := client.Do(req)
+	require.NoError(t, err)
+
+	_, err = io.Copy(io.Discard, rsp.Body)
+	require.NoError(t, err)
+
+	return rsp
+}
@@ -0,0 +1,309 @@
+package multifile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/apikey"
+	localbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/local"
+	"github.com/w-h-a/flags/internal/server/clients/exporter"
+	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
+	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	"github.com/w-h-a/flags/internal/server/clients/reader/github"
+	"github.com/w-h-a/flags/internal/server/clients/reader/gitlab"
+	localreader "github.com/w-h-a/flags/internal/server/clients/reader/local"
+	"github.com/w-h-a/flags/internal/server/clients/writer"
+	"github.com/w-h-a/flags/internal/server/clients/writer/noop"
+	"github.com/w-h-a/flags/internal/server/config"
+)
+
+const (
+	tok = "mytoken"
+)
+
+func TestMultiFile(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	type inputs struct {
+		format   string
+		location string
+		path     string
+	}
+
+	type want struct {
+		httpCode int
+		bodyFile string
+	}
+
+	tests := []struct {
+		name   string
+		inputs inputs
+		want   want
+	}{
+		{
+			name: "200 for get all from a directory",
+			inputs: inputs{
+				format:   "yaml",
+				location: "../testdata/multi_file/flags",
+				path:     "/admin/v1/flags",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/get_flags/valid_response.json",
+			},
+		},
+		{
+			name: "200 for get one from a glob",
+			inputs: inputs{
+				format:   "json",
+				location: "../testdata/multi_file/flags/team-*",
+				path:     "/admin/v1/flags/flag2",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/get_flag/valid_response_flag2.json",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		// env vars
+		os.Setenv("API_KEYS", tok)
+		os.Setenv("FLAG_FORMAT", test.inputs.format)
+		os.Setenv("READ_CLIENT_LOCATION", test.inputs.location)
+
+		// config
+		config.New()
+
+		// clients
+		readClient := localreader.NewReader(
+			reader.WithLocation(config.ReadClientLocation()),
+			reader.WithFormat(config.FlagFormat()),
+		)
+
+		writeClient := noop.NewWriter(
+			writer.WithLocation(config.WriteClientLocation()),
+		)
+
+		exportClient := localexporter.NewExporter(
+			exporter.WithDir(config.ExportClientDir()),
+		)
+
+		notifyClient := localnotifier.NewNotifier()
+
+		authClient := apikey.NewAuthenticator()
+
+		broadcastClient := localbroadcaster.NewBroadcaster()
+
+		// servers and services
+		httpServer, _, exportService, notifyService, err := server.Factory(
+			writeClient,
+			readClient,
+			exportClient,
+			notifyClient,
+			authClient,
+			broadcastClient,
+		)
+		require.NoError(t, err)
+
+		t.Run(test.name, func(t *testing.T) {
+			err = httpServer.Run()
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(
+				http.MethodGet,
+				fmt.Sprintf("http://%s%s", httpServer.Options().Address, test.inputs.path),
+				nil,
+			)
+			require.NoError(t, err)
+
+			req.Header.Set("authorization", fmt.Sprintf("Bearer %s", tok))
+
+			client := &http.Client{}
+
+			rsp, err := client.Do(req)
+			require.NoError(t, err)
+
+			want, err := os.ReadFile(test.want.bodyFile)
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(rsp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, string(want), string(got))
+
+			require.Equal(t, test.want.httpCode, rsp.StatusCode)
+
+			t.Cleanup(func() {
+				rsp.Body.Close()
+				notifyService.Close()
+				exportService.Close()
+				err = httpServer.Stop()
+				require.NoError(t, err)
+				os.Unsetenv("READ_CLIENT_LOCATION")
+				config.Reset()
+			})
+		})
+	}
+}
+
+func TestMultiFile_Duplicate(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	dir := "../testdata/multi_file/duplicate"
+
+	readClient := localreader.NewReader(
+		reader.WithLocation(dir),
+	)
+
+	_, err := readClient.Read(context.TODO())
+	require.EqualError(t, err, fmt.Sprintf(
+		"flag %q is defined in both %s and %s",
+		"flag1",
+		filepath.Join(dir, "a.yaml"),
+		filepath.Join(dir, "b.yml"),
+	))
+}
+
+func TestMultiFile_GitHub(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	var origin *httptest.Server
+
+	origin = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "main", r.URL.Query().Get("ref"))
+
+		switch r.URL.Path {
+		case "/repos/acme/flags/contents/flags":
+			listing := []map[string]string{}
+
+			for _, name := range []string{"README.md", "team-a.yaml", "team-b.json"} {
+				listing = append(listing, map[string]string{
+					"name": name,
+					"path": "flags/" + name,
+					"type": "file",
+					"url":  fmt.Sprintf("%s/repos/acme/flags/contents/flags/%s?ref=main", origin.URL, name),
+				})
+			}
+
+			listing = append(listing, map[string]string{"name": "archive", "path": "flags/archive", "type": "dir"})
+
+			json.NewEncoder(w).Encode(listing)
+		default:
+			bs, err := os.ReadFile(filepath.Join("../testdata/multi_file", strings.TrimPrefix(r.URL.Path, "/repos/acme/flags/contents")))
+			if err != nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			w.Write(bs)
+		}
+	}))
+	defer origin.Close()
+
+	for _, location := range []string{
+		origin.URL + "/repos/acme/flags/contents/flags?ref=main",
+		origin.URL + "/repos/acme/flags/contents/flags/team-*?ref=main",
+	} {
+		readClient := github.NewReader(
+			reader.WithLocation(location),
+			reader.WithFormat("yaml"),
+		)
+
+		bs, err := readClient.Read(context.TODO())
+		require.NoError(t, err)
+
+		got, err := flags.Factory(bs, "yaml")
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+	}
+}
+
+func TestMultiFile_GitLab(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "main", r.URL.Query().Get("ref"))
+
+		prefix := "/api/v4/projects/7/repository"
+
+		switch {
+		case r.URL.Path == prefix+"/tree":
+			require.Equal(t, "flags", r.URL.Query().Get("path"))
+
+			// one file per page
+			pages := []map[string]string{
+				{"name": "team-a.yaml", "path": "flags/team-a.yaml", "type": "blob"},
+				{"name": "README.md", "path": "flags/README.md", "type": "blob"},
+				{"name": "team-b.json", "path": "flags/team-b.json", "type": "blob"},
+			}
+
+			page := 1
+			fmt.Sscanf(r.URL.Query().Get("page"), "%d", &page)
+
+			if page < len(pages) {
+				w.Header().Set("x-next-page", fmt.Sprint(page+1))
+			}
+
+			json.NewEncoder(w).Encode([]map[string]string{pages[page-1]})
+		case strings.HasPrefix(r.URL.Path, prefix+"/files/"):
+			file := strings.TrimSuffix(strings.TrimPrefix(r.URL.EscapedPath(), prefix+"/files/"), "/raw")
+
+			name, err := url.PathUnescape(file)
+			require.NoError(t, err)
+
+			bs, err := os.ReadFile(filepath.Join("../testdata/multi_file", name))
+			if err != nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			w.Write(bs)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer origin.Close()
+
+	for _, location := range []string{
+		origin.URL + "/api/v4/projects/7/repository/tree?path=flags&ref=main",
+		origin.URL + "/api/v4/projects/7/repository/tree?path=flags/team-*&ref=main",
+	} {
+		readClient := gitlab.NewReader(
+			reader.WithLocation(location),
+			reader.WithFormat("json"),
+		)
+
+		bs, err := readClient.Read(context.TODO())
+		require.NoError(t, err)
+
+		got, err := flags.Factory(bs, "json")
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+	}
+}
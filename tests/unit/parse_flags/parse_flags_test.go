@@ -129,6 +129,48 @@ func TestParseFlags(t *testing.T) {
 			wantErr:  true,
 			err:      "rule includes unknown variant",
 		},
+		{
+			name:     "invalid query rule yaml",
+			filePath: "../testdata/parse_flags/invalid_query_rule.yaml",
+			format:   "yaml",
+			wantErr:  true,
+			err:      "rule includes invalid query: unexpected input at position 24",
+		},
+		{
+			name:     "invalid query rule json",
+			filePath: "../testdata/parse_flags/invalid_query_rule.json",
+			format:   "json",
+			wantErr:  true,
+			err:      "rule includes invalid query: 1:15 mismatched input '<EOF>' expecting SP",
+		},
+		{
+			name:     "unknown variant override yaml",
+			filePath: "../testdata/parse_flags/unknown_variant_override.yaml",
+			format:   "yaml",
+			wantErr:  true,
+			err:      "environment override for unknown variant",
+		},
+		{
+			name:     "unknown variant override json",
+			filePath: "../testdata/parse_flags/unknown_variant_override.json",
+			format:   "json",
+			wantErr:  true,
+			err:      "environment override for unknown variant",
+		},
+		{
+			name:     "different type override yaml",
+			filePath: "../testdata/parse_flags/different_type_override.yaml",
+			format:   "yaml",
+			wantErr:  true,
+			err:      "environment override changes the variant type",
+		},
+		{
+			name:     "unknown variant override rule yaml",
+			filePath: "../testdata/parse_flags/unknown_variant_override_rule.yaml",
+			format:   "yaml",
+			wantErr:  true,
+			err:      "rule includes unknown variant",
+		},
 	}
 
 	for _, test := range tests {
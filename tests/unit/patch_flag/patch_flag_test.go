@@ -0,0 +1,246 @@
+package patchflag
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/apikey"
+	localbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/local"
+	"github.com/w-h-a/flags/internal/server/clients/exporter"
+	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
+	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
+	"github.com/w-h-a/flags/internal/server/clients/writereader"
+	mockwritereader "github.com/w-h-a/flags/internal/server/clients/writereader/mock"
+	"github.com/w-h-a/flags/internal/server/config"
+	"github.com/w-h-a/flags/tests/unit"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	tok            = "mytoken"
+	jsonPatchType  = "application/json-patch+json"
+	mergePatchType = "application/merge-patch+json"
+)
+
+func TestPatchFlag(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	type inputs struct {
+		flags       map[string]*flags.Flag
+		key         string
+		contentType string
+		patch       string
+	}
+
+	type want struct {
+		httpCode int
+		bodyFile string
+	}
+
+	tests := []struct {
+		name   string
+		inputs inputs
+		want   want
+	}{
+		{
+			name: "200 for json patch adding a query",
+			inputs: inputs{
+				flags:       unit.DefaultFlags(),
+				key:         "flag2",
+				contentType: jsonPatchType,
+				patch:       `[{"op":"add","path":"/rules/0/query","value":"plan eq \"enterprise\""}]`,
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/patch_flag/valid_response_query.json",
+			},
+		},
+		{
+			name: "200 for json patch adding a variant",
+			inputs: inputs{
+				flags:       unit.DefaultFlags(),
+				key:         "flag2",
+				contentType: jsonPatchType,
+				patch:       `[{"op":"add","path":"/variants/variant3","value":"C"},{"op":"replace","path":"/rules/0/variant","value":"variant3"}]`,
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/upsert_flag/valid_response_updated.json",
+			},
+		},
+		{
+			name: "409 for failed json patch test",
+			inputs: inputs{
+				flags:       unit.DefaultFlags(),
+				key:         "flag2",
+				contentType: jsonPatchType,
+				patch:       `[{"op":"test","path":"/disabled","value":true},{"op":"replace","path":"/disabled","value":false}]`,
+			},
+			want: want{
+				httpCode: http.StatusConflict,
+				bodyFile: "../testdata/patch_flag/test_failed.json",
+			},
+		},
+		{
+			name: "400 for json patch producing an invalid flag",
+			inputs: inputs{
+				flags:       unit.DefaultFlags(),
+				key:         "flag2",
+				contentType: jsonPatchType,
+				patch:       `[{"op":"remove","path":"/variants/default"}]`,
+			},
+			want: want{
+				httpCode: http.StatusBadRequest,
+				bodyFile: "../testdata/patch_flag/missing_default.json",
+			},
+		},
+		{
+			name: "400 for malformed json patch",
+			inputs: inputs{
+				flags:       unit.DefaultFlags(),
+				key:         "flag2",
+				contentType: jsonPatchType,
+				patch:       `{"op":"remove","path":"/disabled"}`,
+			},
+			want: want{
+				httpCode: http.StatusBadRequest,
+				bodyFile: "../testdata/patch_flag/malformed_json_patch.json",
+			},
+		},
+		{
+			name: "200 for merge patch",
+			inputs: inputs{
+				flags:       unit.DefaultFlags(),
+				key:         "flag2",
+				contentType: mergePatchType,
+				patch:       `{"disabled":true,"variants":{"variant3":"C"},"rules":null}`,
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/patch_flag/valid_response_merge.json",
+			},
+		},
+		{
+			name: "400 for merge patch with an invalid query",
+			inputs: inputs{
+				flags:       unit.DefaultFlags(),
+				key:         "flag2",
+				contentType: mergePatchType,
+				patch:       `{"rules":[{"name":"rule1","variant":"variant2","query":"plan eq"}]}`,
+			},
+			want: want{
+				httpCode: http.StatusBadRequest,
+				bodyFile: "../testdata/patch_flag/invalid_query.json",
+			},
+		},
+		{
+			name: "404 if not exists",
+			inputs: inputs{
+				flags:       unit.DefaultFlags(),
+				key:         "flag99",
+				contentType: mergePatchType,
+				patch:       `{"disabled":true}`,
+			},
+			want: want{
+				httpCode: http.StatusNotFound,
+				bodyFile: "../testdata/not_found.json",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		// env vars
+		os.Setenv("API_KEYS", tok)
+		os.Setenv("FLAG_FORMAT", "yaml")
+		os.Setenv("WRITE_CLIENT_LOCATION", "any")
+
+		// config
+		config.New()
+
+		// clients
+		writereadClient := mockwritereader.NewWriteReader(
+			writereader.WithLocation(config.WriteClientLocation()),
+		)
+
+		for k, v := range test.inputs.flags {
+			bs, err := yaml.Marshal(map[string]*flags.Flag{
+				k: v,
+			})
+			require.NoError(t, err)
+
+			err = writereadClient.Write(context.TODO(), k, bs)
+			require.NoError(t, err)
+		}
+
+		exportClient := localexporter.NewExporter(
+			exporter.WithDir(config.ExportClientDir()),
+		)
+
+		notifyClient := localnotifier.NewNotifier()
+
+		authClient := apikey.NewAuthenticator()
+
+		broadcastClient := localbroadcaster.NewBroadcaster()
+
+		// servers and services
+		httpServer, _, exportService, notifyService, err := server.Factory(
+			writereadClient,
+			writereadClient,
+			exportClient,
+			notifyClient,
+			authClient,
+			broadcastClient,
+		)
+		require.NoError(t, err)
+
+		t.Run(test.name, func(t *testing.T) {
+			err = httpServer.Run()
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(
+				http.MethodPatch,
+				fmt.Sprintf("http://%s%s%s", httpServer.Options().Address, "/admin/v1/flags", "/"+test.inputs.key),
+				strings.NewReader(test.inputs.patch),
+			)
+			require.NoError(t, err)
+
+			req.Header.Set("content-type", test.inputs.contentType)
+			req.Header.Set("authorization", fmt.Sprintf("Bearer %s", tok))
+
+			client := &http.Client{}
+
+			rsp, err := client.Do(req)
+			require.NoError(t, err)
+
+			want, err := os.ReadFile(test.want.bodyFile)
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(rsp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, string(want), string(got))
+
+			require.Equal(t, test.want.httpCode, rsp.StatusCode)
+
+			t.Cleanup(func() {
+				rsp.Body.Close()
+				notifyService.Close()
+				exportService.Close()
+				err = httpServer.Stop()
+				require.NoError(t, err)
+				config.Reset()
+			})
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package projects
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/apikey"
+	localbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/local"
+	"github.com/w-h-a/flags/internal/server/clients/exporter"
+	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
+	"github.com/w-h-a/flags/internal/server/clients/notifier"
+	mocknotifier "github.com/w-h-a/flags/internal/server/clients/notifier/mock"
+	"github.com/w-h-a/flags/internal/server/clients/notifier/routing"
+	"github.com/w-h-a/flags/internal/server/clients/writereader"
+	mockwritereader "github.com/w-h-a/flags/internal/server/clients/writereader/mock"
+	"github.com/w-h-a/flags/internal/server/config"
+	"github.com/w-h-a/flags/tests/unit"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	tok         = "mytoken"
+	checkoutTok = "mycheckouttoken"
+)
+
+func TestProjects(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	// env vars
+	os.Setenv("API_KEYS", tok)
+	os.Setenv("FLAG_FORMAT", "yaml")
+	os.Setenv("PROJECTS", "checkout,search")
+	os.Setenv("PROJECT_CHECKOUT_API_KEYS", checkoutTok)
+
+	// config
+	config.New()
+	defer config.Reset()
+
+	// clients
+	writereadClient := mockwritereader.NewWriteReader(
+		writereader.WithLocation("any"),
+	)
+
+	stored := map[string]*flags.Flag{
+		"flag1":          unit.DefaultFlags()["flag1"],
+		"checkout/flag2": unit.DefaultFlags()["flag2"],
+		"search/flag3":   unit.DefaultFlags()["flag2"],
+	}
+
+	for k, v := range stored {
+		bs, err := yaml.Marshal(map[string]*flags.Flag{k: v})
+		require.NoError(t, err)
+
+		err = writereadClient.Write(context.TODO(), k, bs)
+		require.NoError(t, err)
+	}
+
+	exportClient := localexporter.NewExporter(
+		exporter.WithDir(config.ExportClientDir()),
+	)
+
+	notifyClient := mocknotifier.NewNotifier()
+
+	authClient := apikey.NewAuthenticator()
+
+	broadcastClient := localbroadcaster.NewBroadcaster()
+
+	// servers and services
+	httpServer, _, exportService, notifyService, err := server.Factory(
+		writereadClient,
+		writereadClient,
+		exportClient,
+		notifyClient,
+		authClient,
+		broadcastClient,
+	)
+	require.NoError(t, err)
+
+	err = httpServer.Run()
+	require.NoError(t, err)
+
+	defer func() {
+		notifyService.Close()
+		exportService.Close()
+		err := httpServer.Stop()
+		require.NoError(t, err)
+	}()
+
+	address := httpServer.Options().Address
+
+	type inputs struct {
+		method string
+		path   string
+		body   string
+		token  string
+	}
+
+	type want struct {
+		httpCode int
+		body     map[string]any
+		keys     []string
+	}
+
+	tests := []struct {
+		name   string
+		inputs inputs
+		want   want
+	}{
+		{
+			name: "200 for flag in the key's project",
+			inputs: inputs{
+				method: http.MethodPost,
+				path:   "/ofrep/v1/evaluate/flags/flag2",
+				token:  checkoutTok,
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				body:     map[string]any{"key": "flag2", "value": "B"},
+			},
+		},
+		{
+			name: "404 for flag outside the key's project",
+			inputs: inputs{
+				method: http.MethodPost,
+				path:   "/ofrep/v1/evaluate/flags/flag1",
+				token:  checkoutTok,
+			},
+			want: want{
+				httpCode: http.StatusNotFound,
+				body:     map[string]any{"errorCode": "FLAG_NOT_FOUND"},
+			},
+		},
+		{
+			name: "403 for key used in another project",
+			inputs: inputs{
+				method: http.MethodPost,
+				path:   "/projects/search/ofrep/v1/evaluate/flags/flag3",
+				token:  checkoutTok,
+			},
+			want: want{
+				httpCode: http.StatusForbidden,
+				body:     map[string]any{"error": "not authorized"},
+			},
+		},
+		{
+			name: "200 for project taken from the path",
+			inputs: inputs{
+				method: http.MethodPost,
+				path:   "/projects/search/ofrep/v1/evaluate/flags/flag3",
+				token:  tok,
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				body:     map[string]any{"key": "flag3", "value": "B"},
+			},
+		},
+		{
+			name: "404 for unknown project",
+			inputs: inputs{
+				method: http.MethodPost,
+				path:   "/projects/qa/ofrep/v1/evaluate/flags/flag3",
+				token:  tok,
+			},
+			want: want{
+				httpCode: http.StatusNotFound,
+				body:     map[string]any{"error": `unknown project "qa"`},
+			},
+		},
+		{
+			name: "201 for flag created in the key's project",
+			inputs: inputs{
+				method: http.MethodPut,
+				path:   "/admin/v1/flags",
+				body:   `{"flag4": {"variants": {"default": "A"}}}`,
+				token:  checkoutTok,
+			},
+			want: want{
+				httpCode: http.StatusCreated,
+				keys:     []string{"flag4"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rsp := do(t, test.inputs.method, address, test.inputs.path, test.inputs.body, test.inputs.token)
+
+			require.Equal(t, test.want.httpCode, rsp.StatusCode)
+
+			got := map[string]any{}
+
+			err := json.NewDecoder(rsp.Body).Decode(&got)
+			require.NoError(t, err)
+
+			for k, v := range test.want.body {
+				require.Equal(t, v, got[k])
+			}
+
+			for _, k := range test.want.keys {
+				require.Contains(t, got, k)
+			}
+		})
+	}
+
+	// the writer got the flag under its project
+	_, err = writereadClient.ReadByKey(context.TODO(), "checkout/flag4")
+	require.NoError(t, err)
+
+	// evaluating everything only sees the project
+	rsp := do(t, http.MethodPost, address, "/ofrep/v1/evaluate/flags", "", checkoutTok)
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+
+	all := struct {
+		Flags []struct {
+			Key string `json:"key"`
+		} `json:"flags"`
+	}{}
+
+	err = json.NewDecoder(rsp.Body).Decode(&all)
+	require.NoError(t, err)
+
+	keys := []string{}
+
+	for _, f := range all.Flags {
+		keys = append(keys, f.Key)
+	}
+
+	require.Equal(t, []string{"flag2", "flag4"}, keys)
+
+	// keys without a project see every flag
+	rsp = do(t, http.MethodGet, address, "/admin/v1/flags", "", tok)
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+
+	got := map[string]any{}
+
+	err = json.NewDecoder(rsp.Body).Decode(&got)
+	require.NoError(t, err)
+
+	require.Len(t, got, 4)
+	require.Contains(t, got, "checkout/flag4")
+}
+
+func TestProjects_NotifyRouting(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	fallback := mocknotifier.NewNotifier()
+	checkout := mocknotifier.NewNotifier()
+
+	notifyClient := routing.NewNotifier(
+		routing.WithNotifier(fallback),
+		routing.WithRoute("checkout", checkout),
+	)
+
+	diff := flags.NewDiff(
+		map[string]*flags.Flag{},
+		map[string]*flags.Flag{
+			"flag1":          unit.DefaultFlags()["flag1"],
+			"checkout/flag2": unit.DefaultFlags()["flag2"],
+			"search/flag3":   unit.DefaultFlags()["flag2"],
+		},
+	)
+
+	err := notifyClient.Notify(context.TODO(), diff)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"checkout/flag2"}, added(checkout))
+	require.Equal(t, []string{"flag1", "search/flag3"}, added(fallback))
+}
+
+func added(n notifier.Notifier) []string {
+	keys := []string{}
+
+	for _, diff := range n.(*mocknotifier.Client).Diffs() {
+		for k := range diff.Added {
+			keys = append(keys, k)
+		}
+	}
+
+	// map order is random
+	if len(keys) == 2 && keys[0] > keys[1] {
+		keys[0], keys[1] = keys[1], keys[0]
+	}
+
+	return keys
+}
+
+func do(t *testing.T, method, address, path, body, token string) *http.Response {
+	req, err := http.NewRequest(
+		method,
+		fmt.Sprintf("http://%s%s", address, path),
+		strings.NewReader(body),
+	)
+	require.NoError(t, err)
+
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set("authorization", fmt.Sprintf("Bearer %s", token))
+
+	rsp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		io.Copy(io.Discard, rsp.Body)
+		rsp.Body.Close()
+	})
+
+	return rsp
+}
@@ -0,0 +1,359 @@
+package proposeflag
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/apikey"
+	localbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/local"
+	"github.com/w-h-a/flags/internal/server/clients/exporter"
+	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
+	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	localreader "github.com/w-h-a/flags/internal/server/clients/reader/local"
+	"github.com/w-h-a/flags/internal/server/clients/writer"
+	githubwriter "github.com/w-h-a/flags/internal/server/clients/writer/github"
+	gitlabwriter "github.com/w-h-a/flags/internal/server/clients/writer/gitlab"
+	"github.com/w-h-a/flags/internal/server/config"
+)
+
+const (
+	tok      = "mytoken"
+	flagFile = "../testdata/local_writer/flags.yaml"
+)
+
+// repo stands in for the github and gitlab apis
+type repo struct {
+	doc      []byte
+	failPull bool
+	branch   string
+	content  string
+	deleted  string
+	mtx      sync.Mutex
+}
+
+func (f *repo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	body := map[string]any{}
+
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	route := r.Method + " " + r.URL.EscapedPath()
+
+	if r.Method == http.MethodDelete {
+		for _, prefix := range []string{"/repos/o/r/git/refs/heads/", "/api/v4/projects/g%2Fp/repository/branches/"} {
+			if branch, ok := strings.CutPrefix(r.URL.EscapedPath(), prefix); ok {
+				f.deleted, _ = url.PathUnescape(branch)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+	}
+
+	switch route {
+	// github
+	case "GET /repos/o/r/contents/flags.yaml":
+		json.NewEncoder(w).Encode(map[string]any{
+			"sha":     "file-sha",
+			"content": base64.StdEncoding.EncodeToString(f.doc),
+		})
+	case "GET /repos/o/r/git/ref/heads/main":
+		json.NewEncoder(w).Encode(map[string]any{
+			"object": map[string]any{"sha": "base-sha"},
+		})
+	case "POST /repos/o/r/git/refs":
+		f.branch = strings.TrimPrefix(body["ref"].(string), "refs/heads/")
+		w.WriteHeader(http.StatusCreated)
+	case "PUT /repos/o/r/contents/flags.yaml":
+		bs, _ := base64.StdEncoding.DecodeString(body["content"].(string))
+		f.content = string(bs)
+		w.WriteHeader(http.StatusOK)
+	case "POST /repos/o/r/pulls":
+		if f.failPull {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"html_url": "https://github.com/o/r/pull/1",
+		})
+	// gitlab
+	case "GET /api/v4/projects/g%2Fp/repository/files/flags.yaml/raw":
+		w.Write(f.doc)
+	case "POST /api/v4/projects/g%2Fp/repository/branches":
+		f.branch = r.URL.Query().Get("branch")
+		w.WriteHeader(http.StatusCreated)
+	case "POST /api/v4/projects/g%2Fp/repository/commits":
+		actions := body["actions"].([]any)
+		f.content = actions[0].(map[string]any)["content"].(string)
+		w.WriteHeader(http.StatusCreated)
+	case "POST /api/v4/projects/g%2Fp/merge_requests":
+		if f.failPull {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"web_url": "https://gitlab.com/g/p/-/merge_requests/1",
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestProposeFlag(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	type inputs struct {
+		writeClient string
+		failPull    bool
+		method      string
+		key         string
+		contentType string
+		body        string
+	}
+
+	type want struct {
+		httpCode int
+		bodyFile string
+		filePath string
+		deleted  bool
+	}
+
+	tests := []struct {
+		name   string
+		inputs inputs
+		want   want
+	}{
+		{
+			name: "202 with pull request for put",
+			inputs: inputs{
+				writeClient: "github",
+				method:      http.MethodPut,
+				key:         "flag1",
+				body:        `{"flag1":{"disabled":true,"variants":{"default":"A","variant2":"B"}}}`,
+			},
+			want: want{
+				httpCode: http.StatusAccepted,
+				bodyFile: "../testdata/propose_flag/github_response.json",
+				filePath: "../testdata/local_writer/updated.yaml",
+			},
+		},
+		{
+			name: "202 with merge request for patch",
+			inputs: inputs{
+				writeClient: "gitlab",
+				method:      http.MethodPatch,
+				key:         "flag1",
+				contentType: "application/merge-patch+json",
+				body:        `{"disabled":true,"rules":null}`,
+			},
+			want: want{
+				httpCode: http.StatusAccepted,
+				bodyFile: "../testdata/propose_flag/gitlab_response.json",
+				filePath: "../testdata/local_writer/updated.yaml",
+			},
+		},
+		{
+			name: "500 if the pull request fails",
+			inputs: inputs{
+				writeClient: "github",
+				failPull:    true,
+				method:      http.MethodPut,
+				key:         "flag1",
+				body:        `{"flag1":{"disabled":true,"variants":{"default":"A","variant2":"B"}}}`,
+			},
+			want: want{
+				httpCode: http.StatusInternalServerError,
+				bodyFile: "../testdata/propose_flag/pull_error.json",
+				deleted:  true,
+			},
+		},
+		{
+			name: "500 if the merge request fails",
+			inputs: inputs{
+				writeClient: "gitlab",
+				failPull:    true,
+				method:      http.MethodPut,
+				key:         "flag1",
+				body:        `{"flag1":{"disabled":true,"variants":{"default":"A","variant2":"B"}}}`,
+			},
+			want: want{
+				httpCode: http.StatusInternalServerError,
+				bodyFile: "../testdata/propose_flag/merge_request_error.json",
+				deleted:  true,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		// env vars
+		os.Setenv("API_KEYS", tok)
+		os.Setenv("FLAG_FORMAT", "yaml")
+
+		// config
+		config.New()
+
+		// fake api
+		doc, err := os.ReadFile(flagFile)
+		require.NoError(t, err)
+
+		fake := &repo{doc: doc, failPull: test.inputs.failPull}
+
+		api := httptest.NewServer(fake)
+
+		// clients
+		readClient := localreader.NewReader(
+			reader.WithLocation(flagFile),
+			reader.WithFormat(config.FlagFormat()),
+		)
+
+		var writeClient writer.Writer
+
+		switch test.inputs.writeClient {
+		case "gitlab":
+			writeClient = gitlabwriter.NewWriter(
+				writer.WithLocation(api.URL+"/api/v4/projects/g%2Fp/repository/files/flags.yaml/raw?ref=main"),
+				writer.WithFormat(config.FlagFormat()),
+			)
+		default:
+			writeClient = githubwriter.NewWriter(
+				writer.WithLocation(api.URL+"/repos/o/r/contents/flags.yaml?ref=main"),
+				writer.WithFormat(config.FlagFormat()),
+			)
+		}
+
+		exportClient := localexporter.NewExporter(
+			exporter.WithDir(config.ExportClientDir()),
+		)
+
+		notifyClient := localnotifier.NewNotifier()
+
+		authClient := apikey.NewAuthenticator()
+
+		broadcastClient := localbroadcaster.NewBroadcaster()
+
+		// servers and services
+		httpServer, _, exportService, notifyService, err := server.Factory(
+			writeClient,
+			readClient,
+			exportClient,
+			notifyClient,
+			authClient,
+			broadcastClient,
+		)
+		require.NoError(t, err)
+
+		t.Run(test.name, func(t *testing.T) {
+			err = httpServer.Run()
+			require.NoError(t, err)
+
+			path := "/admin/v1/flags"
+			if test.inputs.method == http.MethodPatch {
+				path += "/" + test.inputs.key
+			}
+
+			req, err := http.NewRequest(
+				test.inputs.method,
+				fmt.Sprintf("http://%s%s", httpServer.Options().Address, path),
+				strings.NewReader(test.inputs.body),
+			)
+			require.NoError(t, err)
+
+			if len(test.inputs.contentType) > 0 {
+				req.Header.Set("content-type", test.inputs.contentType)
+			}
+			req.Header.Set("authorization", fmt.Sprintf("Bearer %s", tok))
+
+			client := &http.Client{}
+
+			rsp, err := client.Do(req)
+			require.NoError(t, err)
+
+			want, err := os.ReadFile(test.want.bodyFile)
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(rsp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, string(want), string(got))
+
+			require.Equal(t, test.want.httpCode, rsp.StatusCode)
+
+			if len(test.want.filePath) > 0 {
+				want, err := os.ReadFile(test.want.filePath)
+				require.NoError(t, err)
+
+				require.Equal(t, string(want), fake.content)
+				require.True(t, strings.HasPrefix(fake.branch, "flags/"+test.inputs.key+"-"))
+			}
+
+			if test.want.deleted {
+				require.Equal(t, fake.branch, fake.deleted)
+			} else {
+				require.Empty(t, fake.deleted)
+			}
+
+			t.Cleanup(func() {
+				rsp.Body.Close()
+				api.Close()
+				notifyService.Close()
+				exportService.Close()
+				err = httpServer.Stop()
+				require.NoError(t, err)
+				config.Reset()
+			})
+		})
+	}
+}
+
+func TestProposalBranch(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{
+			name: "plain key",
+			key:  "flag1",
+			want: "flags/flag1-",
+		},
+		{
+			name: "project key",
+			key:  "team-a/flag1",
+			want: "flags/team-a-flag1-",
+		},
+		{
+			name: "characters git refuses",
+			key:  "a..b c~d^e:f?g*h[i\\j.lock",
+			want: "flags/a-b-c-d-e-f-g-h-i-j-lock-",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.True(t, strings.HasPrefix(writer.ProposalBranch(test.key), test.want))
+		})
+	}
+}
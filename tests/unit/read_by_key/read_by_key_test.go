@@ -0,0 +1,221 @@
+package readbykey
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/apikey"
+	localbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/local"
+	"github.com/w-h-a/flags/internal/server/clients/exporter"
+	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
+	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	"github.com/w-h-a/flags/internal/server/clients/reader/github"
+	"github.com/w-h-a/flags/internal/server/clients/reader/gitlab"
+	localreader "github.com/w-h-a/flags/internal/server/clients/reader/local"
+	"github.com/w-h-a/flags/internal/server/clients/writer"
+	"github.com/w-h-a/flags/internal/server/clients/writer/noop"
+	"github.com/w-h-a/flags/internal/server/config"
+)
+
+const (
+	tok = "mytoken"
+)
+
+func TestReadByKey(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	type inputs struct {
+		readClient string
+		format     string
+		filePath   string
+		key        string
+	}
+
+	type want struct {
+		httpCode int
+		bodyFile string
+	}
+
+	tests := []struct {
+		name   string
+		inputs inputs
+		want   want
+	}{
+		{
+			name: "200 if found in local yaml",
+			inputs: inputs{
+				readClient: "local",
+				format:     "yaml",
+				filePath:   "../testdata/flags.yaml",
+				key:        "number-flag",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/read_by_key/valid_response.json",
+			},
+		},
+		{
+			name: "200 if found in local json",
+			inputs: inputs{
+				readClient: "local",
+				format:     "json",
+				filePath:   "../testdata/flags.json",
+				key:        "number-flag",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/read_by_key/valid_response.json",
+			},
+		},
+		{
+			name: "404 if not found in local yaml",
+			inputs: inputs{
+				readClient: "local",
+				format:     "yaml",
+				filePath:   "../testdata/flags.yaml",
+				key:        "flag99",
+			},
+			want: want{
+				httpCode: http.StatusNotFound,
+				bodyFile: "../testdata/not_found.json",
+			},
+		},
+		{
+			name: "200 if found in github",
+			inputs: inputs{
+				readClient: "github",
+				format:     "yaml",
+				filePath:   "../testdata/flags.yaml",
+				key:        "number-flag",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/read_by_key/valid_response.json",
+			},
+		},
+		{
+			name: "404 if not found in gitlab",
+			inputs: inputs{
+				readClient: "gitlab",
+				format:     "json",
+				filePath:   "../testdata/flags.json",
+				key:        "flag99",
+			},
+			want: want{
+				httpCode: http.StatusNotFound,
+				bodyFile: "../testdata/not_found.json",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		// env vars
+		os.Setenv("API_KEYS", tok)
+		os.Setenv("FLAG_FORMAT", test.inputs.format)
+
+		// config
+		config.New()
+
+		// clients
+		bs, err := os.ReadFile(test.inputs.filePath)
+		require.NoError(t, err)
+
+		repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(bs)
+		}))
+
+		var readClient reader.Reader
+
+		switch test.inputs.readClient {
+		case "github":
+			readClient = github.NewReader(
+				reader.WithLocation(repo.URL),
+				reader.WithFormat(config.FlagFormat()),
+			)
+		case "gitlab":
+			readClient = gitlab.NewReader(
+				reader.WithLocation(repo.URL),
+				reader.WithFormat(config.FlagFormat()),
+			)
+		default:
+			readClient = localreader.NewReader(
+				reader.WithLocation(test.inputs.filePath),
+				reader.WithFormat(config.FlagFormat()),
+			)
+		}
+
+		writeClient := noop.NewWriter(
+			writer.WithLocation(config.WriteClientLocation()),
+		)
+
+		exportClient := localexporter.NewExporter(
+			exporter.WithDir(config.ExportClientDir()),
+		)
+
+		notifyClient := localnotifier.NewNotifier()
+
+		authClient := apikey.NewAuthenticator()
+
+		broadcastClient := localbroadcaster.NewBroadcaster()
+
+		// servers and services
+		httpServer, _, exportService, notifyService, err := server.Factory(
+			writeClient,
+			readClient,
+			exportClient,
+			notifyClient,
+			authClient,
+			broadcastClient,
+		)
+		require.NoError(t, err)
+
+		t.Run(test.name, func(t *testing.T) {
+			err = httpServer.Run()
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(
+				http.MethodGet,
+				fmt.Sprintf("http://%s%s%s", httpServer.Options().Address, "/admin/v1/flags/", test.inputs.key),
+				nil,
+			)
+			require.NoError(t, err)
+
+			req.Header.Set("authorization", fmt.Sprintf("Bearer %s", tok))
+
+			client := &http.Client{}
+
+			rsp, err := client.Do(req)
+			require.NoError(t, err)
+
+			want, err := os.ReadFile(test.want.bodyFile)
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(rsp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, string(want), string(got))
+
+			require.Equal(t, test.want.httpCode, rsp.StatusCode)
+
+			t.Cleanup(func() {
+				rsp.Body.Close()
+				repo.Close()
+				notifyService.Close()
+				exportService.Close()
+				err = httpServer.Stop()
+				require.NoError(t, err)
+				config.Reset()
+			})
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package redisclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/apikey"
+	"github.com/w-h-a/flags/internal/server/clients/broadcaster"
+	redisbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/redis"
+	"github.com/w-h-a/flags/internal/server/clients/exporter"
+	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
+	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	redisreader "github.com/w-h-a/flags/internal/server/clients/reader/redis"
+	"github.com/w-h-a/flags/internal/server/clients/writer"
+	rediswriter "github.com/w-h-a/flags/internal/server/clients/writer/redis"
+	"github.com/w-h-a/flags/internal/server/config"
+	"github.com/w-h-a/flags/tests/unit"
+)
+
+const (
+	tok = "mytoken"
+)
+
+func TestRedisClient(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	type inputs struct {
+		format string
+		method string
+		path   string
+		body   string
+	}
+
+	type want struct {
+		httpCode int
+		bodyFile string
+	}
+
+	tests := []struct {
+		name   string
+		inputs inputs
+		want   want
+	}{
+		{
+			name: "200 for get all in yaml",
+			inputs: inputs{
+				format: "yaml",
+				method: http.MethodGet,
+				path:   "/admin/v1/flags",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/get_flags/valid_response.json",
+			},
+		},
+		{
+			name: "200 for get all in json",
+			inputs: inputs{
+				format: "json",
+				method: http.MethodGet,
+				path:   "/admin/v1/flags",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/get_flags/valid_response.json",
+			},
+		},
+		{
+			name: "200 for get one",
+			inputs: inputs{
+				format: "yaml",
+				method: http.MethodGet,
+				path:   "/admin/v1/flags/flag2",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/get_flag/valid_response_flag2.json",
+			},
+		},
+		{
+			name: "404 for get one if not exists",
+			inputs: inputs{
+				format: "yaml",
+				method: http.MethodGet,
+				path:   "/admin/v1/flags/flag99",
+			},
+			want: want{
+				httpCode: http.StatusNotFound,
+				bodyFile: "../testdata/not_found.json",
+			},
+		},
+		{
+			name: "201 for put",
+			inputs: inputs{
+				format: "json",
+				method: http.MethodPut,
+				path:   "/admin/v1/flags",
+				body:   `{"flag3":{"disabled":true,"variants":{"default":"A","variant2":"B"},"rules":[{"name":"rule1","variant":"variant2"}]}}`,
+			},
+			want: want{
+				httpCode: http.StatusCreated,
+				bodyFile: "../testdata/upsert_flag/valid_response_created.json",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		// redis
+		mr := miniredis.RunT(t)
+
+		// env vars
+		os.Setenv("API_KEYS", tok)
+		os.Setenv("FLAG_FORMAT", test.inputs.format)
+
+		// config
+		config.New()
+
+		// clients
+		location := fmt.Sprintf("redis://%s/0", mr.Addr())
+
+		readClient := redisreader.NewReader(
+			reader.WithLocation(location),
+			reader.WithFormat(config.FlagFormat()),
+			reader.WithPrefix(config.ReadClientPrefix()),
+		)
+
+		writeClient := rediswriter.NewWriter(
+			writer.WithLocation(location),
+			writer.WithPrefix(config.WriteClientPrefix()),
+		)
+
+		for k, v := range unit.DefaultFlags() {
+			bs, err := unit.Encode(map[string]*flags.Flag{k: v}, test.inputs.format)
+			require.NoError(t, err)
+
+			err = writeClient.Write(context.TODO(), k, bs)
+			require.NoError(t, err)
+		}
+
+		exportClient := localexporter.NewExporter(
+			exporter.WithDir(config.ExportClientDir()),
+		)
+
+		notifyClient := localnotifier.NewNotifier()
+
+		authClient := apikey.NewAuthenticator()
+
+		broadcastClient := redisbroadcaster.NewBroadcaster(
+			broadcaster.WithLocation(location),
+			broadcaster.WithPrefix(config.ReadClientPrefix()),
+		)
+
+		// servers and services
+		httpServer, _, exportService, notifyService, err := server.Factory(
+			writeClient,
+			readClient,
+			exportClient,
+			notifyClient,
+			authClient,
+			broadcastClient,
+		)
+		require.NoError(t, err)
+
+		t.Run(test.name, func(t *testing.T) {
+			err = httpServer.Run()
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(
+				test.inputs.method,
+				fmt.Sprintf("http://%s%s", httpServer.Options().Address, test.inputs.path),
+				strings.NewReader(test.inputs.body),
+			)
+			require.NoError(t, err)
+
+			req.Header.Set("authorization", fmt.Sprintf("Bearer %s", tok))
+
+			client := &http.Client{}
+
+			rsp, err := client.Do(req)
+			require.NoError(t, err)
+
+			want, err := os.ReadFile(test.want.bodyFile)
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(rsp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, string(want), string(got))
+
+			require.Equal(t, test.want.httpCode, rsp.StatusCode)
+
+			t.Cleanup(func() {
+				rsp.Body.Close()
+				notifyService.Close()
+				exportService.Close()
+				err = httpServer.Stop()
+				require.NoError(t, err)
+				config.Reset()
+			})
+		})
+	}
+}
+
+func TestRedisClient_Broadcast(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	mr := miniredis.RunT(t)
+
+	location := fmt.Sprintf("redis://%s/0", mr.Addr())
+
+	// two instances
+	first := redisbroadcaster.NewBroadcaster(
+		broadcaster.WithLocation(location),
+	)
+
+	second := redisbroadcaster.NewBroadcaster(
+		broadcaster.WithLocation(location),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	firstCh, err := first.Listen(ctx)
+	require.NoError(t, err)
+
+	secondCh, err := second.Listen(ctx)
+	require.NoError(t, err)
+
+	err = first.Broadcast(ctx)
+	require.NoError(t, err)
+
+	select {
+	case <-secondCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("second instance was not told to refresh")
+	}
+
+	// nobody needs to hear their own broadcast
+	select {
+	case <-firstCh:
+		t.Fatal("first instance heard its own broadcast")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,432 @@
+package refreshcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/apikey"
+	mockbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/mock"
+	"github.com/w-h-a/flags/internal/server/clients/exporter"
+	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
+	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
+	"github.com/w-h-a/flags/internal/server/clients/writereader"
+	mockwritereader "github.com/w-h-a/flags/internal/server/clients/writereader/mock"
+	"github.com/w-h-a/flags/internal/server/config"
+	"github.com/w-h-a/flags/internal/server/services/cache"
+	"github.com/w-h-a/flags/internal/server/services/notify"
+	"github.com/w-h-a/flags/tests/unit"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	tok = "mytoken"
+)
+
+func TestRefreshCache(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	type inputs struct {
+		flags       map[string]*flags.Flag
+		outOfBand   map[string]*flags.Flag
+		method      string
+		path        string
+		contentType string
+		body        string
+		evalKey     string
+	}
+
+	type want struct {
+		httpCode     int
+		bodyFile     string
+		evalBodyFile string
+		broadcasts   int
+	}
+
+	tests := []struct {
+		name   string
+		inputs inputs
+		want   want
+	}{
+		{
+			name: "patch is served without waiting for the cache updater",
+			inputs: inputs{
+				flags:       unit.DefaultFlags(),
+				method:      http.MethodPatch,
+				path:        "/admin/v1/flags/flag1",
+				contentType: "application/merge-patch+json",
+				body:        `{"disabled":true}`,
+				evalKey:     "flag1",
+			},
+			want: want{
+				httpCode:     http.StatusOK,
+				evalBodyFile: "../testdata/refresh_cache/disabled_response.json",
+				broadcasts:   1,
+			},
+		},
+		{
+			name: "put is served without waiting for the cache updater",
+			inputs: inputs{
+				flags:       unit.DefaultFlags(),
+				method:      http.MethodPut,
+				path:        "/admin/v1/flags",
+				contentType: "application/json",
+				body:        `{"flag3":{"disabled":false,"variants":{"default":"C"}}}`,
+				evalKey:     "flag3",
+			},
+			want: want{
+				httpCode:     http.StatusCreated,
+				evalBodyFile: "../testdata/refresh_cache/created_response.json",
+				broadcasts:   1,
+			},
+		},
+		{
+			name: "refresh picks up out of band changes",
+			inputs: inputs{
+				flags: unit.DefaultFlags(),
+				outOfBand: map[string]*flags.Flag{
+					"flag3": {
+						Disabled: unit.Bool(false),
+						Variants: map[string]any{
+							"default": "C",
+						},
+					},
+				},
+				method:  http.MethodPost,
+				path:    "/admin/v1/refresh",
+				evalKey: "flag3",
+			},
+			want: want{
+				httpCode:     http.StatusOK,
+				bodyFile:     "../testdata/refresh_cache/refresh_response.json",
+				evalBodyFile: "../testdata/refresh_cache/created_response.json",
+				broadcasts:   1,
+			},
+		},
+		{
+			name: "failed write does not broadcast",
+			inputs: inputs{
+				flags:       unit.DefaultFlags(),
+				method:      http.MethodPatch,
+				path:        "/admin/v1/flags/flag1",
+				contentType: "application/merge-patch+json",
+				body:        `{"variants":{"default":null}}`,
+				evalKey:     "flag1",
+			},
+			want: want{
+				httpCode:     http.StatusBadRequest,
+				evalBodyFile: "../testdata/refresh_cache/unchanged_response.json",
+				broadcasts:   0,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		// env vars
+		os.Setenv("API_KEYS", tok)
+		os.Setenv("FLAG_FORMAT", "yaml")
+		os.Setenv("WRITE_CLIENT_LOCATION", "any")
+
+		// config
+		config.New()
+
+		// clients
+		writereadClient := mockwritereader.NewWriteReader(
+			writereader.WithLocation(config.WriteClientLocation()),
+		)
+
+		for k, v := range test.inputs.flags {
+			bs, err := yaml.Marshal(map[string]*flags.Flag{
+				k: v,
+			})
+			require.NoError(t, err)
+
+			err = writereadClient.Write(context.TODO(), k, bs)
+			require.NoError(t, err)
+		}
+
+		exportClient := localexporter.NewExporter(
+			exporter.WithDir(config.ExportClientDir()),
+		)
+
+		notifyClient := localnotifier.NewNotifier()
+
+		authClient := apikey.NewAuthenticator()
+
+		broadcastClient := mockbroadcaster.NewBroadcaster()
+
+		// servers and services
+		httpServer, _, exportService, notifyService, err := server.Factory(
+			writereadClient,
+			writereadClient,
+			exportClient,
+			notifyClient,
+			authClient,
+			broadcastClient,
+		)
+		require.NoError(t, err)
+
+		// changes made behind the server's back
+		for k, v := range test.inputs.outOfBand {
+			bs, err := yaml.Marshal(map[string]*flags.Flag{
+				k: v,
+			})
+			require.NoError(t, err)
+
+			err = writereadClient.Write(context.TODO(), k, bs)
+			require.NoError(t, err)
+		}
+
+		t.Run(test.name, func(t *testing.T) {
+			err = httpServer.Run()
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(
+				test.inputs.method,
+				fmt.Sprintf("http://%s%s", httpServer.Options().Address, test.inputs.path),
+				strings.NewReader(test.inputs.body),
+			)
+			require.NoError(t, err)
+
+			if len(test.inputs.contentType) > 0 {
+				req.Header.Set("content-type", test.inputs.contentType)
+			}
+			req.Header.Set("authorization", fmt.Sprintf("Bearer %s", tok))
+
+			client := &http.Client{}
+
+			rsp, err := client.Do(req)
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(rsp.Body)
+			require.NoError(t, err)
+
+			if len(test.want.bodyFile) > 0 {
+				want, err := os.ReadFile(test.want.bodyFile)
+				require.NoError(t, err)
+
+				require.Equal(t, string(want), string(got))
+			}
+
+			require.Equal(t, test.want.httpCode, rsp.StatusCode)
+
+			// no cache updater is running so this has to come from the refresh
+			evalRsp := evaluate(t, httpServer.Options().Address, test.inputs.evalKey)
+
+			want, err := os.ReadFile(test.want.evalBodyFile)
+			require.NoError(t, err)
+
+			require.Equal(t, string(want), evalRsp)
+
+			b := broadcastClient.(*mockbroadcaster.Client)
+			require.Equal(t, test.want.broadcasts, b.BroadcastCount())
+
+			t.Cleanup(func() {
+				rsp.Body.Close()
+				notifyService.Close()
+				exportService.Close()
+				err = httpServer.Stop()
+				require.NoError(t, err)
+				config.Reset()
+			})
+		})
+	}
+}
+
+func TestRefreshCache_Invalidation(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	// env vars
+	os.Setenv("API_KEYS", tok)
+	os.Setenv("FLAG_FORMAT", "yaml")
+	os.Setenv("WRITE_CLIENT_LOCATION", "any")
+
+	// config
+	config.New()
+
+	// clients
+	writereadClient := mockwritereader.NewWriteReader(
+		writereader.WithLocation(config.WriteClientLocation()),
+	)
+
+	for k, v := range unit.DefaultFlags() {
+		bs, err := yaml.Marshal(map[string]*flags.Flag{
+			k: v,
+		})
+		require.NoError(t, err)
+
+		err = writereadClient.Write(context.TODO(), k, bs)
+		require.NoError(t, err)
+	}
+
+	exportClient := localexporter.NewExporter(
+		exporter.WithDir(config.ExportClientDir()),
+	)
+
+	notifyClient := localnotifier.NewNotifier()
+
+	authClient := apikey.NewAuthenticator()
+
+	broadcastClient := mockbroadcaster.NewBroadcaster()
+
+	// servers and services
+	httpServer, cacheService, exportService, notifyService, err := server.Factory(
+		writereadClient,
+		writereadClient,
+		exportClient,
+		notifyClient,
+		authClient,
+		broadcastClient,
+	)
+	require.NoError(t, err)
+
+	err = httpServer.Run()
+	require.NoError(t, err)
+
+	errCh := make(chan error, 1)
+	invalidationStop := make(chan struct{})
+
+	go func() {
+		errCh <- server.ListenForInvalidations(cacheService, notifyService, broadcastClient, invalidationStop)
+	}()
+
+	// another instance writes and tells us about it
+	bs, err := yaml.Marshal(map[string]*flags.Flag{
+		"flag3": {
+			Disabled: unit.Bool(false),
+			Variants: map[string]any{
+				"default": "C",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	err = writereadClient.Write(context.TODO(), "flag3", bs)
+	require.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	b := broadcastClient.(*mockbroadcaster.Client)
+	b.Trigger()
+
+	time.Sleep(500 * time.Millisecond)
+
+	want, err := os.ReadFile("../testdata/refresh_cache/created_response.json")
+	require.NoError(t, err)
+
+	require.Equal(t, string(want), evaluate(t, httpServer.Options().Address, "flag3"))
+
+	close(invalidationStop)
+
+	select {
+	case err := <-errCh:
+		require.NoError(t, err)
+	case <-time.After(30 * time.Second):
+	}
+
+	notifyService.Close()
+	exportService.Close()
+	err = httpServer.Stop()
+	require.NoError(t, err)
+	config.Reset()
+}
+
+func TestRefreshCache_ListenFailure(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	// env vars
+	os.Setenv("FLAG_FORMAT", "yaml")
+
+	// config
+	config.New()
+	defer config.Reset()
+
+	// clients
+	writereadClient := mockwritereader.NewWriteReader(
+		writereader.WithLocation("any"),
+	)
+
+	notifyClient := localnotifier.NewNotifier()
+
+	// services
+	cacheService := cache.New(writereadClient)
+
+	notifyService := notify.New(notifyClient)
+	defer notifyService.Close()
+
+	errCh := make(chan error, 1)
+	invalidationStop := make(chan struct{})
+
+	go func() {
+		errCh <- server.ListenForInvalidations(cacheService, notifyService, &unreachableBroadcaster{}, invalidationStop)
+	}()
+
+	// the cache updater covers for the listener so nothing is shut down
+	select {
+	case err := <-errCh:
+		t.Fatalf("listener returned before it was stopped: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	close(invalidationStop)
+
+	select {
+	case err := <-errCh:
+		require.NoError(t, err)
+	case <-time.After(30 * time.Second):
+		t.Fatal("listener did not stop")
+	}
+}
+
+func evaluate(t *testing.T, address, key string) string {
+	req, err := http.NewRequest(
+		http.MethodPost,
+		fmt.Sprintf("http://%s%s%s", address, "/ofrep/v1/evaluate/flags/", key),
+		strings.NewReader(`{"context":{}}`),
+	)
+	require.NoError(t, err)
+
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set("authorization", fmt.Sprintf("Bearer %s", tok))
+
+	client := &http.Client{}
+
+	rsp, err := client.Do(req)
+	require.NoError(t, err)
+
+	defer rsp.Body.Close()
+
+	got, err := io.ReadAll(rsp.Body)
+	require.NoError(t, err)
+
+	return string(got)
+}
+
+// unreachableBroadcaster stands in for a broadcaster whose store is down
+type unreachableBroadcaster struct{}
+
+func (b *unreachableBroadcaster) Broadcast(ctx context.Context) error {
+	return errors.New("connection refused")
+}
+
+func (b *unreachableBroadcaster) Listen(ctx context.Context) (<-chan struct{}, error) {
+	return nil, errors.New("connection refused")
+}
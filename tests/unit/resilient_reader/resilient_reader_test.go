@@ -0,0 +1,344 @@
+package resilientreader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/apikey"
+	localbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/local"
+	"github.com/w-h-a/flags/internal/server/clients/exporter"
+	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
+	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	"github.com/w-h-a/flags/internal/server/clients/reader/github"
+	"github.com/w-h-a/flags/internal/server/clients/reader/resilient"
+	"github.com/w-h-a/flags/internal/server/clients/writer"
+	"github.com/w-h-a/flags/internal/server/clients/writer/noop"
+	"github.com/w-h-a/flags/internal/server/config"
+	"github.com/w-h-a/flags/tests/unit"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	tok = "mytoken"
+)
+
+func TestResilientReader(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	type inputs struct {
+		failures int64
+		path     string
+	}
+
+	type want struct {
+		httpCode int
+		bodyFile string
+	}
+
+	tests := []struct {
+		name   string
+		inputs inputs
+		want   want
+	}{
+		{
+			name: "200 for get all",
+			inputs: inputs{
+				path: "/admin/v1/flags",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/get_flags/valid_response.json",
+			},
+		},
+		{
+			name: "200 for get one after retries",
+			inputs: inputs{
+				failures: 2,
+				path:     "/admin/v1/flags/flag2",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/get_flag/valid_response_flag2.json",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		// origin
+		bs, err := yaml.Marshal(unit.DefaultFlags())
+		require.NoError(t, err)
+
+		failures := &atomic.Int64{}
+		failures.Store(test.inputs.failures)
+
+		origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if failures.Add(-1) >= 0 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+
+			w.Write(bs)
+		}))
+
+		// env vars
+		os.Setenv("API_KEYS", tok)
+		os.Setenv("FLAG_FORMAT", "yaml")
+
+		// config
+		config.New()
+
+		// clients
+		readClient := resilient.NewReader(
+			resilient.WithReader("github", github.NewReader(
+				reader.WithLocation(origin.URL),
+				reader.WithFormat(config.FlagFormat()),
+			)),
+			reader.WithRetries(config.ReadClientRetries()),
+			resilient.WithBackoff(time.Millisecond, 10*time.Millisecond),
+			resilient.WithBreaker(
+				config.ReadClientBreakerThreshold(),
+				time.Duration(config.ReadClientBreakerCooldown())*time.Second,
+			),
+		)
+
+		writeClient := noop.NewWriter(
+			writer.WithLocation(config.WriteClientLocation()),
+		)
+
+		exportClient := localexporter.NewExporter(
+			exporter.WithDir(config.ExportClientDir()),
+		)
+
+		notifyClient := localnotifier.NewNotifier()
+
+		authClient := apikey.NewAuthenticator()
+
+		broadcastClient := localbroadcaster.NewBroadcaster()
+
+		// servers and services
+		httpServer, _, exportService, notifyService, err := server.Factory(
+			writeClient,
+			readClient,
+			exportClient,
+			notifyClient,
+			authClient,
+			broadcastClient,
+		)
+		require.NoError(t, err)
+
+		t.Run(test.name, func(t *testing.T) {
+			err = httpServer.Run()
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(
+				http.MethodGet,
+				fmt.Sprintf("http://%s%s", httpServer.Options().Address, test.inputs.path),
+				nil,
+			)
+			require.NoError(t, err)
+
+			req.Header.Set("authorization", fmt.Sprintf("Bearer %s", tok))
+
+			client := &http.Client{}
+
+			rsp, err := client.Do(req)
+			require.NoError(t, err)
+
+			want, err := os.ReadFile(test.want.bodyFile)
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(rsp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, string(want), string(got))
+
+			require.Equal(t, test.want.httpCode, rsp.StatusCode)
+
+			t.Cleanup(func() {
+				rsp.Body.Close()
+				origin.Close()
+				notifyService.Close()
+				exportService.Close()
+				err = httpServer.Stop()
+				require.NoError(t, err)
+				config.Reset()
+			})
+		})
+	}
+}
+
+func TestResilientReader_Breaker(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	bs, err := yaml.Marshal(unit.DefaultFlags())
+	require.NoError(t, err)
+
+	requests := &atomic.Int64{}
+	healthy := &atomic.Bool{}
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write(bs)
+	}))
+	defer origin.Close()
+
+	readClient := resilient.NewReader(
+		resilient.WithReader("github", github.NewReader(
+			reader.WithLocation(origin.URL),
+		)),
+		reader.WithRetries(1),
+		resilient.WithBackoff(time.Millisecond, 10*time.Millisecond),
+		resilient.WithBreaker(2, 200*time.Millisecond),
+	)
+
+	// two failed reads with one retry each open the circuit
+	for i := 0; i < 2; i++ {
+		_, err := readClient.Read(context.TODO())
+		require.EqualError(t, err, "received status code 503 from github")
+	}
+
+	require.Equal(t, int64(4), requests.Load())
+
+	degradable, ok := readClient.(reader.Degradable)
+	require.True(t, ok)
+	require.ErrorIs(t, degradable.Degraded(), resilient.ErrCircuitOpen)
+
+	// an open circuit fails fast without asking the source
+	_, err = readClient.Read(context.TODO())
+	require.ErrorIs(t, err, resilient.ErrCircuitOpen)
+	require.Equal(t, int64(4), requests.Load())
+
+	// after the cooldown a single probe closes the circuit again
+	healthy.Store(true)
+
+	time.Sleep(250 * time.Millisecond)
+
+	got, err := readClient.Read(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, bs, got)
+	require.Equal(t, int64(5), requests.Load())
+	require.NoError(t, degradable.Degraded())
+}
+
+func TestResilientReader_CancelledProbe(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer origin.Close()
+
+	readClient := resilient.NewReader(
+		resilient.WithReader("github", github.NewReader(
+			reader.WithLocation(origin.URL),
+		)),
+		reader.WithRetries(0),
+		resilient.WithBreaker(1, 100*time.Millisecond),
+	)
+
+	_, err := readClient.Read(context.TODO())
+	require.Error(t, err)
+
+	degradable, ok := readClient.(reader.Degradable)
+	require.True(t, ok)
+	require.ErrorIs(t, degradable.Degraded(), resilient.ErrCircuitOpen)
+
+	time.Sleep(150 * time.Millisecond)
+
+	// a probe that was cancelled proves nothing about the source
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = readClient.Read(ctx)
+	require.Error(t, err)
+	require.ErrorIs(t, degradable.Degraded(), resilient.ErrCircuitOpen)
+
+	// and the next read probes again
+	_, err = readClient.Read(context.TODO())
+	require.EqualError(t, err, "received status code 503 from github")
+}
+
+func TestResilientReader_InvalidBreaker(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	for _, breaker := range []reader.Option{
+		resilient.WithBreaker(0, time.Second),
+		resilient.WithBreaker(5, 0),
+	} {
+		require.Panics(t, func() {
+			resilient.NewReader(
+				resilient.WithReader("github", github.NewReader(
+					reader.WithLocation("http://localhost"),
+				)),
+				breaker,
+			)
+		})
+	}
+}
+
+func TestResilientReader_RateLimit(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	requests := &atomic.Int64{}
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer origin.Close()
+
+	readClient := resilient.NewReader(
+		resilient.WithReader("github", github.NewReader(
+			reader.WithLocation(origin.URL),
+		)),
+		reader.WithRetries(3),
+		resilient.WithBackoff(time.Millisecond, 10*time.Millisecond),
+		resilient.WithBreaker(5, 200*time.Millisecond),
+	)
+
+	_, err := readClient.Read(context.TODO())
+
+	var rateLimitErr *reader.RateLimitError
+	require.ErrorAs(t, err, &rateLimitErr)
+	require.Equal(t, 2*time.Minute, rateLimitErr.RetryAfter)
+
+	// the source asked for two minutes so the cooldown is not enough
+	time.Sleep(250 * time.Millisecond)
+
+	_, err = readClient.Read(context.TODO())
+	require.ErrorIs(t, err, resilient.ErrCircuitOpen)
+
+	require.Equal(t, int64(1), requests.Load())
+}
@@ -0,0 +1,393 @@
+package s3reader
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/apikey"
+	localbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/local"
+	"github.com/w-h-a/flags/internal/server/clients/exporter"
+	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
+	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	s3reader "github.com/w-h-a/flags/internal/server/clients/reader/s3"
+	"github.com/w-h-a/flags/internal/server/clients/writer"
+	"github.com/w-h-a/flags/internal/server/clients/writer/noop"
+	"github.com/w-h-a/flags/internal/server/config"
+	"github.com/w-h-a/flags/internal/server/services/cache"
+	"github.com/w-h-a/flags/tests/unit"
+)
+
+const (
+	tok    = "mytoken"
+	bucket = "flags"
+)
+
+func TestS3Reader(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	type inputs struct {
+		format   string
+		location string
+		split    bool
+		path     string
+	}
+
+	type want struct {
+		httpCode int
+		bodyFile string
+	}
+
+	tests := []struct {
+		name   string
+		inputs inputs
+		want   want
+	}{
+		{
+			name: "200 for get all from one yaml object",
+			inputs: inputs{
+				format:   "yaml",
+				location: "s3://flags/flags.yaml",
+				path:     "/admin/v1/flags",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/get_flags/valid_response.json",
+			},
+		},
+		{
+			name: "200 for get all from json objects under a prefix",
+			inputs: inputs{
+				format:   "json",
+				location: "s3://flags/prod/",
+				split:    true,
+				path:     "/admin/v1/flags",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/get_flags/valid_response.json",
+			},
+		},
+		{
+			name: "200 for get one from yaml objects under a prefix",
+			inputs: inputs{
+				format:   "yaml",
+				location: "s3://flags/prod/",
+				split:    true,
+				path:     "/admin/v1/flags/flag2",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/get_flag/valid_response_flag2.json",
+			},
+		},
+		{
+			name: "404 for get one if not exists",
+			inputs: inputs{
+				format:   "yaml",
+				location: "s3://flags/flags.yaml",
+				path:     "/admin/v1/flags/flag99",
+			},
+			want: want{
+				httpCode: http.StatusNotFound,
+				bodyFile: "../testdata/not_found.json",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		// s3
+		objects := seed(t, test.inputs.format, test.inputs.split)
+
+		store, _ := newStore(objects)
+
+		// env vars
+		os.Setenv("API_KEYS", tok)
+		os.Setenv("FLAG_FORMAT", test.inputs.format)
+		os.Setenv("AWS_ACCESS_KEY_ID", "test")
+		os.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+		// config
+		config.New()
+
+		// clients
+		readClient := s3reader.NewReader(
+			reader.WithLocation(test.inputs.location),
+			reader.WithFormat(config.FlagFormat()),
+			reader.WithEndpoint(store.URL),
+		)
+
+		writeClient := noop.NewWriter(
+			writer.WithLocation(config.WriteClientLocation()),
+		)
+
+		exportClient := localexporter.NewExporter(
+			exporter.WithDir(config.ExportClientDir()),
+		)
+
+		notifyClient := localnotifier.NewNotifier()
+
+		authClient := apikey.NewAuthenticator()
+
+		broadcastClient := localbroadcaster.NewBroadcaster()
+
+		// servers and services
+		httpServer, _, exportService, notifyService, err := server.Factory(
+			writeClient,
+			readClient,
+			exportClient,
+			notifyClient,
+			authClient,
+			broadcastClient,
+		)
+		require.NoError(t, err)
+
+		t.Run(test.name, func(t *testing.T) {
+			err = httpServer.Run()
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(
+				http.MethodGet,
+				fmt.Sprintf("http://%s%s", httpServer.Options().Address, test.inputs.path),
+				nil,
+			)
+			require.NoError(t, err)
+
+			req.Header.Set("authorization", fmt.Sprintf("Bearer %s", tok))
+
+			client := &http.Client{}
+
+			rsp, err := client.Do(req)
+			require.NoError(t, err)
+
+			want, err := os.ReadFile(test.want.bodyFile)
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(rsp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, string(want), string(got))
+
+			require.Equal(t, test.want.httpCode, rsp.StatusCode)
+
+			t.Cleanup(func() {
+				rsp.Body.Close()
+				store.Close()
+				notifyService.Close()
+				exportService.Close()
+				err = httpServer.Stop()
+				require.NoError(t, err)
+				config.Reset()
+			})
+		})
+	}
+}
+
+func TestS3Reader_NotModified(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	os.Setenv("AWS_ACCESS_KEY_ID", "test")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	for _, split := range []bool{false, true} {
+		objects := seed(t, "yaml", split)
+
+		store, downloads := newStore(objects)
+		defer store.Close()
+
+		location := "s3://flags/flags.yaml"
+		if split {
+			location = "s3://flags/prod/"
+		}
+
+		readClient := s3reader.NewReader(
+			reader.WithLocation(location),
+			reader.WithFormat("yaml"),
+			reader.WithEndpoint(store.URL),
+		)
+
+		conditional, ok := readClient.(reader.ConditionalReader)
+		require.True(t, ok)
+
+		first, err := conditional.ReadIfModified(context.TODO())
+		require.NoError(t, err)
+		require.NotEmpty(t, first)
+
+		count := downloads.Load()
+		require.Equal(t, int64(len(objects)), count)
+
+		// the cache has not accepted it so it is served again
+		again, err := conditional.ReadIfModified(context.TODO())
+		require.NoError(t, err)
+		require.Equal(t, first, again)
+		require.Equal(t, count, downloads.Load())
+
+		conditional.Ack(context.TODO())
+
+		// nothing changed so nothing is downloaded
+		_, err = conditional.ReadIfModified(context.TODO())
+		require.ErrorIs(t, err, reader.ErrNotModified)
+		require.Equal(t, count, downloads.Load())
+
+		// a plain read still has the document
+		bs, err := readClient.Read(context.TODO())
+		require.NoError(t, err)
+		require.Equal(t, first, bs)
+		require.Equal(t, count, downloads.Load())
+
+		// one changed object is downloaded again
+		for k := range objects {
+			objects[k] = append(objects[k], '\n')
+			break
+		}
+
+		_, err = conditional.ReadIfModified(context.TODO())
+		require.NoError(t, err)
+		require.Equal(t, count+1, downloads.Load())
+	}
+}
+
+func TestS3Reader_RefusedBody(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	os.Setenv("AWS_ACCESS_KEY_ID", "test")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	os.Setenv("FLAG_FORMAT", "yaml")
+
+	config.New()
+	defer config.Reset()
+
+	objects := map[string][]byte{
+		"flags.yaml": []byte("flag1: [not, a, flag]\n"),
+	}
+
+	store, _ := newStore(objects)
+	defer store.Close()
+
+	readClient := s3reader.NewReader(
+		reader.WithLocation("s3://flags/flags.yaml"),
+		reader.WithFormat(config.FlagFormat()),
+		reader.WithEndpoint(store.URL),
+	)
+
+	cacheService := cache.New(readClient)
+
+	// the body the cache refused is not taken as loaded
+	for range 2 {
+		_, _, err := cacheService.RetrieveFlags()
+		require.Error(t, err)
+		require.True(t, cacheService.LastUpdate().IsZero())
+	}
+}
+
+func seed(t *testing.T, format string, split bool) map[string][]byte {
+	objects := map[string][]byte{}
+
+	if !split {
+		bs, err := unit.Encode(unit.DefaultFlags(), format)
+		require.NoError(t, err)
+
+		objects["flags.yaml"] = bs
+
+		return objects
+	}
+
+	for k, v := range unit.DefaultFlags() {
+		bs, err := unit.Encode(map[string]*flags.Flag{k: v}, format)
+		require.NoError(t, err)
+
+		objects["prod/"+k] = bs
+	}
+
+	return objects
+}
+
+type listBucketResult struct {
+	XMLName  xml.Name         `xml:"ListBucketResult"`
+	Name     string           `xml:"Name"`
+	Prefix   string           `xml:"Prefix"`
+	KeyCount int              `xml:"KeyCount"`
+	Contents []listBucketItem `xml:"Contents"`
+}
+
+type listBucketItem struct {
+	Key  string `xml:"Key"`
+	ETag string `xml:"ETag"`
+	Size int    `xml:"Size"`
+}
+
+// newStore stands in for an s3 compatible store addressed by path
+func newStore(objects map[string][]byte) (*httptest.Server, *atomic.Int64) {
+	downloads := &atomic.Int64{}
+
+	etag := func(bs []byte) string {
+		return fmt.Sprintf(`"%x"`, md5.Sum(bs))
+	}
+
+	store := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/"+bucket)
+
+		if len(strings.TrimPrefix(path, "/")) == 0 && r.URL.Query().Get("list-type") == "2" {
+			prefix := r.URL.Query().Get("prefix")
+
+			result := listBucketResult{Name: bucket, Prefix: prefix}
+
+			for k, v := range objects {
+				if strings.HasPrefix(k, prefix) {
+					result.Contents = append(result.Contents, listBucketItem{Key: k, ETag: etag(v), Size: len(v)})
+				}
+			}
+
+			sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+
+			result.KeyCount = len(result.Contents)
+
+			w.Header().Set("Content-Type", "application/xml")
+			xml.NewEncoder(w).Encode(result)
+			return
+		}
+
+		bs, ok := objects[strings.TrimPrefix(path, "/")]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<Error><Code>NoSuchKey</Code></Error>`))
+			return
+		}
+
+		w.Header().Set("ETag", etag(bs))
+
+		if r.Header.Get("If-None-Match") == etag(bs) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		downloads.Add(1)
+
+		w.Write(bs)
+	}))
+
+	return store, downloads
+}
@@ -0,0 +1,286 @@
+package signedreader
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/apikey"
+	localbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/local"
+	"github.com/w-h-a/flags/internal/server/clients/exporter"
+	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
+	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	httpreader "github.com/w-h-a/flags/internal/server/clients/reader/http"
+	localreader "github.com/w-h-a/flags/internal/server/clients/reader/local"
+	"github.com/w-h-a/flags/internal/server/clients/reader/signed"
+	"github.com/w-h-a/flags/internal/server/clients/writer"
+	"github.com/w-h-a/flags/internal/server/clients/writer/noop"
+	"github.com/w-h-a/flags/internal/server/config"
+	"github.com/w-h-a/flags/internal/server/services/cache"
+	"github.com/w-h-a/flags/tests/unit"
+	"github.com/w-h-a/pkg/serverv2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	tok = "mytoken"
+)
+
+func TestSignedReader(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	// env vars
+	os.Setenv("API_KEYS", tok)
+	os.Setenv("FLAG_FORMAT", "yaml")
+
+	// config
+	config.New()
+	defer config.Reset()
+
+	// signed flags
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	_, otherPublicKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	bs, err := yaml.Marshal(unit.DefaultFlags())
+	require.NoError(t, err)
+
+	location := filepath.Join(t.TempDir(), "flags.yaml")
+
+	err = os.WriteFile(location, bs, 0644)
+	require.NoError(t, err)
+
+	err = os.WriteFile(location+signed.Extension, signed.Sign(bs, privateKey), 0644)
+	require.NoError(t, err)
+
+	// clients
+	readClient := signed.NewReader(
+		reader.WithFormat(config.FlagFormat()),
+		signed.WithReader(localreader.NewReader(
+			reader.WithLocation(location),
+			reader.WithFormat(config.FlagFormat()),
+		)),
+		signed.WithSignature(localreader.NewReader(
+			reader.WithLocation(location+signed.Extension),
+		)),
+		signed.WithPublicKeys(otherPublicKey.Public().(ed25519.PublicKey), publicKey),
+	)
+
+	writeClient := noop.NewWriter(
+		writer.WithLocation(config.WriteClientLocation()),
+	)
+
+	exportClient := localexporter.NewExporter(
+		exporter.WithDir(config.ExportClientDir()),
+	)
+
+	notifyClient := localnotifier.NewNotifier()
+
+	authClient := apikey.NewAuthenticator()
+
+	broadcastClient := localbroadcaster.NewBroadcaster()
+
+	// servers and services
+	httpServer, cacheService, exportService, notifyService, err := server.Factory(
+		writeClient,
+		readClient,
+		exportClient,
+		notifyClient,
+		authClient,
+		broadcastClient,
+	)
+	require.NoError(t, err)
+
+	err = httpServer.Run()
+	require.NoError(t, err)
+
+	defer func() {
+		notifyService.Close()
+		exportService.Close()
+		err := httpServer.Stop()
+		require.NoError(t, err)
+	}()
+
+	status := getStatus(t, httpServer)
+	require.Equal(t, false, status["degraded"])
+
+	// someone with write access to the source changes a flag
+	tampered, err := yaml.Marshal(map[string]any{
+		"flag1": map[string]any{"variants": map[string]any{"default": "C"}},
+	})
+	require.NoError(t, err)
+
+	err = os.WriteFile(location, tampered, 0644)
+	require.NoError(t, err)
+
+	_, _, err = cacheService.RetrieveFlags()
+	require.ErrorIs(t, err, signed.ErrInvalidSignature)
+
+	status = getStatus(t, httpServer)
+	require.Equal(t, true, status["degraded"])
+	require.Equal(t, signed.ErrInvalidSignature.Error(), status["degradedReason"])
+
+	// the verified flags are still served
+	state, err := cacheService.EvaluateFlag(context.TODO(), "flag1", map[string]any{"targetingKey": "user"})
+	require.NoError(t, err)
+	require.Equal(t, "B", state.Value)
+
+	// putting the signed flags back recovers
+	err = os.WriteFile(location, bs, 0644)
+	require.NoError(t, err)
+
+	_, _, err = cacheService.RetrieveFlags()
+	require.NoError(t, err)
+
+	status = getStatus(t, httpServer)
+	require.Equal(t, false, status["degraded"])
+}
+
+func TestSignedReader_Unsigned(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	bs, err := yaml.Marshal(unit.DefaultFlags())
+	require.NoError(t, err)
+
+	location := filepath.Join(t.TempDir(), "flags.yaml")
+
+	err = os.WriteFile(location, bs, 0644)
+	require.NoError(t, err)
+
+	readClient := signed.NewReader(
+		signed.WithReader(localreader.NewReader(
+			reader.WithLocation(location),
+		)),
+		signed.WithSignature(localreader.NewReader(
+			reader.WithLocation(location+signed.Extension),
+		)),
+		signed.WithPublicKeys(publicKey),
+	)
+
+	// nothing unsigned reaches the cache
+	_, _, err = cache.New(readClient).RetrieveFlags()
+	require.ErrorIs(t, err, signed.ErrUnsigned)
+}
+
+func TestSignedReader_CorrectedSignature(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	// env vars
+	os.Setenv("FLAG_FORMAT", "yaml")
+
+	// config
+	config.New()
+	defer config.Reset()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	bs, err := yaml.Marshal(unit.DefaultFlags())
+	require.NoError(t, err)
+
+	// an origin that answers conditional requests
+	var served atomic.Value
+	served.Store(bs)
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := served.Load().([]byte)
+		etag := fmt.Sprintf(`"%x"`, sha256.Sum256(body))
+
+		w.Header().Set("ETag", etag)
+
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Write(body)
+	}))
+	defer origin.Close()
+
+	location := filepath.Join(t.TempDir(), "flags.yaml"+signed.Extension)
+
+	err = os.WriteFile(location, signed.Sign(bs, privateKey), 0644)
+	require.NoError(t, err)
+
+	readClient := signed.NewReader(
+		reader.WithFormat(config.FlagFormat()),
+		signed.WithReader(httpreader.NewReader(
+			reader.WithLocation(origin.URL),
+			reader.WithFormat(config.FlagFormat()),
+			reader.WithRetries(0),
+		)),
+		signed.WithSignature(localreader.NewReader(
+			reader.WithLocation(location),
+		)),
+		signed.WithPublicKeys(publicKey),
+	)
+
+	cacheService := cache.New(readClient)
+
+	_, _, err = cacheService.RetrieveFlags()
+	require.NoError(t, err)
+
+	// the flags are published before their signature
+	updated, err := yaml.Marshal(map[string]any{
+		"flag1": map[string]any{"variants": map[string]any{"default": "C"}},
+	})
+	require.NoError(t, err)
+
+	served.Store(updated)
+
+	_, _, err = cacheService.RetrieveFlags()
+	require.ErrorIs(t, err, signed.ErrInvalidSignature)
+
+	// the origin now answers 304 but the corrected signature still loads
+	err = os.WriteFile(location, signed.Sign(updated, privateKey), 0644)
+	require.NoError(t, err)
+
+	_, _, err = cacheService.RetrieveFlags()
+	require.NoError(t, err)
+
+	state, err := cacheService.EvaluateFlag(context.TODO(), "flag1", map[string]any{"targetingKey": "user"})
+	require.NoError(t, err)
+	require.Equal(t, "C", state.Value)
+}
+
+func getStatus(t *testing.T, httpServer serverv2.Server) map[string]any {
+	rsp, err := http.Get(fmt.Sprintf("http://%s%s", httpServer.Options().Address, "/status"))
+	require.NoError(t, err)
+
+	defer rsp.Body.Close()
+
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+
+	status := map[string]any{}
+
+	err = json.NewDecoder(rsp.Body).Decode(&status)
+	require.NoError(t, err)
+
+	return status
+}
@@ -0,0 +1,248 @@
+package sqliteclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/apikey"
+	localbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/local"
+	"github.com/w-h-a/flags/internal/server/clients/exporter"
+	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
+	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
+	"github.com/w-h-a/flags/internal/server/clients/reader"
+	sqlitereader "github.com/w-h-a/flags/internal/server/clients/reader/sqlite"
+	"github.com/w-h-a/flags/internal/server/clients/writer"
+	sqlitewriter "github.com/w-h-a/flags/internal/server/clients/writer/sqlite"
+	"github.com/w-h-a/flags/internal/server/config"
+	"github.com/w-h-a/flags/tests/unit"
+)
+
+const (
+	tok = "mytoken"
+)
+
+func TestSQLiteClient(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	type inputs struct {
+		format string
+		method string
+		path   string
+		body   string
+	}
+
+	type want struct {
+		httpCode int
+		bodyFile string
+	}
+
+	tests := []struct {
+		name   string
+		inputs inputs
+		want   want
+	}{
+		{
+			name: "200 for get all in yaml",
+			inputs: inputs{
+				format: "yaml",
+				method: http.MethodGet,
+				path:   "/admin/v1/flags",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/get_flags/valid_response.json",
+			},
+		},
+		{
+			name: "200 for get all in json",
+			inputs: inputs{
+				format: "json",
+				method: http.MethodGet,
+				path:   "/admin/v1/flags",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/get_flags/valid_response.json",
+			},
+		},
+		{
+			name: "200 for get one",
+			inputs: inputs{
+				format: "yaml",
+				method: http.MethodGet,
+				path:   "/admin/v1/flags/flag2",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/get_flag/valid_response_flag2.json",
+			},
+		},
+		{
+			name: "404 for get one if not exists",
+			inputs: inputs{
+				format: "yaml",
+				method: http.MethodGet,
+				path:   "/admin/v1/flags/flag99",
+			},
+			want: want{
+				httpCode: http.StatusNotFound,
+				bodyFile: "../testdata/not_found.json",
+			},
+		},
+		{
+			name: "201 for put",
+			inputs: inputs{
+				format: "json",
+				method: http.MethodPut,
+				path:   "/admin/v1/flags",
+				body:   `{"flag3":{"disabled":true,"variants":{"default":"A","variant2":"B"},"rules":[{"name":"rule1","variant":"variant2"}]}}`,
+			},
+			want: want{
+				httpCode: http.StatusCreated,
+				bodyFile: "../testdata/upsert_flag/valid_response_created.json",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		// sqlite
+		location := filepath.Join(t.TempDir(), "flags.db")
+
+		// env vars
+		os.Setenv("API_KEYS", tok)
+		os.Setenv("FLAG_FORMAT", test.inputs.format)
+
+		// config
+		config.New()
+
+		// clients
+		readClient := sqlitereader.NewReader(
+			reader.WithLocation(location),
+			reader.WithFormat(config.FlagFormat()),
+		)
+
+		writeClient := sqlitewriter.NewWriter(
+			writer.WithLocation(location),
+		)
+
+		for k, v := range unit.DefaultFlags() {
+			bs, err := unit.Encode(map[string]*flags.Flag{k: v}, test.inputs.format)
+			require.NoError(t, err)
+
+			err = writeClient.Write(context.TODO(), k, bs)
+			require.NoError(t, err)
+		}
+
+		exportClient := localexporter.NewExporter(
+			exporter.WithDir(config.ExportClientDir()),
+		)
+
+		notifyClient := localnotifier.NewNotifier()
+
+		authClient := apikey.NewAuthenticator()
+
+		broadcastClient := localbroadcaster.NewBroadcaster()
+
+		// servers and services
+		httpServer, _, exportService, notifyService, err := server.Factory(
+			writeClient,
+			readClient,
+			exportClient,
+			notifyClient,
+			authClient,
+			broadcastClient,
+		)
+		require.NoError(t, err)
+
+		t.Run(test.name, func(t *testing.T) {
+			err = httpServer.Run()
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(
+				test.inputs.method,
+				fmt.Sprintf("http://%s%s", httpServer.Options().Address, test.inputs.path),
+				strings.NewReader(test.inputs.body),
+			)
+			require.NoError(t, err)
+
+			req.Header.Set("authorization", fmt.Sprintf("Bearer %s", tok))
+
+			client := &http.Client{}
+
+			rsp, err := client.Do(req)
+			require.NoError(t, err)
+
+			want, err := os.ReadFile(test.want.bodyFile)
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(rsp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, string(want), string(got))
+
+			require.Equal(t, test.want.httpCode, rsp.StatusCode)
+
+			t.Cleanup(func() {
+				rsp.Body.Close()
+				notifyService.Close()
+				exportService.Close()
+				err = httpServer.Stop()
+				require.NoError(t, err)
+				config.Reset()
+			})
+		})
+	}
+}
+
+func TestSQLiteClient_Persist(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	location := filepath.Join(t.TempDir(), "flags.db")
+
+	writeClient := sqlitewriter.NewWriter(
+		writer.WithLocation(location),
+	)
+
+	bs, err := json.Marshal(map[string]*flags.Flag{"flag2": unit.DefaultFlags()["flag2"]})
+	require.NoError(t, err)
+
+	err = writeClient.Write(context.TODO(), "flag2", bs)
+	require.NoError(t, err)
+
+	// writing the same key again replaces it
+	err = writeClient.Write(context.TODO(), "flag2", bs)
+	require.NoError(t, err)
+
+	// a restarted server finds what was written before
+	readClient := sqlitereader.NewReader(
+		reader.WithLocation(location),
+		reader.WithFormat("json"),
+	)
+
+	got, err := readClient.Read(context.TODO())
+	require.NoError(t, err)
+
+	want, err := os.ReadFile("../testdata/get_flag/valid_response_flag2.json")
+	require.NoError(t, err)
+
+	require.JSONEq(t, string(want), string(got))
+
+	_, err = readClient.ReadByKey(context.TODO(), "flag99")
+	require.ErrorIs(t, err, reader.ErrRecordNotFound)
+}
@@ -304,3 +304,68 @@ func TestUpdateFlags_RemoveFlags(t *testing.T) {
 	case <-time.After(30 * time.Second):
 	}
 }
+
+func TestUpdateFlags_Watch(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	readClient := mockreader.NewReader(
+		reader.WithLocation("any"),
+		mockreader.WithInitialFlags(
+			map[string]*flags.Flag{},
+		),
+		mockreader.WithUpdatedFlags(
+			map[string]*flags.Flag{
+				"flag1": {
+					Variants: map[string]any{
+						"default": "default",
+					},
+				},
+			},
+		),
+	)
+
+	cacheService := cache.New(readClient)
+
+	_, _, err := cacheService.RetrieveFlags()
+	require.NoError(t, err)
+
+	r := readClient.(*mockreader.Client)
+
+	notifyClient := mocknotifier.NewNotifier()
+
+	notifyService := notify.New(notifyClient)
+
+	errCh := make(chan error, 1)
+	updateStop := make(chan struct{})
+
+	// the interval is far too long to be what refreshes the cache
+	go func() {
+		errCh <- server.UpdateCache(cacheService, notifyService, updateStop, time.Hour)
+	}()
+
+	require.Eventually(t, func() bool { return r.WatchCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	r.Trigger()
+
+	require.Eventually(t, func() bool { return r.CallCount() == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	n := notifyClient.(*mocknotifier.Client)
+	wasCalled := n.WasCalled()
+	require.True(t, wasCalled)
+
+	// a lost watch is re-established and the cache is refreshed
+	r.Drop()
+
+	require.Eventually(t, func() bool { return r.WatchCount() == 2 && r.CallCount() == 3 }, 5*time.Second, 10*time.Millisecond)
+
+	close(updateStop)
+
+	select {
+	case err := <-errCh:
+		require.NoError(t, err)
+	case <-time.After(30 * time.Second):
+	}
+}
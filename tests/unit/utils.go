@@ -1,7 +1,10 @@
 package unit
 
 import (
+	"encoding/json"
+
 	"github.com/w-h-a/flags/internal/flags"
+	"gopkg.in/yaml.v3"
 )
 
 func DefaultFlags() map[string]*flags.Flag {
@@ -35,6 +38,14 @@ func DefaultFlags() map[string]*flags.Flag {
 	}
 }
 
+func Encode(flag map[string]*flags.Flag, format string) ([]byte, error) {
+	if format == "json" {
+		return json.Marshal(flag)
+	}
+
+	return yaml.Marshal(flag)
+}
+
 func Bool(v bool) *bool {
 	return &v
 }
@@ -0,0 +1,220 @@
+package validateflags
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/flags/internal/flags"
+	"github.com/w-h-a/flags/internal/server"
+	"github.com/w-h-a/flags/internal/server/clients/authenticator/apikey"
+	localbroadcaster "github.com/w-h-a/flags/internal/server/clients/broadcaster/local"
+	"github.com/w-h-a/flags/internal/server/clients/exporter"
+	localexporter "github.com/w-h-a/flags/internal/server/clients/exporter/local"
+	localnotifier "github.com/w-h-a/flags/internal/server/clients/notifier/local"
+	"github.com/w-h-a/flags/internal/server/clients/writereader"
+	mockwritereader "github.com/w-h-a/flags/internal/server/clients/writereader/mock"
+	"github.com/w-h-a/flags/internal/server/config"
+	"github.com/w-h-a/flags/tests/unit"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	tok = "mytoken"
+)
+
+func TestValidateFlags(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	type inputs struct {
+		flags        map[string]*flags.Flag
+		bodyFile     string
+		contentType  string
+		query        string
+		unauthorized bool
+	}
+
+	type want struct {
+		httpCode int
+		bodyFile string
+	}
+
+	tests := []struct {
+		name   string
+		inputs inputs
+		want   want
+	}{
+		{
+			name: "200 for valid yaml",
+			inputs: inputs{
+				flags:       unit.DefaultFlags(),
+				bodyFile:    "../testdata/validate_flags/valid.yaml",
+				contentType: "application/yaml",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/validate_flags/valid_response.json",
+			},
+		},
+		{
+			name: "200 for valid json",
+			inputs: inputs{
+				flags:       unit.DefaultFlags(),
+				bodyFile:    "../testdata/upsert_flag/valid_response_created.json",
+				contentType: "application/json",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/validate_flags/valid_response.json",
+			},
+		},
+		{
+			name: "200 with diff against stored flags",
+			inputs: inputs{
+				flags:       unit.DefaultFlags(),
+				bodyFile:    "../testdata/validate_flags/diff.json",
+				contentType: "application/json",
+				query:       "?diff=true",
+			},
+			want: want{
+				httpCode: http.StatusOK,
+				bodyFile: "../testdata/validate_flags/diff_response.json",
+			},
+		},
+		{
+			name: "422 with every error",
+			inputs: inputs{
+				flags:       unit.DefaultFlags(),
+				bodyFile:    "../testdata/validate_flags/invalid.json",
+				contentType: "application/json",
+				query:       "?diff=true",
+			},
+			want: want{
+				httpCode: http.StatusUnprocessableEntity,
+				bodyFile: "../testdata/validate_flags/invalid_response.json",
+			},
+		},
+		{
+			name: "422 for malformed document",
+			inputs: inputs{
+				flags:       unit.DefaultFlags(),
+				bodyFile:    "../testdata/validate_flags/valid.yaml",
+				contentType: "application/json",
+			},
+			want: want{
+				httpCode: http.StatusUnprocessableEntity,
+				bodyFile: "../testdata/validate_flags/malformed_response.json",
+			},
+		},
+		{
+			name: "403 if unauthorized",
+			inputs: inputs{
+				bodyFile:     "../testdata/validate_flags/valid.yaml",
+				unauthorized: true,
+			},
+			want: want{
+				httpCode: http.StatusUnauthorized,
+				bodyFile: "../testdata/unauthorized.json",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		// env vars
+		os.Setenv("API_KEYS", tok)
+		os.Setenv("FLAG_FORMAT", "yaml")
+		os.Setenv("WRITE_CLIENT_LOCATION", "any")
+
+		// config
+		config.New()
+
+		// clients
+		writereadClient := mockwritereader.NewWriteReader(
+			writereader.WithLocation(config.WriteClientLocation()),
+		)
+
+		for k, v := range test.inputs.flags {
+			bs, err := yaml.Marshal(map[string]*flags.Flag{
+				k: v,
+			})
+			require.NoError(t, err)
+
+			err = writereadClient.Write(context.TODO(), k, bs)
+			require.NoError(t, err)
+		}
+
+		exportClient := localexporter.NewExporter(
+			exporter.WithDir(config.ExportClientDir()),
+		)
+
+		notifyClient := localnotifier.NewNotifier()
+
+		authClient := apikey.NewAuthenticator()
+
+		broadcastClient := localbroadcaster.NewBroadcaster()
+
+		// servers and services
+		httpServer, _, exportService, notifyService, err := server.Factory(
+			writereadClient,
+			writereadClient,
+			exportClient,
+			notifyClient,
+			authClient,
+			broadcastClient,
+		)
+		require.NoError(t, err)
+
+		t.Run(test.name, func(t *testing.T) {
+			err = httpServer.Run()
+			require.NoError(t, err)
+
+			bs, err := os.ReadFile(test.inputs.bodyFile)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(
+				http.MethodPost,
+				fmt.Sprintf("http://%s%s%s", httpServer.Options().Address, "/admin/v1/flags/validate", test.inputs.query),
+				bytes.NewReader(bs),
+			)
+			require.NoError(t, err)
+
+			req.Header.Set("content-type", test.inputs.contentType)
+
+			if !test.inputs.unauthorized {
+				req.Header.Set("authorization", fmt.Sprintf("Bearer %s", tok))
+			}
+
+			client := &http.Client{}
+
+			rsp, err := client.Do(req)
+			require.NoError(t, err)
+
+			want, err := os.ReadFile(test.want.bodyFile)
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(rsp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, string(want), string(got))
+
+			require.Equal(t, test.want.httpCode, rsp.StatusCode)
+
+			t.Cleanup(func() {
+				rsp.Body.Close()
+				notifyService.Close()
+				exportService.Close()
+				err = httpServer.Stop()
+				require.NoError(t, err)
+				config.Reset()
+			})
+		})
+	}
+}